@@ -0,0 +1,46 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/cahitcaginkaratas/backend_insider/internal/database"
+)
+
+func main() {
+	dsn := flag.String("dsn", database.DSN(), "SQLite file to back up, defaults to $LEAGUE_DSN")
+	dir := flag.String("dir", database.DefaultBackupDir, "directory to write the backup to")
+	keep := flag.Int("keep", database.DefaultBackupKeep, "number of backups to keep, 0 keeps all")
+	list := flag.Bool("list", false, "list the backups instead of making one")
+	flag.Parse()
+
+	if *list {
+		backups, err := database.ListBackups(*dir)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, backup := range backups {
+			fmt.Printf("%s  %10d  %s\n", backup.CreatedAt.Format("2006-01-02 15:04:05"), backup.Size, backup.Path)
+		}
+		return
+	}
+
+	if !database.IsSQLiteDSN(*dsn) {
+		log.Fatal(database.ErrBackupUnsupported)
+	}
+	db := database.NewSQLiteDB(*dsn)
+	err := db.InitDB()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	backup, removed, err := db.Snapshot(*dir, *keep)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, path := range removed {
+		log.Printf("Removed old backup %s", path)
+	}
+	log.Printf("Backed up %s to %s (%d bytes)", *dsn, backup.Path, backup.Size)
+}
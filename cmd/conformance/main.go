@@ -0,0 +1,39 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/cahitcaginkaratas/backend_insider/internal/database"
+	"github.com/cahitcaginkaratas/backend_insider/internal/database/conformance"
+)
+
+func main() {
+	dsn := flag.String("dsn", "", "SQLite file or postgres:// URL of a scratch database, all data in it is replaced")
+	flag.Parse()
+	if *dsn == "" {
+		log.Fatal("-dsn is required, the checks reset the database")
+	}
+
+	db := database.Open(*dsn)
+	err := db.InitDB()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	failed := 0
+	for _, result := range conformance.Run(db) {
+		if result.Err != nil {
+			failed++
+			fmt.Printf("FAIL  %s: %v\n", result.Name, result.Err)
+			continue
+		}
+		fmt.Printf("ok    %s\n", result.Name)
+	}
+	if failed > 0 {
+		fmt.Printf("%d of %d checks failed\n", failed, len(conformance.Checks))
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/cahitcaginkaratas/backend_insider/internal/database"
+	"github.com/cahitcaginkaratas/backend_insider/internal/ratings"
+)
+
+func main() {
+	csvPath := flag.String("csv", "", "fit from a football-data.co.uk CSV file instead of the database")
+	xi := flag.Float64("xi", ratings.DefaultXi, "time decay per day, 0 weighs all matches equally")
+	dryRun := flag.Bool("dry-run", false, "print the fit without storing it")
+	dsn := flag.String("dsn", database.DSN(), "SQLite file or postgres:// URL, defaults to $LEAGUE_DSN")
+	flag.Parse()
+
+	// Initialize database
+	db := database.Open(*dsn)
+	err := db.InitDB()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Collect observations
+	var observations []ratings.Observation
+	source := "database"
+	if *csvPath != "" {
+		file, err := os.Open(*csvPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		observations, err = ratings.ReadCSV(file)
+		file.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+		source = "csv"
+	} else {
+		matches, err := db.GetMatches()
+		if err != nil {
+			log.Fatal(err)
+		}
+		observations = ratings.FromMatches(matches)
+	}
+
+	// Fit ratings
+	opts := ratings.DefaultOptions()
+	opts.Xi = *xi
+	result, err := ratings.Fit(observations, opts)
+	if err != nil {
+		log.Fatal(err)
+	}
+	result.Fit.Source = source
+
+	fmt.Printf("Matches: %d  Iterations: %d  Converged: %t\n", result.Fit.Matches, result.Fit.Iterations, result.Fit.Converged)
+	fmt.Printf("Home advantage: %.4f  Rho: %.4f  Log-likelihood: %.2f\n\n", result.Fit.HomeAdvantage, result.Fit.Rho, result.Fit.LogLikelihood)
+	fmt.Printf("%-25s %7s %8s %8s\n", "Team", "Matches", "Attack", "Defence")
+	for _, team := range result.Teams {
+		fmt.Printf("%-25s %7d %8.4f %8.4f\n", team.Name, team.Matches, team.Attack, team.Defence)
+	}
+
+	if *dryRun {
+		return
+	}
+
+	// Store ratings
+	unmatched, err := ratings.Store(db, result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, name := range unmatched {
+		log.Printf("Team %q is not in the database, its rating was not stored", name)
+	}
+	log.Printf("Stored rating fit %d", result.Fit.ID)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cahitcaginkaratas/backend_insider/internal/database"
+	"github.com/cahitcaginkaratas/backend_insider/internal/importer"
+	"github.com/cahitcaginkaratas/backend_insider/internal/models"
+)
+
+func main() {
+	dsn := flag.String("dsn", database.DSN(), "SQLite file or postgres:// URL, defaults to $LEAGUE_DSN")
+	seasonID := flag.Uint("season", 0, "season to import into, defaults to the current season")
+	newSeason := flag.String("new-season", "", "name of a new season to create and import into instead")
+	dryRun := flag.Bool("dry-run", false, "check the file and print the changes without storing them")
+	actor := flag.String("actor", "import", "name recorded in the audit log")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: import [flags] FILE.csv|FILE.json")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 || (*newSeason != "" && *seasonID != 0) {
+		flag.Usage()
+		os.Exit(2)
+	}
+	path := flag.Arg(0)
+
+	// Read the file
+	file, err := os.Open(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var data *importer.Data
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		data, err = importer.ReadCSV(file)
+	} else {
+		data, err = importer.ReadJSON(file)
+	}
+	file.Close()
+	var errs importer.Errors
+	if errors.As(err, &errs) {
+		for _, lineErr := range errs {
+			fmt.Fprintf(os.Stderr, "%s:%d: %s\n", path, lineErr.Line, lineErr.Message)
+		}
+		os.Exit(1)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Initialize database
+	db := database.Open(*dsn)
+	err = db.InitDB()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var summary *importer.Summary
+	name := *newSeason
+	if name != "" {
+		summary, err = importer.ImportNewSeason(db, name, data, *actor, *dryRun)
+	} else {
+		var season *models.Season
+		if *seasonID != 0 {
+			season, err = db.GetSeason(*seasonID)
+		} else {
+			season, err = db.GetCurrentSeason()
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		if season == nil {
+			log.Fatalf("Season %d not found", *seasonID)
+		}
+		name = season.Name
+		summary, err = importer.Import(db, season.ID, data, *actor, *dryRun)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if summary.DryRun {
+		fmt.Println("Dry run, nothing was stored")
+	}
+	fmt.Printf("Season: %s (%d)\n", name, summary.SeasonID)
+	fmt.Printf("Teams created: %d %s\n", len(summary.TeamsCreated), strings.Join(summary.TeamsCreated, ", "))
+	fmt.Printf("Teams updated: %d %s\n", len(summary.TeamsUpdated), strings.Join(summary.TeamsUpdated, ", "))
+	fmt.Printf("Matches created: %d  updated: %d  unchanged: %d\n", summary.MatchesCreated, summary.MatchesUpdated, summary.MatchesUnchanged)
+}
@@ -1,34 +1,40 @@
 package main
 
 import (
+	"flag"
 	"log"
 	"net/http"
+	"strings"
 
 	"github.com/cahitcaginkaratas/backend_insider/internal/database"
 	"github.com/cahitcaginkaratas/backend_insider/internal/handlers"
 	"github.com/cahitcaginkaratas/backend_insider/internal/models"
-	"github.com/gorilla/mux"
+	"github.com/cahitcaginkaratas/backend_insider/internal/ratings"
 )
 
-func corsMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
-
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
+func main() {
+	dsn := flag.String("dsn", database.DSN(), "SQLite file or postgres:// URL, defaults to $LEAGUE_DSN")
+	ephemeral := flag.Bool("ephemeral", false, "keep everything in memory instead of a database")
+	backupDir := flag.String("backup-dir", database.DefaultBackupDir, "directory for backups made with POST /api/admin/backup")
+	backupKeep := flag.Int("backup-keep", database.DefaultBackupKeep, "number of backups to keep, 0 keeps all")
+	seedFile := flag.String("seed", "", "YAML or JSON file with the teams of a new league, defaults to the built-in teams")
+	flag.Parse()
+	if *ephemeral {
+		*dsn = database.MemoryDSN
+	}
 
-		next.ServeHTTP(w, r)
-	})
-}
+	// Load the teams of a new league
+	seed, err := database.DefaultSeed()
+	if *seedFile != "" {
+		seed, err = database.LoadSeed(*seedFile)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
 
-func main() {
 	// Initialize database
-	db := database.NewSQLiteDB()
-	err := db.InitDB()
+	db := database.Open(*dsn)
+	err = db.InitDB()
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -41,53 +47,30 @@ func main() {
 
 	// Only create teams if none exist
 	if len(existingTeams) == 0 {
-		// Initialize teams
-		teams := []*models.Team{
-			models.NewTeam("Manchester City", 90),
-			models.NewTeam("Liverpool", 85),
-			models.NewTeam("Arsenal", 80),
-			models.NewTeam("Chelsea", 75),
+		err = database.Seed(db, seed)
+		if err != nil {
+			log.Fatal(err)
 		}
+	}
 
-		// Save teams to database
-		for _, team := range teams {
-			err = db.SaveTeam(team)
-			if err != nil {
-				log.Fatal(err)
-			}
-		}
-
-		// Create league and generate fixtures
-		league := models.NewLeague()
-		for _, team := range teams {
-			league.AddTeam(team)
-		}
-		league.GenerateFixtures()
-
-		// Save matches to database
-		for _, match := range league.Matches {
-			err = db.SaveMatch(&match)
-			if err != nil {
-				log.Fatal(err)
-			}
-		}
+	// Use fitted ratings if there are any
+	engine, err := ratings.Load(db)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if engine != nil {
+		models.SetEngine(engine)
 	}
 
 	// Initialize API handler
 	apiHandler := handlers.NewAPIHandler(db)
+	apiHandler.SetBackupRotation(*backupDir, *backupKeep)
 
-	// Set up router
-	router := mux.NewRouter()
-	router.Use(corsMiddleware)
-
-	// API routes
-	router.HandleFunc("/api/teams", apiHandler.GetTeams).Methods("GET")
-	router.HandleFunc("/api/matches", apiHandler.GetMatches).Methods("GET")
-	router.HandleFunc("/api/league", apiHandler.GetLeagueStats).Methods("GET")
-	router.HandleFunc("/api/matches/simulate/{week}", apiHandler.SimulateWeek).Methods("POST")
-	router.HandleFunc("/api/matches/simulate-all", apiHandler.SimulateAll).Methods("POST")
-	router.HandleFunc("/api/matches/{id}", apiHandler.UpdateMatchResult).Methods("PUT")
-	router.HandleFunc("/api/reset", apiHandler.ResetLeague).Methods("POST")
+	// Set up router, every route is described in the OpenAPI specification
+	router := handlers.NewRouter(apiHandler)
+	if problems := apiHandler.Spec().CheckRouter(router); len(problems) > 0 {
+		log.Fatalf("Routes and OpenAPI specification differ:\n%s", strings.Join(problems, "\n"))
+	}
 
 	// Start server
 	log.Println("Server starting on :8080")
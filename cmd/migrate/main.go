@@ -0,0 +1,87 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/cahitcaginkaratas/backend_insider/internal/database"
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: migrate [-dsn DSN] up [VERSION] | down [VERSION] | status")
+	fmt.Fprintln(os.Stderr, "  up      apply every pending migration, or those up to VERSION")
+	fmt.Fprintln(os.Stderr, "  down    roll back the latest migration, or every migration after VERSION")
+	fmt.Fprintln(os.Stderr, "  status  list migrations and whether they are applied")
+	flag.PrintDefaults()
+}
+
+func main() {
+	dsn := flag.String("dsn", database.DSN(), "SQLite file or postgres:// URL, defaults to $LEAGUE_DSN")
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() < 1 || flag.NArg() > 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	db, ok := database.Open(*dsn).(*database.GormDB)
+	if !ok {
+		log.Fatal("The in-memory database has no schema to migrate")
+	}
+	err := db.Connect()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	current, err := db.CurrentVersion()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	target := -1
+	if flag.NArg() == 2 {
+		target, err = strconv.Atoi(flag.Arg(1))
+		if err != nil || target < 0 || target > database.LatestVersion() {
+			log.Fatalf("Invalid version %q, expected 0 to %d", flag.Arg(1), database.LatestVersion())
+		}
+	}
+
+	switch flag.Arg(0) {
+	case "up":
+		// Version 0 has no tables, rolling back to it is "down 0"
+		if target == 0 {
+			log.Fatalf("Invalid version 0 for up, leave the version out to apply every migration")
+		}
+		if target < 0 {
+			target = database.LatestVersion()
+		}
+		err = db.MigrateUp(target)
+	case "down":
+		if target < 0 {
+			target = current - 1
+		}
+		err = db.MigrateDown(max(target, 0))
+	case "status":
+	default:
+		usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	statuses, err := db.MigrationStatus()
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, status := range statuses {
+		applied := "pending"
+		if status.Applied {
+			applied = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		fmt.Printf("%3d  %-35s %s\n", status.Version, status.Name, applied)
+	}
+}
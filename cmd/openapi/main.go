@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/cahitcaginkaratas/backend_insider/internal/database"
+	"github.com/cahitcaginkaratas/backend_insider/internal/handlers"
+)
+
+func main() {
+	check := flag.Bool("check", false, "check the specification against the router and the README instead of printing it")
+	readme := flag.String("readme", "README.md", "README that has to mention every endpoint, empty skips it")
+	flag.Parse()
+
+	// The routes do not need a database, only the handler methods
+	apiHandler := handlers.NewAPIHandler(database.NewMemoryDB())
+	router := handlers.NewRouter(apiHandler)
+	spec := apiHandler.Spec()
+
+	if !*check {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(spec); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	problems := spec.CheckRouter(router)
+	if *readme != "" {
+		text, err := os.ReadFile(*readme)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, problem := range spec.CheckText(string(text)) {
+			problems = append(problems, problem+" in "+*readme)
+		}
+	}
+	for _, problem := range problems {
+		fmt.Println(problem)
+	}
+	if len(problems) > 0 {
+		os.Exit(1)
+	}
+
+	operations := 0
+	for _, item := range spec.Paths {
+		operations += len(item)
+	}
+	fmt.Printf("%d operations match the router\n", operations)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"slices"
+
+	"github.com/cahitcaginkaratas/backend_insider/internal/database"
+	"github.com/cahitcaginkaratas/backend_insider/internal/models"
+)
+
+func main() {
+	buckets := flag.Int("buckets", 10, "number of calibration buckets")
+	season := flag.Uint("season", 0, "only score the predictions of this season, 0 scores every season")
+	source := flag.String("source", "", "only score results of this source (simulation, manual, import or all), entered and imported results by default")
+	dsn := flag.String("dsn", database.DSN(), "SQLite file or postgres:// URL, defaults to $LEAGUE_DSN")
+	flag.Parse()
+	if *source != "" && *source != "all" && !slices.Contains(models.PredictionSources, *source) {
+		log.Fatalf("Unknown source %q", *source)
+	}
+
+	// Initialize database
+	db := database.Open(*dsn)
+	err := db.InitDB()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	records, err := db.GetPredictionRecords(*season)
+	if err != nil {
+		log.Fatal(err)
+	}
+	records = models.FilterPredictionSource(records, *source)
+	if len(records) == 0 {
+		log.Fatal("No recorded predictions, enter or import some results first")
+	}
+
+	for _, report := range models.NewCalibrationReport(records, *buckets) {
+		fmt.Printf("Engine: %s (%d matches)\n", report.Engine, report.Matches)
+		fmt.Printf("  Brier score: %.4f  Log loss: %.4f  RPS: %.4f\n", report.BrierScore, report.LogLoss, report.RankedProbabilityScore)
+		fmt.Printf("  %-11s %6s %10s %10s\n", "Bucket", "Count", "Predicted", "Observed")
+		for _, bucket := range report.Buckets {
+			if bucket.Count == 0 {
+				continue
+			}
+			fmt.Printf("  %.2f-%.2f %6d %10.3f %10.3f\n", bucket.Lower, bucket.Upper, bucket.Count, bucket.MeanPredicted, bucket.ObservedFrequency)
+		}
+		fmt.Println()
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/cahitcaginkaratas/backend_insider/internal/database"
+)
+
+func main() {
+	dsn := flag.String("dsn", database.DSN(), "SQLite file to replace, defaults to $LEAGUE_DSN")
+	check := flag.Bool("check", false, "only check the backup")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: restore [flags] BACKUP")
+		fmt.Fprintln(os.Stderr, "Stop the server first, the database is replaced on disk.")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	backupPath := flag.Arg(0)
+
+	if *check {
+		version, err := database.CheckBackup(backupPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("%s is intact, schema version %d", backupPath, version)
+		return
+	}
+
+	if !database.IsSQLiteDSN(*dsn) {
+		log.Fatal(database.ErrBackupUnsupported)
+	}
+	version, previous, err := database.RestoreBackup(backupPath, *dsn)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Restored %s from %s, schema version %d", *dsn, backupPath, version)
+	if previous != "" {
+		log.Printf("The replaced database was moved to %s", previous)
+	}
+	if version < database.LatestVersion() {
+		log.Printf("Pending migrations up to version %d are applied when the server starts", database.LatestVersion())
+	}
+}
@@ -0,0 +1,296 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrBackupUnsupported is returned when backing up a database that is not SQLite
+var ErrBackupUnsupported = errors.New("backups are only supported for SQLite databases")
+
+// DefaultBackupDir is the directory backups are written to unless configured
+const DefaultBackupDir = "backups"
+
+// DefaultBackupKeep is the number of backups kept unless configured
+const DefaultBackupKeep = 7
+
+// backupPrefix and backupSuffix name the backup files in a backup directory,
+// backupStamp is the layout of the time between them
+const (
+	backupPrefix = "league-"
+	backupSuffix = ".db"
+	backupStamp  = "20060102-150405.000"
+)
+
+// Snapshotter is implemented by databases that can be backed up while in use
+type Snapshotter interface {
+	Snapshot(dir string, keep int) (*Backup, []string, error)
+}
+
+// Backup is a snapshot file in a backup directory
+type Backup struct {
+	Path      string    `json:"path"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Backup writes a consistent snapshot of a SQLite database to a new file
+// with VACUUM INTO. Other connections can keep reading and writing while it
+// runs.
+func (s *GormDB) Backup(path string) error {
+	if s.db.Dialector.Name() != "sqlite" {
+		return ErrBackupUnsupported
+	}
+	_, err := os.Stat(path)
+	if err == nil {
+		return fmt.Errorf("backup %s already exists", path)
+	}
+	return s.db.Exec("VACUUM INTO ?", path).Error
+}
+
+// Snapshot backs up a SQLite database into a timestamped file in dir and
+// then deletes the oldest backups so that keep are left, a keep of 0 keeps
+// every backup. It returns the new backup and the deleted ones.
+func (s *GormDB) Snapshot(dir string, keep int) (*Backup, []string, error) {
+	err := os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now().UTC()
+	path, err := uniquePath(filepath.Join(dir, backupPrefix+now.Format(backupStamp)), backupSuffix)
+	if err != nil {
+		return nil, nil, err
+	}
+	err = s.Backup(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	removed, err := rotateBackups(dir, keep)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &Backup{Path: path, Size: info.Size(), CreatedAt: now}, removed, nil
+}
+
+// uniquePath returns base+ext, or base-2+ext, base-3+ext and so on when that
+// file already exists
+func uniquePath(base, ext string) (string, error) {
+	path := base + ext
+	for n := 2; ; n++ {
+		_, err := os.Stat(path)
+		if errors.Is(err, os.ErrNotExist) {
+			return path, nil
+		}
+		if err != nil {
+			return "", err
+		}
+		path = fmt.Sprintf("%s-%d%s", base, n, ext)
+	}
+}
+
+// ListBackups returns the backups in dir, oldest first
+func ListBackups(dir string) ([]Backup, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return []Backup{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	backups := []Backup{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, backupPrefix) || !strings.HasSuffix(name, backupSuffix) {
+			continue
+		}
+		stamp := strings.TrimSuffix(strings.TrimPrefix(name, backupPrefix), backupSuffix)
+		if len(stamp) > len(backupStamp) {
+			_, err := strconv.Atoi(strings.TrimPrefix(stamp[len(backupStamp):], "-"))
+			if err != nil {
+				continue
+			}
+			stamp = stamp[:len(backupStamp)]
+		}
+		createdAt, err := time.Parse(backupStamp, stamp)
+		if err != nil {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		backups = append(backups, Backup{Path: filepath.Join(dir, name), Size: info.Size(), CreatedAt: createdAt})
+	}
+	// Backups of the same millisecond are numbered by uniquePath, a longer
+	// name has a higher number
+	sort.Slice(backups, func(i, j int) bool {
+		if !backups[i].CreatedAt.Equal(backups[j].CreatedAt) {
+			return backups[i].CreatedAt.Before(backups[j].CreatedAt)
+		}
+		if len(backups[i].Path) != len(backups[j].Path) {
+			return len(backups[i].Path) < len(backups[j].Path)
+		}
+		return backups[i].Path < backups[j].Path
+	})
+	return backups, nil
+}
+
+// rotateBackups deletes the oldest backups in dir so that keep are left
+func rotateBackups(dir string, keep int) ([]string, error) {
+	if keep <= 0 {
+		return nil, nil
+	}
+	backups, err := ListBackups(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []string
+	for len(backups) > keep {
+		err = os.Remove(backups[0].Path)
+		if err != nil {
+			return removed, err
+		}
+		removed = append(removed, backups[0].Path)
+		backups = backups[1:]
+	}
+	return removed, nil
+}
+
+// CheckBackup opens a backup read-only and checks that it is an intact
+// league database whose schema this binary can migrate. It returns the
+// schema version of the backup.
+func CheckBackup(path string) (int, error) {
+	_, err := os.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+	// Escape the path so that ? and # in it do not end up in the URI query
+	backup := NewSQLiteDB("file:" + url.PathEscape(path) + "?mode=ro")
+	err = backup.Connect()
+	if err != nil {
+		return 0, err
+	}
+	sqlDB, err := backup.db.DB()
+	if err != nil {
+		return 0, err
+	}
+	defer sqlDB.Close()
+
+	var result string
+	err = backup.db.Raw("PRAGMA integrity_check").Scan(&result).Error
+	if err != nil {
+		return 0, fmt.Errorf("%s is not a SQLite database: %w", path, err)
+	}
+	if result != "ok" {
+		return 0, fmt.Errorf("%s is damaged: %s", path, result)
+	}
+
+	if !backup.db.Migrator().HasTable(&schemaMigration{}) {
+		return 0, fmt.Errorf("%s has no schema version, it is not a league database", path)
+	}
+	applied, err := appliedMigrations(backup.db)
+	if err != nil {
+		return 0, err
+	}
+	err = checkVersion(applied)
+	if err != nil {
+		return 0, err
+	}
+	return backup.CurrentVersion()
+}
+
+// RestoreBackup replaces the SQLite database at dbPath with a checked copy
+// of a backup. The database being replaced is kept next to it with a
+// .before-restore- suffix and the time of the restore. It returns the schema
+// version of the backup and where the replaced database was moved, which is
+// empty when there was none. The server must not be running.
+func RestoreBackup(backupPath, dbPath string) (int, string, error) {
+	version, err := CheckBackup(backupPath)
+	if err != nil {
+		return 0, "", err
+	}
+	aside, err := uniquePath(dbPath+".before-restore-"+time.Now().UTC().Format(backupStamp), "")
+	if err != nil {
+		return 0, "", err
+	}
+
+	// Copy next to the database first so the swap is a rename
+	tmpPath := dbPath + ".restore"
+	err = copyFile(backupPath, tmpPath)
+	if err != nil {
+		os.Remove(tmpPath)
+		return 0, "", err
+	}
+
+	// Move the old database aside together with its journal files, and
+	// back again if the restore cannot be completed
+	var moved []string
+	rollback := func() {
+		for _, suffix := range moved {
+			os.Rename(aside+suffix, dbPath+suffix)
+		}
+		os.Remove(tmpPath)
+	}
+	for _, suffix := range []string{"", "-journal", "-wal", "-shm"} {
+		_, err = os.Stat(dbPath + suffix)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err == nil {
+			err = os.Rename(dbPath+suffix, aside+suffix)
+		}
+		if err != nil {
+			rollback()
+			return 0, "", err
+		}
+		moved = append(moved, suffix)
+	}
+	err = os.Rename(tmpPath, dbPath)
+	if err != nil {
+		rollback()
+		return 0, "", err
+	}
+	if len(moved) == 0 || moved[0] != "" {
+		aside = ""
+	}
+	return version, aside, nil
+}
+
+// copyFile copies a file and syncs the copy to disk
+func copyFile(from, to string) error {
+	source, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	target, err := os.Create(to)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(target, source)
+	if err == nil {
+		err = target.Sync()
+	}
+	closeErr := target.Close()
+	if err != nil {
+		return err
+	}
+	return closeErr
+}
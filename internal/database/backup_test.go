@@ -0,0 +1,176 @@
+package database
+
+import (
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cahitcaginkaratas/backend_insider/internal/models"
+)
+
+// newBackupDB returns a seeded SQLite database and a backup directory whose
+// name needs escaping in a SQLite URI
+func newBackupDB(t *testing.T) (*GormDB, string, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "league.db")
+	db := NewSQLiteDB(path)
+	if err := db.InitDB(); err != nil {
+		t.Fatal(err)
+	}
+	seed, err := DefaultSeed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Seed(db, seed); err != nil {
+		t.Fatal(err)
+	}
+	return db, path, filepath.Join(filepath.Dir(path), "backups?mode=memory#1")
+}
+
+// countTeams opens the SQLite database at path and counts its teams
+func countTeams(t *testing.T, path string) int {
+	t.Helper()
+	db := NewSQLiteDB(path)
+	if err := db.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqlDB.Close()
+	teams, err := db.GetTeams()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(teams)
+}
+
+func TestBackupRotateAndRestore(t *testing.T) {
+	db, path, dir := newBackupDB(t)
+
+	// Three backups in quick succession, often in the same millisecond
+	var made []string
+	for i := 0; i < 3; i++ {
+		backup, removed, err := db.Snapshot(dir, 2)
+		if err != nil {
+			t.Fatalf("backup %d: %v", i+1, err)
+		}
+		made = append(made, backup.Path)
+		if i == 2 && (len(removed) != 1 || removed[0] != made[0]) {
+			t.Errorf("third backup removed %v, want the first backup %s", removed, made[0])
+		}
+	}
+	if made[0] == made[1] || made[1] == made[2] {
+		t.Fatalf("backups share a name: %v", made)
+	}
+	backups, err := ListBackups(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 2 || backups[0].Path != made[1] || backups[1].Path != made[2] {
+		t.Fatalf("got backups %+v, want the last two of %v", backups, made)
+	}
+
+	version, err := CheckBackup(backups[1].Path)
+	if err != nil || version != LatestVersion() {
+		t.Fatalf("check: got version %d, %v, want %d", version, err, LatestVersion())
+	}
+
+	// A team added after the backup is gone after the restore, and the
+	// replaced databases of two restores are both kept
+	if err := db.SaveTeam(models.NewTeam("Extra", 50)); err != nil {
+		t.Fatal(err)
+	}
+	var asides []string
+	for i := 0; i < 2; i++ {
+		version, aside, err := RestoreBackup(backups[1].Path, path)
+		if err != nil || version != LatestVersion() {
+			t.Fatalf("restore %d: got version %d, %v", i+1, version, err)
+		}
+		asides = append(asides, aside)
+	}
+	if asides[0] == "" || asides[0] == asides[1] {
+		t.Fatalf("replaced databases were moved to %v, want two different files", asides)
+	}
+	if got := countTeams(t, path); got != 4 {
+		t.Errorf("restored database has %d teams, want 4", got)
+	}
+	if got := countTeams(t, asides[0]); got != 5 {
+		t.Errorf("database replaced by the first restore has %d teams, want 5", got)
+	}
+}
+
+func TestRestoreRejectsNewerSchema(t *testing.T) {
+	db, path, dir := newBackupDB(t)
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	backup := filepath.Join(dir, "newer.db")
+	if err := db.Backup(backup); err != nil {
+		t.Fatal(err)
+	}
+
+	// Mark the backup as written by a newer binary
+	newer := NewSQLiteDB("file:" + url.PathEscape(backup))
+	if err := newer.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	err := newer.db.Create(&schemaMigration{Version: LatestVersion() + 1, Name: "future", AppliedAt: time.Now()}).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := newer.db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.Close()
+
+	_, _, err = RestoreBackup(backup, path)
+	if !errors.Is(err, ErrSchemaTooNew) {
+		t.Fatalf("got %v, want %v", err, ErrSchemaTooNew)
+	}
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if name := entry.Name(); name != "league.db" && name != filepath.Base(dir) {
+			t.Errorf("rejected restore left %s behind", name)
+		}
+	}
+	if got := countTeams(t, path); got != 4 {
+		t.Errorf("database has %d teams after a rejected restore, want 4", got)
+	}
+}
+
+func TestListBackupsOfTheSameMillisecond(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "league-20240501-120000.000")
+	for _, name := range []string{base + "-10.db", base + ".db", base + "-9.db", base + "-2.db"} {
+		if err := os.WriteFile(name, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	next, err := uniquePath(base, ".db")
+	if err != nil || next != base+"-3.db" {
+		t.Errorf("got %s, %v, want the free name %s", next, err, base+"-3.db")
+	}
+
+	backups, err := ListBackups(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{base + ".db", base + "-2.db", base + "-9.db", base + "-10.db"}
+	if len(backups) != len(want) {
+		t.Fatalf("got %d backups, want %d", len(backups), len(want))
+	}
+	for i := range want {
+		if backups[i].Path != want[i] {
+			t.Errorf("backup %d is %s, want %s", i+1, backups[i].Path, want[i])
+		}
+	}
+}
@@ -0,0 +1,934 @@
+// Package conformance checks that a database.Database backend behaves like
+// every other backend. The checks reset the database, so only run them
+// against a scratch database.
+package conformance
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/cahitcaginkaratas/backend_insider/internal/database"
+	"github.com/cahitcaginkaratas/backend_insider/internal/models"
+)
+
+// Check is a single conformance check
+type Check struct {
+	Name string
+	Run  func(db database.Database) error
+}
+
+// Result is the outcome of a check, Err is nil when it passed
+type Result struct {
+	Name string
+	Err  error
+}
+
+// Checks lists every conformance check
+var Checks = []Check{
+	{"seed", checkSeed},
+	{"seasons", checkSeasons},
+	{"missing records", checkMissing},
+	{"teams", checkTeams},
+	{"queries", checkQueries},
+	{"match versions", checkVersions},
+	{"league stats", checkLeagueStats},
+	{"transactions", checkTransactions},
+	{"audits and events", checkAuditsAndEvents},
+	{"rating fits", checkRatingFits},
+	{"prediction records", checkPredictionRecords},
+	{"scenarios", checkScenarios},
+	{"clear season", checkClearSeason},
+	{"find matches", checkFindMatches},
+}
+
+// Run resets the database to the built-in teams before every check and runs
+// all checks. The database must already be initialized.
+func Run(db database.Database) []Result {
+	seed, seedErr := database.DefaultSeed()
+	results := make([]Result, 0, len(Checks))
+	for _, check := range Checks {
+		err := seedErr
+		if err == nil {
+			err = db.ResetDatabase(seed)
+		}
+		if err == nil {
+			err = check.Run(db)
+		}
+		results = append(results, Result{Name: check.Name, Err: err})
+	}
+	return results
+}
+
+// checkSeed checks the teams, fixtures and season created by a reset
+func checkSeed(db database.Database) error {
+	teams, err := db.GetTeams()
+	if err != nil {
+		return err
+	}
+	if len(teams) != 4 {
+		return fmt.Errorf("got %d teams, want 4", len(teams))
+	}
+
+	matches, err := db.GetMatches()
+	if err != nil {
+		return err
+	}
+	if len(matches) != 12 {
+		return fmt.Errorf("got %d matches, want 12", len(matches))
+	}
+	for _, match := range matches {
+		if match.Played || match.SeasonID == 0 || match.Version != 0 {
+			return fmt.Errorf("match %d is not a fresh fixture of a season", match.ID)
+		}
+		if match.HomeTeam.ID != match.HomeTeamID || match.AwayTeam.ID != match.AwayTeamID {
+			return fmt.Errorf("match %d is missing its teams", match.ID)
+		}
+	}
+
+	seasons, err := db.GetSeasons()
+	if err != nil {
+		return err
+	}
+	if len(seasons) != 1 {
+		return fmt.Errorf("got %d seasons, want 1", len(seasons))
+	}
+	current, err := db.GetCurrentSeason()
+	if err != nil {
+		return err
+	}
+	if current.ID != seasons[0].ID {
+		return fmt.Errorf("current season is %d, want %d", current.ID, seasons[0].ID)
+	}
+	return nil
+}
+
+// checkSeasons checks that a new season becomes the current one and that
+// matches without a season are saved in it
+func checkSeasons(db database.Database) error {
+	first, err := db.GetCurrentSeason()
+	if err != nil {
+		return err
+	}
+	season := models.NewSeason("Season 2")
+	err = db.SaveSeason(season)
+	if err != nil {
+		return err
+	}
+	if season.ID == 0 || season.ID == first.ID {
+		return fmt.Errorf("SaveSeason set the season ID to %d", season.ID)
+	}
+	current, err := db.GetCurrentSeason()
+	if err != nil {
+		return err
+	}
+	if current == nil || current.ID != season.ID {
+		return fmt.Errorf("current season is %v, want %d", current, season.ID)
+	}
+
+	teams, err := db.GetTeams()
+	if err != nil {
+		return err
+	}
+	match := models.NewMatch(1, &teams[0], &teams[1])
+	err = db.SaveMatch(match)
+	if err != nil {
+		return err
+	}
+	if match.SeasonID != season.ID {
+		return fmt.Errorf("match without a season was saved in season %d, want %d", match.SeasonID, season.ID)
+	}
+	return nil
+}
+
+// checkMissing checks that looking up missing records returns nil without an error
+func checkMissing(db database.Database) error {
+	match, err := db.GetMatch(9999)
+	if err != nil || match != nil {
+		return fmt.Errorf("GetMatch of a missing match returned %v, %v", match, err)
+	}
+	team, err := db.GetTeam(9999)
+	if err != nil || team != nil {
+		return fmt.Errorf("GetTeam of a missing team returned %v, %v", team, err)
+	}
+	season, err := db.GetSeason(9999)
+	if err != nil || season != nil {
+		return fmt.Errorf("GetSeason of a missing season returned %v, %v", season, err)
+	}
+	scenario, err := db.GetScenario(9999)
+	if err != nil || scenario != nil {
+		return fmt.Errorf("GetScenario of a missing scenario returned %v, %v", scenario, err)
+	}
+	fit, err := db.GetLatestRatingFit()
+	if err != nil || fit != nil {
+		return fmt.Errorf("GetLatestRatingFit without fits returned %v, %v", fit, err)
+	}
+	return nil
+}
+
+// checkTeams checks saving and updating teams
+func checkTeams(db database.Database) error {
+	team := models.NewTeam("Conformance", 60)
+	err := db.SaveTeam(team)
+	if err != nil {
+		return err
+	}
+	if team.ID == 0 {
+		return errors.New("SaveTeam did not set the team ID")
+	}
+
+	team.Strength = 65
+	err = db.UpdateTeam(team)
+	if err != nil {
+		return err
+	}
+	teams, err := db.GetTeams()
+	if err != nil {
+		return err
+	}
+	if len(teams) != 5 {
+		return fmt.Errorf("got %d teams, want 5", len(teams))
+	}
+	for _, stored := range teams {
+		if stored.ID == team.ID && (stored.Name != "Conformance" || stored.Strength != 65) {
+			return fmt.Errorf("got team %+v after an update, want strength 65", stored)
+		}
+	}
+	return nil
+}
+
+// checkQueries checks the match queries by week and season
+func checkQueries(db database.Database) error {
+	season, err := db.GetCurrentSeason()
+	if err != nil {
+		return err
+	}
+	matches, err := db.GetMatchesBySeason(season.ID)
+	if err != nil {
+		return err
+	}
+	if len(matches) != 12 {
+		return fmt.Errorf("got %d matches in the season, want 12", len(matches))
+	}
+	none, err := db.GetMatchesBySeason(season.ID + 1)
+	if err != nil {
+		return err
+	}
+	if len(none) != 0 {
+		return fmt.Errorf("got %d matches in a missing season, want 0", len(none))
+	}
+
+	week, err := db.GetSeasonWeek(season.ID, 1)
+	if err != nil {
+		return err
+	}
+	if len(week) == 0 {
+		return errors.New("week 1 has no matches")
+	}
+	for _, match := range week {
+		if match.Week != 1 {
+			return fmt.Errorf("match %d of week %d returned for week 1", match.ID, match.Week)
+		}
+	}
+
+	match, err := db.GetMatch(week[0].ID)
+	if err != nil {
+		return err
+	}
+	if match == nil || match.ID != week[0].ID || match.HomeTeam.Name == "" {
+		return fmt.Errorf("GetMatch(%d) returned %v", week[0].ID, match)
+	}
+
+	team, err := db.GetTeam(match.HomeTeamID)
+	if err != nil {
+		return err
+	}
+	if team == nil || team.Name != match.HomeTeam.Name {
+		return fmt.Errorf("GetTeam(%d) returned %v", match.HomeTeamID, team)
+	}
+	teamMatches, err := db.GetTeamMatches(season.ID, team.ID)
+	if err != nil {
+		return err
+	}
+	want := 0
+	for _, seasonMatch := range matches {
+		if seasonMatch.HomeTeamID == team.ID || seasonMatch.AwayTeamID == team.ID {
+			want++
+		}
+	}
+	if len(teamMatches) != want {
+		return fmt.Errorf("got %d matches of team %d, want %d", len(teamMatches), team.ID, want)
+	}
+	for i, teamMatch := range teamMatches {
+		if teamMatch.HomeTeamID != team.ID && teamMatch.AwayTeamID != team.ID {
+			return fmt.Errorf("match %d of other teams returned for team %d", teamMatch.ID, team.ID)
+		}
+		if i > 0 && teamMatch.Week < teamMatches[i-1].Week {
+			return fmt.Errorf("matches of team %d are not ordered by week", team.ID)
+		}
+	}
+
+	seasonWeek, err := db.GetSeasonWeek(season.ID, 1)
+	if err != nil {
+		return err
+	}
+	if len(seasonWeek) != len(week) || seasonWeek[0].HomeTeam.Name == "" {
+		return fmt.Errorf("got %d matches in week 1 of the season, want %d", len(seasonWeek), len(week))
+	}
+	none, err = db.GetSeasonWeek(season.ID+1, 1)
+	if err != nil {
+		return err
+	}
+	if len(none) != 0 {
+		return fmt.Errorf("got %d matches in week 1 of a missing season, want 0", len(none))
+	}
+
+	match.UpdateResult(2, 1)
+	err = db.UpdateMatch(match)
+	if err != nil {
+		return err
+	}
+	weeks, err := db.GetWeeks(season.ID)
+	if err != nil {
+		return err
+	}
+	weekMatches := make(map[int]int)
+	for _, seasonMatch := range matches {
+		weekMatches[seasonMatch.Week]++
+	}
+	if len(weeks) != len(weekMatches) {
+		return fmt.Errorf("got %d weeks, want %d", len(weeks), len(weekMatches))
+	}
+	for i, summary := range weeks {
+		if i > 0 && summary.Week <= weeks[i-1].Week {
+			return errors.New("weeks are not ordered by week")
+		}
+		want := models.WeekSummary{Week: summary.Week, Matches: weekMatches[summary.Week]}
+		if summary.Week == match.Week {
+			want.Played = 1
+		}
+		want.Complete = want.Played == want.Matches
+		if summary != want {
+			return fmt.Errorf("got week summary %+v, want %+v", summary, want)
+		}
+	}
+	return nil
+}
+
+// checkVersions checks that updates increment the version and stale updates fail
+func checkVersions(db database.Database) error {
+	match, err := db.GetMatch(1)
+	if err != nil {
+		return err
+	}
+	stale := *match
+
+	match.UpdateResult(2, 1)
+	err = db.UpdateMatch(match)
+	if err != nil {
+		return err
+	}
+	if match.Version != 1 {
+		return fmt.Errorf("version is %d after an update, want 1", match.Version)
+	}
+
+	stale.UpdateResult(0, 5)
+	err = db.UpdateMatch(&stale)
+	if !errors.Is(err, database.ErrVersionConflict) {
+		return fmt.Errorf("stale update returned %v, want ErrVersionConflict", err)
+	}
+	if stale.Version != 0 {
+		return fmt.Errorf("failed update changed the version to %d", stale.Version)
+	}
+
+	stored, err := db.GetMatch(1)
+	if err != nil {
+		return err
+	}
+	if !stored.Played || stored.HomeGoals != 2 || stored.AwayGoals != 1 || stored.Version != 1 {
+		return fmt.Errorf("stored match is %d-%d played=%v version %d, want 2-1 played version 1",
+			stored.HomeGoals, stored.AwayGoals, stored.Played, stored.Version)
+	}
+	return nil
+}
+
+// checkLeagueStats checks the standings query against the table calculated in Go
+func checkLeagueStats(db database.Database) error {
+	matches, err := db.GetMatches()
+	if err != nil {
+		return err
+	}
+	results := [][2]int{{2, 1}, {0, 0}, {1, 3}, {4, 4}, {2, 0}, {1, 1}}
+	for i, result := range results {
+		matches[i].UpdateResult(result[0], result[1])
+		err = db.UpdateMatch(&matches[i])
+		if err != nil {
+			return err
+		}
+	}
+
+	teams, err := db.GetTeams()
+	if err != nil {
+		return err
+	}
+
+	// The results and teams of another season do not count
+	other := models.NewSeason("Other")
+	err = db.SaveSeason(other)
+	if err != nil {
+		return err
+	}
+	newcomer := &models.Team{Name: "Newcomer", Strength: 50}
+	err = db.SaveTeam(newcomer)
+	if err != nil {
+		return err
+	}
+	otherMatch := models.NewMatch(1, &teams[0], newcomer)
+	otherMatch.SeasonID = other.ID
+	err = db.SaveMatch(otherMatch)
+	if err != nil {
+		return err
+	}
+	otherMatch.UpdateResult(5, 0)
+	err = db.UpdateMatch(otherMatch)
+	if err != nil {
+		return err
+	}
+
+	seasonTeams, err := db.GetSeasonTeams(other.ID)
+	if err != nil {
+		return err
+	}
+	if len(seasonTeams) != 2 || seasonTeams[0].ID != teams[0].ID || seasonTeams[1].ID != newcomer.ID {
+		return fmt.Errorf("got teams %+v for the other season, want %s and %s", seasonTeams, teams[0].Name, newcomer.Name)
+	}
+	seasonTeams, err = db.GetSeasonTeams(matches[0].SeasonID)
+	if err != nil {
+		return err
+	}
+	if len(seasonTeams) != len(teams) {
+		return fmt.Errorf("got %d teams for the season, want %d", len(seasonTeams), len(teams))
+	}
+
+	stats, err := db.GetLeagueStats(matches[0].SeasonID)
+	if err != nil {
+		return err
+	}
+	want := models.NewTable(teams, matches)
+	if len(stats) != len(want) {
+		return fmt.Errorf("got %d rows, want %d", len(stats), len(want))
+	}
+	for i := range want {
+		got := stats[i]
+		if got.Position != want[i].Position || got.TeamID != want[i].TeamID || got.Played != want[i].Played ||
+			got.Won != want[i].Won || got.Drawn != want[i].Drawn || got.Lost != want[i].Lost ||
+			got.GoalsFor != want[i].GoalsFor || got.GoalsAgainst != want[i].GoalsAgainst ||
+			got.GoalDifference != want[i].GoalDifference || got.Points != want[i].Points {
+			return fmt.Errorf("row %d is %+v, want %+v", i+1, got, want[i])
+		}
+	}
+	return nil
+}
+
+// checkTransactions checks commit, rollback and nested rollback of WithTx
+func checkTransactions(db database.Database) error {
+	errRollback := errors.New("rollback")
+
+	err := db.WithTx(func(tx database.Database) error {
+		match, err := tx.GetMatch(1)
+		if err != nil {
+			return err
+		}
+		match.UpdateResult(3, 0)
+		err = tx.UpdateMatch(match)
+		if err != nil {
+			return err
+		}
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		return fmt.Errorf("WithTx returned %v, want the error of fn", err)
+	}
+	match, err := db.GetMatch(1)
+	if err != nil {
+		return err
+	}
+	if match.Played {
+		return errors.New("rolled back update was stored")
+	}
+
+	err = db.WithTx(func(tx database.Database) error {
+		match, err := tx.GetMatch(1)
+		if err != nil {
+			return err
+		}
+		match.UpdateResult(3, 0)
+		err = tx.UpdateMatch(match)
+		if err != nil {
+			return err
+		}
+
+		// A failed nested transaction only rolls back its own changes
+		nested := tx.WithTx(func(tx database.Database) error {
+			match, err := tx.GetMatch(2)
+			if err != nil {
+				return err
+			}
+			match.UpdateResult(1, 1)
+			err = tx.UpdateMatch(match)
+			if err != nil {
+				return err
+			}
+			return errRollback
+		})
+		if !errors.Is(nested, errRollback) {
+			return fmt.Errorf("nested WithTx returned %v, want the error of fn", nested)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	first, err := db.GetMatch(1)
+	if err != nil {
+		return err
+	}
+	second, err := db.GetMatch(2)
+	if err != nil {
+		return err
+	}
+	if !first.Played || first.HomeGoals != 3 {
+		return errors.New("committed update was not stored")
+	}
+	if second.Played {
+		return errors.New("rolled back nested update was stored")
+	}
+	return nil
+}
+
+// checkAuditsAndEvents checks the audit log and the event stream
+func checkAuditsAndEvents(db database.Database) error {
+	season, err := db.GetCurrentSeason()
+	if err != nil {
+		return err
+	}
+	events, err := db.GetEvents(season.ID)
+	if err != nil {
+		return err
+	}
+	if len(events) != 12 {
+		return fmt.Errorf("got %d events after a reset, want 12 fixtures", len(events))
+	}
+	for _, event := range events {
+		if event.Type != models.EventFixtureScheduled {
+			return fmt.Errorf("event %d is %s, want %s", event.ID, event.Type, models.EventFixtureScheduled)
+		}
+	}
+
+	match, err := db.GetMatch(1)
+	if err != nil {
+		return err
+	}
+	for _, result := range [][2]int{{1, 0}, {2, 2}} {
+		old := *match
+		match.UpdateResult(result[0], result[1])
+		err = db.UpdateMatch(match)
+		if err != nil {
+			return err
+		}
+		err = db.SaveMatchAudit(models.NewMatchAudit(&old, match, "manual", "conformance"))
+		if err != nil {
+			return err
+		}
+		err = db.AppendEvent(models.NewMatchEvent(&old, match, "manual", "conformance"))
+		if err != nil {
+			return err
+		}
+	}
+
+	audits, err := db.GetMatchAudits(match.ID)
+	if err != nil {
+		return err
+	}
+	if len(audits) != 2 || audits[0].NewHomeGoals != 1 || audits[1].NewHomeGoals != 2 {
+		return fmt.Errorf("got audits %+v, want the two changes in order", audits)
+	}
+	audits[1].Undone = true
+	err = db.UpdateMatchAudit(&audits[1])
+	if err != nil {
+		return err
+	}
+	seasonAudits, err := db.GetSeasonAudits(season.ID)
+	if err != nil {
+		return err
+	}
+	if len(seasonAudits) != 2 || !seasonAudits[1].Undone {
+		return fmt.Errorf("got season audits %+v, want the second change undone", seasonAudits)
+	}
+
+	events, err = db.GetEvents(season.ID)
+	if err != nil {
+		return err
+	}
+	state := models.Replay(events)
+	for _, replayed := range state.Matches {
+		if replayed.ID == match.ID && (replayed.HomeGoals != 2 || replayed.AwayGoals != 2 || !replayed.Played) {
+			return fmt.Errorf("replayed match is %d-%d, want 2-2", replayed.HomeGoals, replayed.AwayGoals)
+		}
+	}
+	return nil
+}
+
+// checkRatingFits checks that a fit is stored together with the team ratings
+func checkRatingFits(db database.Database) error {
+	teams, err := db.GetTeams()
+	if err != nil {
+		return err
+	}
+	for i := range teams {
+		teams[i].Attack = 0.1 * float64(i+1)
+		teams[i].Defence = -0.1 * float64(i+1)
+	}
+
+	for _, rho := range []float64{-0.05, -0.1} {
+		err = db.SaveRatingFit(&models.RatingFit{Source: "conformance", HomeAdvantage: 0.25, Rho: rho}, teams)
+		if err != nil {
+			return err
+		}
+	}
+	fit, err := db.GetLatestRatingFit()
+	if err != nil {
+		return err
+	}
+	if fit == nil || fit.Rho != -0.1 {
+		return fmt.Errorf("latest fit is %+v, want rho -0.1", fit)
+	}
+
+	stored, err := db.GetTeams()
+	if err != nil {
+		return err
+	}
+	for _, team := range stored {
+		for _, want := range teams {
+			if team.ID == want.ID && (team.Attack != want.Attack || team.Defence != want.Defence) {
+				return fmt.Errorf("team %d has ratings %v/%v, want %v/%v", team.ID, team.Attack, team.Defence, want.Attack, want.Defence)
+			}
+		}
+	}
+	return nil
+}
+
+// checkPredictionRecords checks that prediction records are returned in
+// order, filtered by season, and replaced for the same match and engine
+func checkPredictionRecords(db database.Database) error {
+	for _, record := range []models.PredictionRecord{
+		{SeasonID: 1, MatchID: 1, Week: 1, Engine: "strength", HomeWin: 0.5, Draw: 0.3, AwayWin: 0.2},
+		{SeasonID: 1, MatchID: 2, Week: 2, Engine: "strength", HomeWin: 0.5, Draw: 0.3, AwayWin: 0.2},
+		{SeasonID: 2, MatchID: 3, Week: 1, Engine: "strength", HomeWin: 0.4, Draw: 0.3, AwayWin: 0.3},
+		{SeasonID: 1, MatchID: 1, Week: 1, Engine: "strength", HomeWin: 0.6, Draw: 0.2, AwayWin: 0.2, HomeGoals: 2},
+	} {
+		err := db.SavePredictionRecord(&record)
+		if err != nil {
+			return err
+		}
+	}
+
+	records, err := db.GetPredictionRecords(0)
+	if err != nil {
+		return err
+	}
+	if len(records) != 3 || records[0].MatchID != 1 || records[1].MatchID != 2 || records[2].MatchID != 3 {
+		return fmt.Errorf("got records %+v, want matches 1, 2 and 3", records)
+	}
+	if records[0].HomeWin != 0.6 || records[0].HomeGoals != 2 {
+		return fmt.Errorf("record of match 1 is %+v, want the one saved last", records[0])
+	}
+
+	records, err = db.GetPredictionRecords(1)
+	if err != nil {
+		return err
+	}
+	if len(records) != 2 || records[0].MatchID != 1 || records[1].MatchID != 2 {
+		return fmt.Errorf("got records %+v of season 1, want matches 1 and 2", records)
+	}
+
+	err = db.DeletePredictionRecords(1)
+	if err != nil {
+		return err
+	}
+	records, err = db.GetPredictionRecords(0)
+	if err != nil {
+		return err
+	}
+	if len(records) != 2 || records[0].MatchID != 2 || records[1].MatchID != 3 {
+		return fmt.Errorf("got records %+v after deleting match 1, want matches 2 and 3", records)
+	}
+	return nil
+}
+
+// checkScenarios checks creating, overriding and deleting scenarios
+func checkScenarios(db database.Database) error {
+	scenario := &models.Scenario{
+		SeasonID:  1,
+		Name:      "conformance",
+		Overrides: []models.ScenarioOverride{{MatchID: 1, HomeGoals: 1, AwayGoals: 0}},
+	}
+	err := db.SaveScenario(scenario)
+	if err != nil {
+		return err
+	}
+
+	// Saving an override for the same match replaces it
+	err = db.SaveScenarioOverride(&models.ScenarioOverride{ScenarioID: scenario.ID, MatchID: 1, HomeGoals: 0, AwayGoals: 3})
+	if err != nil {
+		return err
+	}
+	err = db.SaveScenarioOverride(&models.ScenarioOverride{ScenarioID: scenario.ID, MatchID: 2, HomeGoals: 2, AwayGoals: 2})
+	if err != nil {
+		return err
+	}
+	stored, err := db.GetScenario(scenario.ID)
+	if err != nil {
+		return err
+	}
+	if stored == nil || stored.SeasonID != 1 || len(stored.Overrides) != 2 {
+		return fmt.Errorf("got scenario %+v, want season 1 with 2 overrides", stored)
+	}
+	for _, override := range stored.Overrides {
+		if override.MatchID == 1 && (override.HomeGoals != 0 || override.AwayGoals != 3) {
+			return fmt.Errorf("override of match 1 is %d-%d, want 0-3", override.HomeGoals, override.AwayGoals)
+		}
+	}
+
+	err = db.DeleteScenarioOverride(scenario.ID, 2)
+	if err != nil {
+		return err
+	}
+	stored, err = db.GetScenario(scenario.ID)
+	if err != nil {
+		return err
+	}
+	if len(stored.Overrides) != 1 {
+		return fmt.Errorf("got %d overrides after a delete, want 1", len(stored.Overrides))
+	}
+
+	scenarios, err := db.GetScenarios()
+	if err != nil {
+		return err
+	}
+	if len(scenarios) != 1 {
+		return fmt.Errorf("got %d scenarios, want 1", len(scenarios))
+	}
+
+	err = db.DeleteScenario(scenario.ID)
+	if err != nil {
+		return err
+	}
+	stored, err = db.GetScenario(scenario.ID)
+	if err != nil {
+		return err
+	}
+	if stored != nil {
+		return errors.New("deleted scenario still exists")
+	}
+	return nil
+}
+
+// checkClearSeason checks that clearing a season deletes its matches with
+// their history and keeps the teams, so new fixtures can be scheduled
+func checkClearSeason(db database.Database) error {
+	season, err := db.GetCurrentSeason()
+	if err != nil {
+		return err
+	}
+	match, err := db.GetMatch(1)
+	if err != nil {
+		return err
+	}
+	old := *match
+	match.UpdateResult(3, 1)
+	err = db.UpdateMatch(match)
+	if err != nil {
+		return err
+	}
+	err = db.SaveMatchAudit(models.NewMatchAudit(&old, match, "manual", "conformance"))
+	if err != nil {
+		return err
+	}
+	err = db.SavePredictionRecord(&models.PredictionRecord{SeasonID: season.ID, MatchID: match.ID, Engine: "strength", HomeWin: 1})
+	if err != nil {
+		return err
+	}
+	scenario := &models.Scenario{
+		Name:      "conformance",
+		Overrides: []models.ScenarioOverride{{MatchID: match.ID, HomeGoals: 0, AwayGoals: 1}},
+	}
+	err = db.SaveScenario(scenario)
+	if err != nil {
+		return err
+	}
+
+	err = db.ClearSeason(season.ID)
+	if err != nil {
+		return err
+	}
+	matches, err := db.GetMatchesBySeason(season.ID)
+	if err != nil {
+		return err
+	}
+	audits, err := db.GetSeasonAudits(season.ID)
+	if err != nil {
+		return err
+	}
+	events, err := db.GetEvents(season.ID)
+	if err != nil {
+		return err
+	}
+	records, err := db.GetPredictionRecords(season.ID)
+	if err != nil {
+		return err
+	}
+	if len(matches) != 0 || len(audits) != 0 || len(events) != 0 || len(records) != 0 {
+		return fmt.Errorf("got %d matches, %d audits, %d events and %d prediction records after clearing the season, want none",
+			len(matches), len(audits), len(events), len(records))
+	}
+	stored, err := db.GetScenario(scenario.ID)
+	if err != nil {
+		return err
+	}
+	if stored == nil || len(stored.Overrides) != 0 {
+		return fmt.Errorf("got scenario %+v, want it kept without overrides", stored)
+	}
+
+	teams, err := db.GetTeams()
+	if err != nil {
+		return err
+	}
+	if len(teams) != 4 {
+		return fmt.Errorf("got %d teams after clearing the season, want 4", len(teams))
+	}
+	err = database.ScheduleFixtures(db, season.ID, teams)
+	if err != nil {
+		return err
+	}
+	matches, err = db.GetMatchesBySeason(season.ID)
+	if err != nil {
+		return err
+	}
+	if len(matches) != 12 {
+		return fmt.Errorf("got %d matches after scheduling fixtures, want 12", len(matches))
+	}
+	return nil
+}
+
+// checkFindMatches checks the filters, sort orders and cursors of
+// FindMatches against filtering all matches
+func checkFindMatches(db database.Database) error {
+	season, err := db.GetCurrentSeason()
+	if err != nil {
+		return err
+	}
+	matches, err := db.GetMatches()
+	if err != nil {
+		return err
+	}
+	start := time.Date(2024, 8, 3, 15, 0, 0, 0, time.UTC)
+	for i := range matches {
+		// Matches of a week are played on the same day
+		matches[i].Date = start.AddDate(0, 0, 7*(matches[i].Week-1))
+		if matches[i].Week == 1 {
+			matches[i].UpdateResult(1, 0)
+		}
+		err = db.UpdateMatch(&matches[i])
+		if err != nil {
+			return err
+		}
+	}
+
+	byID := make(map[uint]*models.Match)
+	for i := range matches {
+		byID[matches[i].ID] = &matches[i]
+	}
+
+	unplayed := false
+	teamID := matches[0].HomeTeamID
+	queries := []struct {
+		query database.MatchQuery
+		keep  func(match *models.Match) bool
+	}{
+		{database.MatchQuery{}, func(*models.Match) bool { return true }},
+		{database.MatchQuery{SeasonID: season.ID, Sort: "-date"}, func(*models.Match) bool { return true }},
+		{database.MatchQuery{SeasonID: season.ID + 1}, func(*models.Match) bool { return false }},
+		{database.MatchQuery{TeamID: teamID, Sort: "-week"}, func(match *models.Match) bool {
+			return match.HomeTeamID == teamID || match.AwayTeamID == teamID
+		}},
+		{database.MatchQuery{TeamID: teamID, Venue: database.VenueAway, Sort: "date"}, func(match *models.Match) bool {
+			return match.AwayTeamID == teamID
+		}},
+		{database.MatchQuery{FromWeek: 1, ToWeek: 3, Played: &unplayed, Sort: "-id"}, func(match *models.Match) bool {
+			return match.Week <= 3 && !match.Played
+		}},
+		{database.MatchQuery{From: start.AddDate(0, 0, 7), Until: start.AddDate(0, 0, 21)}, func(match *models.Match) bool {
+			return match.Week == 2 || match.Week == 3
+		}},
+	}
+	for _, q := range queries {
+		field, desc, err := database.ParseMatchSort(q.query.Sort)
+		if err != nil {
+			return err
+		}
+		var want []uint
+		for i := range matches {
+			if q.keep(&matches[i]) {
+				want = append(want, matches[i].ID)
+			}
+		}
+		sort.Slice(want, func(i, j int) bool {
+			a, b := byID[want[i]], byID[want[j]]
+			less := a.ID < b.ID
+			switch {
+			case field == database.SortWeek && a.Week != b.Week:
+				less = a.Week < b.Week
+			case field == database.SortDate && !a.Date.Equal(b.Date):
+				less = a.Date.Before(b.Date)
+			}
+			return less != desc
+		})
+
+		// Read every page of two matches
+		var got []uint
+		query := q.query
+		query.Limit = 2
+		for {
+			page, err := db.FindMatches(query)
+			if err != nil {
+				return err
+			}
+			for _, match := range page {
+				if match.HomeTeam.ID != match.HomeTeamID {
+					return fmt.Errorf("match %d is missing its teams", match.ID)
+				}
+				got = append(got, match.ID)
+			}
+			if len(page) < query.Limit {
+				break
+			}
+			if len(got) > len(matches) {
+				return fmt.Errorf("FindMatches(%+v) does not stop paging", q.query)
+			}
+			query.After = database.NewMatchCursor(&page[len(page)-1])
+		}
+		if !slices.Equal(got, want) {
+			return fmt.Errorf("FindMatches(%+v) returned matches %v, want %v", q.query, got, want)
+		}
+	}
+
+	_, err = db.FindMatches(database.MatchQuery{Sort: "name"})
+	if err == nil {
+		return errors.New("FindMatches accepted an unknown sort")
+	}
+	return nil
+}
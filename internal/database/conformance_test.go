@@ -0,0 +1,102 @@
+package database_test
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"path/filepath"
+	"testing"
+
+	embeddedpostgres "github.com/fergusstrange/embedded-postgres"
+
+	"github.com/cahitcaginkaratas/backend_insider/internal/database"
+	"github.com/cahitcaginkaratas/backend_insider/internal/database/conformance"
+)
+
+// runConformance initializes the database and runs every conformance check
+// as a subtest, resetting the database before each one like conformance.Run
+func runConformance(t *testing.T, db database.Database) {
+	t.Helper()
+	if err := db.InitDB(); err != nil {
+		t.Fatalf("init: %v", err)
+	}
+	seed, err := database.DefaultSeed()
+	if err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+
+	for _, check := range conformance.Checks {
+		t.Run(check.Name, func(t *testing.T) {
+			if err := db.ResetDatabase(seed); err != nil {
+				t.Fatalf("reset: %v", err)
+			}
+			if err := check.Run(db); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+
+	// A reset seeds the teams it is given, not the built-in ones
+	t.Run("reset with a seed file", func(t *testing.T) {
+		custom := &database.SeedData{Teams: []database.SeedTeam{{Name: "Oak", Strength: 70}, {Name: "Pine", Strength: 50}}}
+		if err := db.ResetDatabase(custom); err != nil {
+			t.Fatalf("reset: %v", err)
+		}
+		teams, err := db.GetTeams()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(teams) != 2 || teams[0].Name != "Oak" || teams[1].Name != "Pine" {
+			t.Errorf("got teams %+v, want Oak and Pine", teams)
+		}
+	})
+}
+
+func TestConformanceSQLite(t *testing.T) {
+	runConformance(t, database.NewSQLiteDB(filepath.Join(t.TempDir(), "league.db")))
+}
+
+func TestConformanceMemory(t *testing.T) {
+	runConformance(t, database.NewMemoryDB())
+}
+
+func TestConformancePostgres(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping the embedded PostgreSQL server in short mode")
+	}
+
+	port, err := freePort()
+	if err != nil {
+		t.Skipf("skipping PostgreSQL, no free port: %v", err)
+	}
+	config := embeddedpostgres.DefaultConfig().
+		Port(port).
+		RuntimePath(t.TempDir()).
+		Logger(io.Discard)
+	server := embeddedpostgres.NewDatabase(config)
+	if err := server.Start(); err != nil {
+		t.Skipf("skipping PostgreSQL, the embedded server could not start: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := server.Stop(); err != nil {
+			t.Errorf("stop embedded PostgreSQL: %v", err)
+		}
+	})
+
+	runConformance(t, database.Open(config.GetConnectionURL()+"?sslmode=disable"))
+}
+
+// freePort returns a TCP port nothing listens on right now
+func freePort() (uint32, error) {
+	listener, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		return 0, err
+	}
+	defer listener.Close()
+
+	addr, ok := listener.Addr().(*net.TCPAddr)
+	if !ok {
+		return 0, fmt.Errorf("unexpected address %v", listener.Addr())
+	}
+	return uint32(addr.Port), nil
+}
@@ -1,164 +1,156 @@
 package database
 
 import (
+	"errors"
+	"os"
+	"strings"
+
 	"github.com/cahitcaginkaratas/backend_insider/internal/models"
-	"gorm.io/driver/sqlite"
-	"gorm.io/gorm"
 )
 
-// Database interface defines the methods for database operations
-type Database interface {
-	InitDB() error
-	GetTeams() ([]models.Team, error)
-	GetMatches() ([]models.Match, error)
-	GetLeagueStats() ([]models.TeamStats, error)
-	SaveTeam(team *models.Team) error
-	SaveMatch(match *models.Match) error
-	UpdateMatch(match *models.Match) error
-	GetMatchesByWeek(week int) ([]models.Match, error)
-	ResetDatabase() error
-}
+// DefaultDSN is the SQLite file used when no DSN is configured
+const DefaultDSN = "league.db"
 
-// SQLiteDB implements the Database interface using SQLite
-type SQLiteDB struct {
-	db *gorm.DB
+// DSN returns the configured DSN from the LEAGUE_DSN environment variable,
+// or DefaultDSN
+func DSN() string {
+	if dsn := os.Getenv("LEAGUE_DSN"); dsn != "" {
+		return dsn
+	}
+	return DefaultDSN
 }
 
-// NewSQLiteDB creates a new SQLite database instance
-func NewSQLiteDB() *SQLiteDB {
-	return &SQLiteDB{}
+// MemoryDSN selects the in-memory database
+const MemoryDSN = "memory"
+
+// Open creates the database for a DSN: PostgreSQL for postgres:// and
+// postgresql:// URLs and "host=... dbname=..." strings, the in-memory
+// database for MemoryDSN, otherwise a SQLite file. InitDB still has to be
+// called.
+func Open(dsn string) Database {
+	switch {
+	case isPostgresDSN(dsn):
+		return NewPostgresDB(dsn)
+	case dsn == MemoryDSN:
+		return NewMemoryDB()
+	default:
+		return NewSQLiteDB(dsn)
+	}
 }
 
-// InitDB initializes the database connection and creates tables
-func (s *SQLiteDB) InitDB() error {
-	db, err := gorm.Open(sqlite.Open("league.db"), &gorm.Config{})
-	if err != nil {
-		return err
+// isPostgresDSN reports whether a DSN is a PostgreSQL URL or a key/value
+// string with a host or dbname keyword
+func isPostgresDSN(dsn string) bool {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		return true
 	}
-
-	s.db = db
-
-	// Auto migrate the schema
-	err = s.db.AutoMigrate(&models.Team{}, &models.Match{})
-	if err != nil {
-		return err
+	for _, field := range strings.Fields(dsn) {
+		key, _, ok := strings.Cut(field, "=")
+		if ok && (key == "host" || key == "dbname") {
+			return true
+		}
 	}
-
-	return nil
+	return false
 }
 
-// GetTeams returns all teams
-func (s *SQLiteDB) GetTeams() ([]models.Team, error) {
-	var teams []models.Team
-	err := s.db.Find(&teams).Error
-	return teams, err
+// IsSQLiteDSN reports whether Open stores a DSN in a SQLite file
+func IsSQLiteDSN(dsn string) bool {
+	return !isPostgresDSN(dsn) && dsn != MemoryDSN
 }
 
-// GetMatches returns all matches
-func (s *SQLiteDB) GetMatches() ([]models.Match, error) {
-	var matches []models.Match
-	err := s.db.Preload("HomeTeam").Preload("AwayTeam").Find(&matches).Error
-	return matches, err
-}
+// ErrVersionConflict is returned by UpdateMatch when the match was changed
+// since it was read
+var ErrVersionConflict = errors.New("match was changed by another request")
 
-// GetLeagueStats returns the current league statistics
-func (s *SQLiteDB) GetLeagueStats() ([]models.TeamStats, error) {
-	var stats []models.TeamStats
-	// This is a simplified version - in a real system, you'd want to calculate this from matches
-	err := s.db.Raw(`
-		SELECT 
-			t.id as team_id,
-			t.name as team_name,
-			COUNT(m.id) as played,
-			SUM(CASE WHEN m.home_team_id = t.id AND m.home_goals > m.away_goals THEN 1
-				WHEN m.away_team_id = t.id AND m.away_goals > m.home_goals THEN 1
-				ELSE 0 END) as won,
-			SUM(CASE WHEN m.home_goals = m.away_goals THEN 1 ELSE 0 END) as drawn,
-			SUM(CASE WHEN m.home_team_id = t.id AND m.home_goals < m.away_goals THEN 1
-				WHEN m.away_team_id = t.id AND m.away_goals < m.home_goals THEN 1
-				ELSE 0 END) as lost,
-			SUM(CASE WHEN m.home_team_id = t.id THEN m.home_goals ELSE m.away_goals END) as goals_for,
-			SUM(CASE WHEN m.home_team_id = t.id THEN m.away_goals ELSE m.home_goals END) as goals_against,
-			SUM(CASE WHEN m.home_team_id = t.id THEN m.home_goals - m.away_goals ELSE m.away_goals - m.home_goals END) as goal_difference,
-			SUM(CASE 
-				WHEN m.home_team_id = t.id AND m.home_goals > m.away_goals THEN 3
-				WHEN m.away_team_id = t.id AND m.away_goals > m.home_goals THEN 3
-				WHEN m.home_goals = m.away_goals THEN 1
-				ELSE 0 END) as points
-		FROM teams t
-		LEFT JOIN matches m ON (m.home_team_id = t.id OR m.away_team_id = t.id) AND m.played = true
-		GROUP BY t.id, t.name
-		ORDER BY points DESC, goal_difference DESC, goals_for DESC
-	`).Scan(&stats).Error
-	return stats, err
-}
-
-// SaveTeam saves a team to the database
-func (s *SQLiteDB) SaveTeam(team *models.Team) error {
-	return s.db.Create(team).Error
-}
+// ErrNoSeason is returned by SaveMatch when a match without a season is
+// saved before any season exists
+var ErrNoSeason = errors.New("there is no season yet")
 
-// SaveMatch saves a match to the database
-func (s *SQLiteDB) SaveMatch(match *models.Match) error {
-	return s.db.Create(match).Error
-}
+// Database interface defines the methods for database operations
+type Database interface {
+	InitDB() error
 
-// UpdateMatch updates a match in the database
-func (s *SQLiteDB) UpdateMatch(match *models.Match) error {
-	return s.db.Save(match).Error
-}
+	// WithTx runs fn in a transaction that is committed when fn returns nil
+	// and rolled back otherwise. Everything that belongs to the transaction
+	// has to go through the Database passed to fn.
+	WithTx(fn func(tx Database) error) error
 
-// GetMatchesByWeek returns all matches for a specific week
-func (s *SQLiteDB) GetMatchesByWeek(week int) ([]models.Match, error) {
-	var matches []models.Match
-	err := s.db.Preload("HomeTeam").Preload("AwayTeam").Where("week = ?", week).Find(&matches).Error
-	return matches, err
-}
+	GetTeams() ([]models.Team, error)
 
-// ResetDatabase clears all data and reinitializes the database
-func (s *SQLiteDB) ResetDatabase() error {
-	// Drop all tables
-	err := s.db.Migrator().DropTable(&models.Match{}, &models.Team{})
-	if err != nil {
-		return err
-	}
+	// GetSeasonTeams returns the teams that have fixtures in a season
+	GetSeasonTeams(seasonID uint) ([]models.Team, error)
 
-	// Recreate tables
-	err = s.db.AutoMigrate(&models.Team{}, &models.Match{})
-	if err != nil {
-		return err
-	}
+	GetTeam(id uint) (*models.Team, error)
+	GetMatches() ([]models.Match, error)
 
-	// Initialize teams
-	teams := []*models.Team{
-		models.NewTeam("Manchester City", 90),
-		models.NewTeam("Liverpool", 85),
-		models.NewTeam("Arsenal", 80),
-		models.NewTeam("Chelsea", 75),
-	}
+	// FindMatches returns the matches that pass the filters of a query in
+	// its sort order, starting after its cursor
+	FindMatches(query MatchQuery) ([]models.Match, error)
 
-	// Save teams to database
-	for _, team := range teams {
-		err = s.SaveTeam(team)
-		if err != nil {
-			return err
-		}
-	}
+	GetMatch(id uint) (*models.Match, error)
 
-	// Create league and generate fixtures
-	league := models.NewLeague()
-	for _, team := range teams {
-		league.AddTeam(team)
-	}
-	league.GenerateFixtures()
+	// GetLeagueStats returns the league table of a season without points
+	// deductions
+	GetLeagueStats(seasonID uint) ([]models.TeamStats, error)
 
-	// Save matches to database
-	for _, match := range league.Matches {
-		err = s.SaveMatch(&match)
-		if err != nil {
-			return err
-		}
-	}
+	SaveTeam(team *models.Team) error
+	UpdateTeam(team *models.Team) error
+	SaveMatch(match *models.Match) error
+	UpdateMatch(match *models.Match) error
 
-	return nil
+	// ResetDatabase clears all data and seeds the database again with the
+	// teams of seed, the schema is kept
+	ResetDatabase(seed *SeedData) error
+	SaveSeason(season *models.Season) error
+	GetSeasons() ([]models.Season, error)
+
+	// GetCurrentSeason returns the season with the highest ID, or nil when
+	// there is no season yet
+	GetCurrentSeason() (*models.Season, error)
+
+	GetSeason(id uint) (*models.Season, error)
+	GetMatchesBySeason(seasonID uint) ([]models.Match, error)
+
+	// GetTeamMatches returns the home and away matches of a team in a
+	// season ordered by week
+	GetTeamMatches(seasonID, teamID uint) ([]models.Match, error)
+
+	// GetSeasonWeek returns the matches of one week of a season
+	GetSeasonWeek(seasonID uint, week int) ([]models.Match, error)
+
+	// GetWeeks summarizes every week of a season that has matches, ordered
+	// by week
+	GetWeeks(seasonID uint) ([]models.WeekSummary, error)
+
+	SaveRatingFit(fit *models.RatingFit, teams []models.Team) error
+	GetLatestRatingFit() (*models.RatingFit, error)
+
+	// SavePredictionRecord saves the prediction of a match, replacing the
+	// record of the same match and engine
+	SavePredictionRecord(record *models.PredictionRecord) error
+
+	// GetPredictionRecords returns the recorded predictions of a season, or
+	// of every season when seasonID is 0
+	GetPredictionRecords(seasonID uint) ([]models.PredictionRecord, error)
+
+	// DeletePredictionRecords deletes the recorded predictions of a match
+	DeletePredictionRecords(matchID uint) error
+
+	SaveMatchAudit(audit *models.MatchAudit) error
+	UpdateMatchAudit(audit *models.MatchAudit) error
+	GetMatchAudits(matchID uint) ([]models.MatchAudit, error)
+	GetSeasonAudits(seasonID uint) ([]models.MatchAudit, error)
+	AppendEvent(event *models.LeagueEvent) error
+	GetEvents(seasonID uint) ([]models.LeagueEvent, error)
+	SaveScenario(scenario *models.Scenario) error
+	GetScenarios() ([]models.Scenario, error)
+	GetScenario(id uint) (*models.Scenario, error)
+	SaveScenarioOverride(override *models.ScenarioOverride) error
+	DeleteScenarioOverride(scenarioID, matchID uint) error
+	DeleteScenario(id uint) error
+
+	// ClearSeason deletes the matches of a season together with their
+	// audits, prediction records, scenario overrides and the event stream
+	// of the season
+	ClearSeason(seasonID uint) error
 }
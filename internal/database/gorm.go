@@ -0,0 +1,522 @@
+package database
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/cahitcaginkaratas/backend_insider/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GormDB implements the Database interface with GORM, so every database
+// GORM has a driver for can be used. The queries stick to SQL that SQLite
+// and PostgreSQL both understand.
+type GormDB struct {
+	dialector gorm.Dialector
+	db        *gorm.DB
+}
+
+// Connect opens the database connection without touching the schema
+func (s *GormDB) Connect() error {
+	db, err := gorm.Open(s.dialector, &gorm.Config{})
+	if err != nil {
+		return err
+	}
+	s.db = db
+	return nil
+}
+
+// InitDB connects to the database and applies pending migrations. It fails
+// when the schema is newer than this binary.
+func (s *GormDB) InitDB() error {
+	err := s.Connect()
+	if err != nil {
+		return err
+	}
+
+	err = migrateUp(s.db, LatestVersion())
+	if err != nil {
+		return err
+	}
+
+	// Matches and scenarios stored before seasons existed belong to the
+	// current season
+	season, err := FirstSeason(s)
+	if err != nil {
+		return err
+	}
+	err = s.db.Model(&models.Match{}).Where("season_id = 0 OR season_id IS NULL").Update("season_id", season.ID).Error
+	if err != nil {
+		return err
+	}
+	err = s.db.Model(&models.Scenario{}).Where("season_id = 0 OR season_id IS NULL").Update("season_id", season.ID).Error
+	if err != nil {
+		return err
+	}
+
+	return s.backfillEvents()
+}
+
+// backfillEvents starts the event stream of matches stored before there was
+// one, with their fixture and their current result
+func (s *GormDB) backfillEvents() error {
+	var matches []models.Match
+	err := s.db.Where("id NOT IN (?)", s.db.Model(&models.LeagueEvent{}).Select("match_id")).Order("id").Find(&matches).Error
+	if err != nil || len(matches) == 0 {
+		return err
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		for i := range matches {
+			err := tx.Create(models.NewFixtureEvent(&matches[i], "system")).Error
+			if err != nil {
+				return err
+			}
+			if !matches[i].Played {
+				continue
+			}
+			scheduled := matches[i]
+			scheduled.Played = false
+			err = tx.Create(models.NewMatchEvent(&scheduled, &matches[i], "import", "system")).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// WithTx runs fn in a transaction, nested calls use savepoints
+func (s *GormDB) WithTx(fn func(tx Database) error) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&GormDB{db: tx})
+	})
+}
+
+// GetTeams returns all teams
+func (s *GormDB) GetTeams() ([]models.Team, error) {
+	var teams []models.Team
+	err := s.db.Find(&teams).Error
+	return teams, err
+}
+
+// GetSeasonTeams returns the teams that have fixtures in a season
+func (s *GormDB) GetSeasonTeams(seasonID uint) ([]models.Team, error) {
+	var teams []models.Team
+	home := s.db.Model(&models.Match{}).Select("home_team_id").Where("season_id = ?", seasonID)
+	away := s.db.Model(&models.Match{}).Select("away_team_id").Where("season_id = ?", seasonID)
+	err := s.db.Where("id IN (?) OR id IN (?)", home, away).Order("id").Find(&teams).Error
+	return teams, err
+}
+
+// GetTeam returns a single team or nil if it does not exist
+func (s *GormDB) GetTeam(id uint) (*models.Team, error) {
+	var team models.Team
+	err := s.db.First(&team, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &team, nil
+}
+
+// GetMatches returns all matches
+func (s *GormDB) GetMatches() ([]models.Match, error) {
+	var matches []models.Match
+	err := s.db.Preload("HomeTeam").Preload("AwayTeam").Find(&matches).Error
+	return matches, err
+}
+
+// FindMatches returns the matches that pass the filters of a query in its
+// sort order, starting after its cursor
+func (s *GormDB) FindMatches(query MatchQuery) ([]models.Match, error) {
+	db, err := query.scope(s.db.Preload("HomeTeam").Preload("AwayTeam"))
+	if err != nil {
+		return nil, err
+	}
+	var matches []models.Match
+	err = db.Find(&matches).Error
+	return matches, err
+}
+
+// GetMatchesBySeason returns all matches of a season
+func (s *GormDB) GetMatchesBySeason(seasonID uint) ([]models.Match, error) {
+	var matches []models.Match
+	err := s.db.Preload("HomeTeam").Preload("AwayTeam").Where("season_id = ?", seasonID).Find(&matches).Error
+	return matches, err
+}
+
+// GetTeamMatches returns the home and away matches of a team in a season
+// ordered by week
+func (s *GormDB) GetTeamMatches(seasonID, teamID uint) ([]models.Match, error) {
+	var matches []models.Match
+	err := s.db.Preload("HomeTeam").Preload("AwayTeam").
+		Where("season_id = ? AND (home_team_id = ? OR away_team_id = ?)", seasonID, teamID, teamID).
+		Order("week, id").Find(&matches).Error
+	return matches, err
+}
+
+// GetSeasonWeek returns the matches of one week of a season
+func (s *GormDB) GetSeasonWeek(seasonID uint, week int) ([]models.Match, error) {
+	var matches []models.Match
+	err := s.db.Preload("HomeTeam").Preload("AwayTeam").
+		Where("season_id = ? AND week = ?", seasonID, week).Order("id").Find(&matches).Error
+	return matches, err
+}
+
+// GetWeeks summarizes every week of a season that has matches, ordered by week
+func (s *GormDB) GetWeeks(seasonID uint) ([]models.WeekSummary, error) {
+	var weeks []models.WeekSummary
+	err := s.db.Model(&models.Match{}).
+		Select("week, COUNT(*) AS matches, COUNT(CASE WHEN played THEN 1 END) AS played").
+		Where("season_id = ?", seasonID).Group("week").Order("week").Scan(&weeks).Error
+	for i := range weeks {
+		weeks[i].Complete = weeks[i].Played == weeks[i].Matches
+	}
+	return weeks, err
+}
+
+// GetMatch returns a single match or nil if it does not exist
+func (s *GormDB) GetMatch(id uint) (*models.Match, error) {
+	var match models.Match
+	err := s.db.Preload("HomeTeam").Preload("AwayTeam").First(&match, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &match, nil
+}
+
+// GetLeagueStats returns the league table of a season without points deductions
+func (s *GormDB) GetLeagueStats(seasonID uint) ([]models.TeamStats, error) {
+	var stats []models.TeamStats
+	// This is a simplified version - in a real system, you'd want to calculate this from matches
+	err := s.db.Raw(`
+		SELECT 
+			t.id as team_id,
+			t.name as team_name,
+			COUNT(m.id) as played,
+			COALESCE(SUM(CASE WHEN m.home_team_id = t.id AND m.home_goals > m.away_goals THEN 1
+				WHEN m.away_team_id = t.id AND m.away_goals > m.home_goals THEN 1
+				ELSE 0 END), 0) as won,
+			COALESCE(SUM(CASE WHEN m.home_goals = m.away_goals THEN 1 ELSE 0 END), 0) as drawn,
+			COALESCE(SUM(CASE WHEN m.home_team_id = t.id AND m.home_goals < m.away_goals THEN 1
+				WHEN m.away_team_id = t.id AND m.away_goals < m.home_goals THEN 1
+				ELSE 0 END), 0) as lost,
+			COALESCE(SUM(CASE WHEN m.home_team_id = t.id THEN m.home_goals ELSE m.away_goals END), 0) as goals_for,
+			COALESCE(SUM(CASE WHEN m.home_team_id = t.id THEN m.away_goals ELSE m.home_goals END), 0) as goals_against,
+			COALESCE(SUM(CASE WHEN m.home_team_id = t.id THEN m.home_goals - m.away_goals ELSE m.away_goals - m.home_goals END), 0) as goal_difference,
+			COALESCE(SUM(CASE 
+				WHEN m.home_team_id = t.id AND m.home_goals > m.away_goals THEN 3
+				WHEN m.away_team_id = t.id AND m.away_goals > m.home_goals THEN 3
+				WHEN m.home_goals = m.away_goals THEN 1
+				ELSE 0 END), 0) as points
+		FROM teams t
+		LEFT JOIN matches m ON (m.home_team_id = t.id OR m.away_team_id = t.id) AND m.played = true AND m.season_id = ?
+		WHERE t.id IN (SELECT home_team_id FROM matches WHERE season_id = ?)
+			OR t.id IN (SELECT away_team_id FROM matches WHERE season_id = ?)
+		GROUP BY t.id, t.name
+		ORDER BY points DESC, goal_difference DESC, goals_for DESC, t.name
+	`, seasonID, seasonID, seasonID).Scan(&stats).Error
+	for i := range stats {
+		stats[i].Position = i + 1
+	}
+	return stats, err
+}
+
+// SaveTeam saves a team to the database
+func (s *GormDB) SaveTeam(team *models.Team) error {
+	return s.db.Create(team).Error
+}
+
+// UpdateTeam updates the name and strength of a team
+func (s *GormDB) UpdateTeam(team *models.Team) error {
+	return s.db.Model(team).Select("name", "strength").Updates(team).Error
+}
+
+// SaveMatch saves a match to the database, in the current season unless one is set
+func (s *GormDB) SaveMatch(match *models.Match) error {
+	if match.SeasonID == 0 {
+		season, err := s.GetCurrentSeason()
+		if err != nil {
+			return err
+		}
+		if season == nil {
+			return ErrNoSeason
+		}
+		match.SeasonID = season.ID
+	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Create(match).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(models.NewFixtureEvent(match, "system")).Error
+	})
+}
+
+// UpdateMatch updates a match if its version is still the one stored and
+// increments the version
+func (s *GormDB) UpdateMatch(match *models.Match) error {
+	version := match.Version
+	match.Version++
+	result := s.db.Model(match).Where("version = ?", version).
+		Select("*").Omit(clause.Associations, "created_at").Updates(match)
+	if result.Error == nil && result.RowsAffected == 0 {
+		result.Error = ErrVersionConflict
+	}
+	if result.Error != nil {
+		match.Version = version
+	}
+	return result.Error
+}
+
+// SaveRatingFit stores a rating fit and the fitted ratings of the given teams
+func (s *GormDB) SaveRatingFit(fit *models.RatingFit, teams []models.Team) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		for _, team := range teams {
+			err := tx.Model(&models.Team{}).Where("id = ?", team.ID).Updates(map[string]interface{}{
+				"attack":  team.Attack,
+				"defence": team.Defence,
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return tx.Create(fit).Error
+	})
+}
+
+// GetLatestRatingFit returns the most recent rating fit or nil if none exists
+func (s *GormDB) GetLatestRatingFit() (*models.RatingFit, error) {
+	var fit models.RatingFit
+	err := s.db.Order("id DESC").First(&fit).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &fit, nil
+}
+
+// SavePredictionRecord saves a prediction together with the match result,
+// replacing the record of the same match and engine
+func (s *GormDB) SavePredictionRecord(record *models.PredictionRecord) error {
+	return s.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "match_id"}, {Name: "engine"}},
+		DoUpdates: clause.AssignmentColumns([]string{"season_id", "week", "source", "home_win", "draw", "away_win",
+			"expected_home_goals", "expected_away_goals", "home_goals", "away_goals", "created_at"}),
+	}).Create(record).Error
+}
+
+// GetPredictionRecords returns the recorded predictions of a season, or of
+// every season when seasonID is 0
+func (s *GormDB) GetPredictionRecords(seasonID uint) ([]models.PredictionRecord, error) {
+	var records []models.PredictionRecord
+	db := s.db.Order("id")
+	if seasonID != 0 {
+		db = db.Where("season_id = ?", seasonID)
+	}
+	err := db.Find(&records).Error
+	return records, err
+}
+
+// DeletePredictionRecords deletes the recorded predictions of a match
+func (s *GormDB) DeletePredictionRecords(matchID uint) error {
+	return s.db.Where("match_id = ?", matchID).Delete(&models.PredictionRecord{}).Error
+}
+
+// SaveMatchAudit saves a change to a match result
+func (s *GormDB) SaveMatchAudit(audit *models.MatchAudit) error {
+	return s.db.Create(audit).Error
+}
+
+// UpdateMatchAudit updates a recorded change, e.g. when it was undone
+func (s *GormDB) UpdateMatchAudit(audit *models.MatchAudit) error {
+	return s.db.Save(audit).Error
+}
+
+// GetMatchAudits returns the recorded changes of a match in the order they were made
+func (s *GormDB) GetMatchAudits(matchID uint) ([]models.MatchAudit, error) {
+	var audits []models.MatchAudit
+	err := s.db.Where("match_id = ?", matchID).Order("id").Find(&audits).Error
+	return audits, err
+}
+
+// GetSeasonAudits returns the recorded changes of all matches of a season in the order they were made
+func (s *GormDB) GetSeasonAudits(seasonID uint) ([]models.MatchAudit, error) {
+	var audits []models.MatchAudit
+	err := s.db.Where("season_id = ?", seasonID).Order("id").Find(&audits).Error
+	return audits, err
+}
+
+// AppendEvent appends an event to the event stream of its season
+func (s *GormDB) AppendEvent(event *models.LeagueEvent) error {
+	return s.db.Create(event).Error
+}
+
+// GetEvents returns the event stream of a season in order
+func (s *GormDB) GetEvents(seasonID uint) ([]models.LeagueEvent, error) {
+	var events []models.LeagueEvent
+	err := s.db.Where("season_id = ?", seasonID).Order("id").Find(&events).Error
+	return events, err
+}
+
+// SaveScenario creates a scenario together with its overrides
+func (s *GormDB) SaveScenario(scenario *models.Scenario) error {
+	return s.db.Create(scenario).Error
+}
+
+// GetScenarios returns all scenarios with their overrides
+func (s *GormDB) GetScenarios() ([]models.Scenario, error) {
+	var scenarios []models.Scenario
+	err := s.db.Preload("Overrides").Order("id").Find(&scenarios).Error
+	return scenarios, err
+}
+
+// GetScenario returns a single scenario or nil if it does not exist
+func (s *GormDB) GetScenario(id uint) (*models.Scenario, error) {
+	var scenario models.Scenario
+	err := s.db.Preload("Overrides").First(&scenario, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &scenario, nil
+}
+
+// SaveScenarioOverride creates or replaces the overridden result of a match in a scenario
+func (s *GormDB) SaveScenarioOverride(override *models.ScenarioOverride) error {
+	return s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "scenario_id"}, {Name: "match_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"home_goals", "away_goals"}),
+	}).Create(override).Error
+}
+
+// DeleteScenarioOverride removes the overridden result of a match from a scenario
+func (s *GormDB) DeleteScenarioOverride(scenarioID, matchID uint) error {
+	return s.db.Where("scenario_id = ? AND match_id = ?", scenarioID, matchID).Delete(&models.ScenarioOverride{}).Error
+}
+
+// DeleteScenario deletes a scenario and its overrides
+func (s *GormDB) DeleteScenario(id uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("scenario_id = ?", id).Delete(&models.ScenarioOverride{}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Delete(&models.Scenario{}, id).Error
+	})
+}
+
+// SaveSeason saves a season to the database
+func (s *GormDB) SaveSeason(season *models.Season) error {
+	return s.db.Create(season).Error
+}
+
+// GetSeasons returns all seasons
+func (s *GormDB) GetSeasons() ([]models.Season, error) {
+	var seasons []models.Season
+	err := s.db.Order("id").Find(&seasons).Error
+	return seasons, err
+}
+
+// GetSeason returns a single season or nil if it does not exist
+func (s *GormDB) GetSeason(id uint) (*models.Season, error) {
+	var season models.Season
+	err := s.db.First(&season, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &season, nil
+}
+
+// GetCurrentSeason returns the latest season or nil if there is none
+func (s *GormDB) GetCurrentSeason() (*models.Season, error) {
+	var season models.Season
+	err := s.db.Order("id DESC").First(&season).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &season, nil
+}
+
+// dataTables are the tables holding league data, every table comes before
+// the tables it references
+var dataTables = []string{
+	"scenario_overrides", "scenarios", "prediction_records", "match_audits",
+	"league_events", "matches", "rating_fits", "teams", "seasons",
+}
+
+// truncate deletes every row of the data tables and restarts their IDs,
+// the schema and the applied migrations stay as they are
+func truncate(db *gorm.DB) error {
+	if db.Dialector.Name() == "postgres" {
+		return db.Exec("TRUNCATE TABLE " + strings.Join(dataTables, ", ") + " RESTART IDENTITY").Error
+	}
+
+	for _, table := range dataTables {
+		err := db.Exec("DELETE FROM " + table).Error
+		if err != nil {
+			return err
+		}
+	}
+	// SQLite creates the table of AUTOINCREMENT counters with the first row
+	if db.Migrator().HasTable("sqlite_sequence") {
+		return db.Exec("DELETE FROM sqlite_sequence WHERE name IN ?", dataTables).Error
+	}
+	return nil
+}
+
+// ResetDatabase clears all data and seeds the database again in one
+// transaction, it does not touch the schema
+func (s *GormDB) ResetDatabase(seed *SeedData) error {
+	return s.db.Transaction(func(db *gorm.DB) error {
+		tx := &GormDB{db: db}
+
+		err := truncate(tx.db)
+		if err != nil {
+			return err
+		}
+		return Seed(tx, seed)
+	})
+}
+
+// ClearSeason deletes the matches of a season together with their audits,
+// prediction records, scenario overrides and the event stream of the season
+func (s *GormDB) ClearSeason(seasonID uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		matches := tx.Model(&models.Match{}).Select("id").Where("season_id = ?", seasonID)
+		err := tx.Where("match_id IN (?)", matches).Delete(&models.ScenarioOverride{}).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Where("season_id = ?", seasonID).Delete(&models.PredictionRecord{}).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Where("season_id = ?", seasonID).Delete(&models.MatchAudit{}).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Where("season_id = ?", seasonID).Delete(&models.LeagueEvent{}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("season_id = ?", seasonID).Delete(&models.Match{}).Error
+	})
+}
@@ -0,0 +1,26 @@
+package database
+
+import (
+	"github.com/cahitcaginkaratas/backend_insider/internal/models"
+)
+
+// RecordMatchChange saves a changed match, records the change in the audit
+// log and appends it to the event stream of the season. Clearing a result
+// deletes the prediction records of the match, which were scored against it.
+func RecordMatchChange(db Database, old, match *models.Match, source, actor string) error {
+	err := db.UpdateMatch(match)
+	if err != nil {
+		return err
+	}
+	if old.Played && !match.Played {
+		err = db.DeletePredictionRecords(match.ID)
+		if err != nil {
+			return err
+		}
+	}
+	err = db.SaveMatchAudit(models.NewMatchAudit(old, match, source, actor))
+	if err != nil {
+		return err
+	}
+	return db.AppendEvent(models.NewMatchEvent(old, match, source, actor))
+}
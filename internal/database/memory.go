@@ -0,0 +1,699 @@
+package database
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cahitcaginkaratas/backend_insider/internal/models"
+)
+
+// MemoryDB implements the Database interface in memory, nothing is written
+// to disk and everything is lost when the process exits
+type MemoryDB struct {
+	mu    *sync.RWMutex
+	state *memoryState
+}
+
+// memoryState holds the rows of every table
+type memoryState struct {
+	lastID      map[string]uint
+	seasons     []models.Season
+	teams       []models.Team
+	matches     []models.Match
+	fits        []models.RatingFit
+	predictions []models.PredictionRecord
+	audits      []models.MatchAudit
+	events      []models.LeagueEvent
+	scenarios   []models.Scenario
+	overrides   []models.ScenarioOverride
+}
+
+// NewMemoryDB creates a new in-memory database instance
+func NewMemoryDB() *MemoryDB {
+	return &MemoryDB{mu: &sync.RWMutex{}, state: newMemoryState()}
+}
+
+// newMemoryState creates empty tables
+func newMemoryState() *memoryState {
+	return &memoryState{lastID: make(map[string]uint)}
+}
+
+// nextID returns the next auto-increment ID of a table
+func (s *memoryState) nextID(table string) uint {
+	s.lastID[table]++
+	return s.lastID[table]
+}
+
+// clone copies every table so a transaction can change the copy
+func (s *memoryState) clone() *memoryState {
+	clone := &memoryState{
+		lastID:      make(map[string]uint, len(s.lastID)),
+		seasons:     append([]models.Season(nil), s.seasons...),
+		teams:       append([]models.Team(nil), s.teams...),
+		matches:     append([]models.Match(nil), s.matches...),
+		fits:        append([]models.RatingFit(nil), s.fits...),
+		predictions: append([]models.PredictionRecord(nil), s.predictions...),
+		audits:      append([]models.MatchAudit(nil), s.audits...),
+		events:      append([]models.LeagueEvent(nil), s.events...),
+		scenarios:   append([]models.Scenario(nil), s.scenarios...),
+		overrides:   append([]models.ScenarioOverride(nil), s.overrides...),
+	}
+	for table, id := range s.lastID {
+		clone.lastID[table] = id
+	}
+	return clone
+}
+
+// InitDB starts with empty tables and the first season
+func (m *MemoryDB) InitDB() error {
+	m.mu.Lock()
+	m.state = newMemoryState()
+	m.mu.Unlock()
+
+	_, err := FirstSeason(m)
+	return err
+}
+
+// WithTx runs fn on a copy of the tables that replaces them when fn succeeds.
+// Transactions are serialized with every other write.
+func (m *MemoryDB) WithTx(fn func(tx Database) error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tx := &MemoryDB{mu: &sync.RWMutex{}, state: m.state.clone()}
+	err := fn(tx)
+	if err != nil {
+		return err
+	}
+	m.state = tx.state
+	return nil
+}
+
+// GetTeams returns all teams
+func (m *MemoryDB) GetTeams() ([]models.Team, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]models.Team{}, m.state.teams...), nil
+}
+
+// GetSeasonTeams returns the teams that have fixtures in a season
+func (m *MemoryDB) GetSeasonTeams(seasonID uint) ([]models.Team, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.state.seasonTeams(seasonID), nil
+}
+
+// GetTeam returns a single team or nil if it does not exist
+func (m *MemoryDB) GetTeam(id uint) (*models.Team, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, team := range m.state.teams {
+		if team.ID == id {
+			return &team, nil
+		}
+	}
+	return nil, nil
+}
+
+// withTeams fills in the home and away team of matches
+func (s *memoryState) withTeams(matches []models.Match) []models.Match {
+	teams := make(map[uint]models.Team, len(s.teams))
+	for _, team := range s.teams {
+		teams[team.ID] = team
+	}
+	result := make([]models.Match, len(matches))
+	for i, match := range matches {
+		match.HomeTeam = teams[match.HomeTeamID]
+		match.AwayTeam = teams[match.AwayTeamID]
+		result[i] = match
+	}
+	return result
+}
+
+// seasonTeams returns the teams that have fixtures in a season
+func (s *memoryState) seasonTeams(seasonID uint) []models.Team {
+	inSeason := make(map[uint]bool)
+	for _, match := range s.matches {
+		if match.SeasonID == seasonID {
+			inSeason[match.HomeTeamID] = true
+			inSeason[match.AwayTeamID] = true
+		}
+	}
+	teams := make([]models.Team, 0, len(inSeason))
+	for _, team := range s.teams {
+		if inSeason[team.ID] {
+			teams = append(teams, team)
+		}
+	}
+	return teams
+}
+
+// filterMatches returns the matches for which keep returns true
+func (s *memoryState) filterMatches(keep func(match *models.Match) bool) []models.Match {
+	matches := make([]models.Match, 0)
+	for i := range s.matches {
+		if keep(&s.matches[i]) {
+			matches = append(matches, s.matches[i])
+		}
+	}
+	return s.withTeams(matches)
+}
+
+// GetMatches returns all matches
+func (m *MemoryDB) GetMatches() ([]models.Match, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.state.filterMatches(func(*models.Match) bool { return true }), nil
+}
+
+// FindMatches returns the matches that pass the filters of a query in its
+// sort order, starting after its cursor
+func (m *MemoryDB) FindMatches(query MatchQuery) ([]models.Match, error) {
+	field, desc, err := ParseMatchSort(query.Sort)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	matches := m.state.filterMatches(func(match *models.Match) bool {
+		return query.matches(match) &&
+			(query.After == nil || compareMatches(NewMatchCursor(match), query.After, field, desc) > 0)
+	})
+	sort.Slice(matches, func(i, j int) bool {
+		return compareMatches(NewMatchCursor(&matches[i]), NewMatchCursor(&matches[j]), field, desc) < 0
+	})
+	if query.Limit > 0 && len(matches) > query.Limit {
+		matches = matches[:query.Limit]
+	}
+	return matches, nil
+}
+
+// GetMatchesBySeason returns all matches of a season
+func (m *MemoryDB) GetMatchesBySeason(seasonID uint) ([]models.Match, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.state.filterMatches(func(match *models.Match) bool { return match.SeasonID == seasonID }), nil
+}
+
+// GetTeamMatches returns the home and away matches of a team in a season
+// ordered by week
+func (m *MemoryDB) GetTeamMatches(seasonID, teamID uint) ([]models.Match, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	matches := m.state.filterMatches(func(match *models.Match) bool {
+		return match.SeasonID == seasonID && (match.HomeTeamID == teamID || match.AwayTeamID == teamID)
+	})
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Week < matches[j].Week })
+	return matches, nil
+}
+
+// GetSeasonWeek returns the matches of one week of a season
+func (m *MemoryDB) GetSeasonWeek(seasonID uint, week int) ([]models.Match, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.state.filterMatches(func(match *models.Match) bool {
+		return match.SeasonID == seasonID && match.Week == week
+	}), nil
+}
+
+// GetWeeks summarizes every week of a season that has matches, ordered by week
+func (m *MemoryDB) GetWeeks(seasonID uint) ([]models.WeekSummary, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	matches := m.state.filterMatches(func(match *models.Match) bool { return match.SeasonID == seasonID })
+	return models.WeekSummaries(matches), nil
+}
+
+// GetMatch returns a single match or nil if it does not exist
+func (m *MemoryDB) GetMatch(id uint) (*models.Match, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	matches := m.state.filterMatches(func(match *models.Match) bool { return match.ID == id })
+	if len(matches) == 0 {
+		return nil, nil
+	}
+	return &matches[0], nil
+}
+
+// GetLeagueStats returns the league table of a season without points deductions
+func (m *MemoryDB) GetLeagueStats(seasonID uint) ([]models.TeamStats, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	matches := m.state.filterMatches(func(match *models.Match) bool { return match.SeasonID == seasonID })
+	return models.NewTable(m.state.seasonTeams(seasonID), matches), nil
+}
+
+// SaveTeam saves a team to the database
+func (m *MemoryDB) SaveTeam(team *models.Team) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	team.ID = m.state.nextID("teams")
+	team.CreatedAt = time.Now()
+	team.UpdatedAt = team.CreatedAt
+	m.state.teams = append(m.state.teams, *team)
+	return nil
+}
+
+// UpdateTeam updates the name and strength of a team
+func (m *MemoryDB) UpdateTeam(team *models.Team) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.state.teams {
+		stored := &m.state.teams[i]
+		if stored.ID == team.ID {
+			stored.Name = team.Name
+			stored.Strength = team.Strength
+			stored.UpdatedAt = time.Now()
+			team.UpdatedAt = stored.UpdatedAt
+		}
+	}
+	return nil
+}
+
+// SaveMatch saves a match to the database, in the current season unless one is
+// set, and schedules it in the event stream
+func (m *MemoryDB) SaveMatch(match *models.Match) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if match.SeasonID == 0 {
+		season := m.state.currentSeason()
+		if season == nil {
+			return ErrNoSeason
+		}
+		match.SeasonID = season.ID
+	}
+	match.ID = m.state.nextID("matches")
+	match.CreatedAt = time.Now()
+	match.UpdatedAt = match.CreatedAt
+	stored := *match
+	stored.HomeTeam, stored.AwayTeam = models.Team{}, models.Team{}
+	m.state.matches = append(m.state.matches, stored)
+	m.state.appendEvent(models.NewFixtureEvent(match, "system"))
+	return nil
+}
+
+// UpdateMatch updates a match if its version is still the one stored and
+// increments the version
+func (m *MemoryDB) UpdateMatch(match *models.Match) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.state.matches {
+		stored := &m.state.matches[i]
+		if stored.ID != match.ID {
+			continue
+		}
+		if stored.Version != match.Version {
+			return ErrVersionConflict
+		}
+		match.Version++
+		match.UpdatedAt = time.Now()
+		updated := *match
+		updated.HomeTeam, updated.AwayTeam = models.Team{}, models.Team{}
+		updated.CreatedAt = stored.CreatedAt
+		*stored = updated
+		return nil
+	}
+	return ErrVersionConflict
+}
+
+// SaveRatingFit stores a rating fit and the fitted ratings of the given teams
+func (m *MemoryDB) SaveRatingFit(fit *models.RatingFit, teams []models.Team) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ratings := make(map[uint]models.Team, len(teams))
+	for _, team := range teams {
+		ratings[team.ID] = team
+	}
+	for i := range m.state.teams {
+		if team, ok := ratings[m.state.teams[i].ID]; ok {
+			m.state.teams[i].Attack = team.Attack
+			m.state.teams[i].Defence = team.Defence
+		}
+	}
+	fit.ID = m.state.nextID("rating_fits")
+	fit.CreatedAt = time.Now()
+	m.state.fits = append(m.state.fits, *fit)
+	return nil
+}
+
+// GetLatestRatingFit returns the most recent rating fit or nil if none exists
+func (m *MemoryDB) GetLatestRatingFit() (*models.RatingFit, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if len(m.state.fits) == 0 {
+		return nil, nil
+	}
+	fit := m.state.fits[len(m.state.fits)-1]
+	return &fit, nil
+}
+
+// SavePredictionRecord saves a prediction together with the match result,
+// replacing the record of the same match and engine
+func (m *MemoryDB) SavePredictionRecord(record *models.PredictionRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	record.CreatedAt = time.Now()
+	for i := range m.state.predictions {
+		stored := &m.state.predictions[i]
+		if stored.MatchID == record.MatchID && stored.Engine == record.Engine {
+			record.ID = stored.ID
+			*stored = *record
+			return nil
+		}
+	}
+	record.ID = m.state.nextID("prediction_records")
+	m.state.predictions = append(m.state.predictions, *record)
+	return nil
+}
+
+// GetPredictionRecords returns the recorded predictions of a season, or of
+// every season when seasonID is 0
+func (m *MemoryDB) GetPredictionRecords(seasonID uint) ([]models.PredictionRecord, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	records := make([]models.PredictionRecord, 0)
+	for _, record := range m.state.predictions {
+		if seasonID == 0 || record.SeasonID == seasonID {
+			records = append(records, record)
+		}
+	}
+	return records, nil
+}
+
+// deletePredictions removes the prediction records for which remove returns true
+func (s *memoryState) deletePredictions(remove func(record *models.PredictionRecord) bool) {
+	kept := s.predictions[:0:0]
+	for i := range s.predictions {
+		if !remove(&s.predictions[i]) {
+			kept = append(kept, s.predictions[i])
+		}
+	}
+	s.predictions = kept
+}
+
+// DeletePredictionRecords deletes the recorded predictions of a match
+func (m *MemoryDB) DeletePredictionRecords(matchID uint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.state.deletePredictions(func(record *models.PredictionRecord) bool { return record.MatchID == matchID })
+	return nil
+}
+
+// SaveMatchAudit saves a change to a match result
+func (m *MemoryDB) SaveMatchAudit(audit *models.MatchAudit) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	audit.ID = m.state.nextID("match_audits")
+	audit.CreatedAt = time.Now()
+	m.state.audits = append(m.state.audits, *audit)
+	return nil
+}
+
+// UpdateMatchAudit updates a recorded change, e.g. when it was undone
+func (m *MemoryDB) UpdateMatchAudit(audit *models.MatchAudit) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.state.audits {
+		if m.state.audits[i].ID == audit.ID {
+			m.state.audits[i] = *audit
+		}
+	}
+	return nil
+}
+
+// GetMatchAudits returns the recorded changes of a match in the order they were made
+func (m *MemoryDB) GetMatchAudits(matchID uint) ([]models.MatchAudit, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	audits := make([]models.MatchAudit, 0)
+	for _, audit := range m.state.audits {
+		if audit.MatchID == matchID {
+			audits = append(audits, audit)
+		}
+	}
+	return audits, nil
+}
+
+// GetSeasonAudits returns the recorded changes of all matches of a season in the order they were made
+func (m *MemoryDB) GetSeasonAudits(seasonID uint) ([]models.MatchAudit, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	audits := make([]models.MatchAudit, 0)
+	for _, audit := range m.state.audits {
+		if audit.SeasonID == seasonID {
+			audits = append(audits, audit)
+		}
+	}
+	return audits, nil
+}
+
+// appendEvent appends an event without locking
+func (s *memoryState) appendEvent(event *models.LeagueEvent) {
+	event.ID = s.nextID("league_events")
+	event.CreatedAt = time.Now()
+	s.events = append(s.events, *event)
+}
+
+// AppendEvent appends an event to the event stream of its season
+func (m *MemoryDB) AppendEvent(event *models.LeagueEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.state.appendEvent(event)
+	return nil
+}
+
+// GetEvents returns the event stream of a season in order
+func (m *MemoryDB) GetEvents(seasonID uint) ([]models.LeagueEvent, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	events := make([]models.LeagueEvent, 0)
+	for _, event := range m.state.events {
+		if event.SeasonID == seasonID {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+// withOverrides fills in the overrides of a scenario
+func (s *memoryState) withOverrides(scenario models.Scenario) models.Scenario {
+	scenario.Overrides = make([]models.ScenarioOverride, 0)
+	for _, override := range s.overrides {
+		if override.ScenarioID == scenario.ID {
+			scenario.Overrides = append(scenario.Overrides, override)
+		}
+	}
+	return scenario
+}
+
+// SaveScenario creates a scenario together with its overrides
+func (m *MemoryDB) SaveScenario(scenario *models.Scenario) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	scenario.ID = m.state.nextID("scenarios")
+	scenario.CreatedAt = time.Now()
+	scenario.UpdatedAt = scenario.CreatedAt
+	for i := range scenario.Overrides {
+		scenario.Overrides[i].ScenarioID = scenario.ID
+		m.state.saveOverride(&scenario.Overrides[i])
+	}
+	stored := *scenario
+	stored.Overrides = nil
+	m.state.scenarios = append(m.state.scenarios, stored)
+	return nil
+}
+
+// GetScenarios returns all scenarios with their overrides
+func (m *MemoryDB) GetScenarios() ([]models.Scenario, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	scenarios := make([]models.Scenario, 0, len(m.state.scenarios))
+	for _, scenario := range m.state.scenarios {
+		scenarios = append(scenarios, m.state.withOverrides(scenario))
+	}
+	return scenarios, nil
+}
+
+// GetScenario returns a single scenario with its overrides or nil if it does not exist
+func (m *MemoryDB) GetScenario(id uint) (*models.Scenario, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, scenario := range m.state.scenarios {
+		if scenario.ID == id {
+			scenario = m.state.withOverrides(scenario)
+			return &scenario, nil
+		}
+	}
+	return nil, nil
+}
+
+// saveOverride creates or replaces an override without locking
+func (s *memoryState) saveOverride(override *models.ScenarioOverride) {
+	for i := range s.overrides {
+		stored := &s.overrides[i]
+		if stored.ScenarioID == override.ScenarioID && stored.MatchID == override.MatchID {
+			stored.HomeGoals = override.HomeGoals
+			stored.AwayGoals = override.AwayGoals
+			override.ID = stored.ID
+			return
+		}
+	}
+	override.ID = s.nextID("scenario_overrides")
+	s.overrides = append(s.overrides, *override)
+}
+
+// SaveScenarioOverride creates or replaces the overridden result of a match in a scenario
+func (m *MemoryDB) SaveScenarioOverride(override *models.ScenarioOverride) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.state.saveOverride(override)
+	return nil
+}
+
+// DeleteScenarioOverride removes the overridden result of a match from a scenario
+func (m *MemoryDB) DeleteScenarioOverride(scenarioID, matchID uint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.state.deleteOverrides(func(override *models.ScenarioOverride) bool {
+		return override.ScenarioID == scenarioID && override.MatchID == matchID
+	})
+	return nil
+}
+
+// deleteOverrides removes the overrides for which remove returns true
+func (s *memoryState) deleteOverrides(remove func(override *models.ScenarioOverride) bool) {
+	kept := s.overrides[:0:0]
+	for i := range s.overrides {
+		if !remove(&s.overrides[i]) {
+			kept = append(kept, s.overrides[i])
+		}
+	}
+	s.overrides = kept
+}
+
+// DeleteScenario deletes a scenario and its overrides
+func (m *MemoryDB) DeleteScenario(id uint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.state.deleteOverrides(func(override *models.ScenarioOverride) bool {
+		return override.ScenarioID == id
+	})
+	kept := m.state.scenarios[:0:0]
+	for _, scenario := range m.state.scenarios {
+		if scenario.ID != id {
+			kept = append(kept, scenario)
+		}
+	}
+	m.state.scenarios = kept
+	return nil
+}
+
+// SaveSeason saves a season to the database
+func (m *MemoryDB) SaveSeason(season *models.Season) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	season.ID = m.state.nextID("seasons")
+	season.CreatedAt = time.Now()
+	season.UpdatedAt = season.CreatedAt
+	m.state.seasons = append(m.state.seasons, *season)
+	return nil
+}
+
+// GetSeasons returns all seasons
+func (m *MemoryDB) GetSeasons() ([]models.Season, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]models.Season{}, m.state.seasons...), nil
+}
+
+// GetSeason returns a single season or nil if it does not exist
+func (m *MemoryDB) GetSeason(id uint) (*models.Season, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, season := range m.state.seasons {
+		if season.ID == id {
+			return &season, nil
+		}
+	}
+	return nil, nil
+}
+
+// currentSeason returns the latest season or nil if there is none, without
+// locking
+func (s *memoryState) currentSeason() *models.Season {
+	var latest *models.Season
+	for i := range s.seasons {
+		if latest == nil || s.seasons[i].ID > latest.ID {
+			latest = &s.seasons[i]
+		}
+	}
+	if latest == nil {
+		return nil
+	}
+	season := *latest
+	return &season
+}
+
+// GetCurrentSeason returns the latest season or nil if there is none
+func (m *MemoryDB) GetCurrentSeason() (*models.Season, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.state.currentSeason(), nil
+}
+
+// ResetDatabase clears all data and reinitializes the database in one transaction
+func (m *MemoryDB) ResetDatabase(seed *SeedData) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tx := NewMemoryDB()
+	err := Seed(tx, seed)
+	if err != nil {
+		return err
+	}
+	m.state = tx.state
+	return nil
+}
+
+// ClearSeason deletes the matches of a season together with their audits,
+// prediction records, scenario overrides and the event stream of the season
+func (m *MemoryDB) ClearSeason(seasonID uint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cleared := make(map[uint]bool)
+	matches := m.state.matches[:0:0]
+	for _, match := range m.state.matches {
+		if match.SeasonID == seasonID {
+			cleared[match.ID] = true
+		} else {
+			matches = append(matches, match)
+		}
+	}
+	m.state.matches = matches
+	m.state.deleteOverrides(func(override *models.ScenarioOverride) bool {
+		return cleared[override.MatchID]
+	})
+	m.state.deletePredictions(func(record *models.PredictionRecord) bool {
+		return record.SeasonID == seasonID
+	})
+
+	audits := m.state.audits[:0:0]
+	for _, audit := range m.state.audits {
+		if audit.SeasonID != seasonID {
+			audits = append(audits, audit)
+		}
+	}
+	m.state.audits = audits
+
+	events := m.state.events[:0:0]
+	for _, event := range m.state.events {
+		if event.SeasonID != seasonID {
+			events = append(events, event)
+		}
+	}
+	m.state.events = events
+	return nil
+}
@@ -0,0 +1,531 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrSchemaTooNew is returned when the database was migrated by a newer
+// version of the application than the running one
+var ErrSchemaTooNew = errors.New("database schema is newer than this binary")
+
+// Migration is one numbered schema change. Up only creates what is missing,
+// so databases created before migrations existed are adopted as they are.
+// Every migration declares the tables as they were at that version instead
+// of using the models, which keep changing.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// MigrationStatus tells whether a migration has been applied
+type MigrationStatus struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at"`
+}
+
+// schemaMigration is a row of the schema_migrations table
+type schemaMigration struct {
+	Version   int `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	AppliedAt time.Time
+}
+
+// TableName returns the table of applied migrations
+func (schemaMigration) TableName() string { return "schema_migrations" }
+
+// Tables as of migration 1
+type teamV1 struct {
+	ID        uint `gorm:"primaryKey"`
+	Name      string
+	Strength  int
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (teamV1) TableName() string { return "teams" }
+
+type matchV1 struct {
+	ID         uint `gorm:"primaryKey"`
+	Week       int
+	HomeTeam   teamV1 `gorm:"foreignKey:HomeTeamID"`
+	HomeTeamID uint
+	AwayTeam   teamV1 `gorm:"foreignKey:AwayTeamID"`
+	AwayTeamID uint
+	HomeGoals  int
+	AwayGoals  int
+	Played     bool
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+func (matchV1) TableName() string { return "matches" }
+
+// Tables as of migration 2
+type teamRatingsV2 struct {
+	Attack  float64
+	Defence float64
+}
+
+func (teamRatingsV2) TableName() string { return "teams" }
+
+type ratingFitV2 struct {
+	ID            uint `gorm:"primaryKey"`
+	Source        string
+	HomeAdvantage float64
+	Rho           float64
+	Xi            float64
+	LogLikelihood float64
+	Matches       int
+	Iterations    int
+	Converged     bool
+	ReferenceDate time.Time
+	CreatedAt     time.Time
+}
+
+func (ratingFitV2) TableName() string { return "rating_fits" }
+
+// Tables as of migration 3
+type predictionRecordV3 struct {
+	ID                uint `gorm:"primaryKey"`
+	MatchID           uint `gorm:"index"`
+	Week              int
+	Engine            string
+	Source            string
+	HomeWin           float64
+	Draw              float64
+	AwayWin           float64
+	ExpectedHomeGoals float64
+	ExpectedAwayGoals float64
+	HomeGoals         int
+	AwayGoals         int
+	CreatedAt         time.Time
+}
+
+func (predictionRecordV3) TableName() string { return "prediction_records" }
+
+// Tables as of migration 4
+type scenarioV4 struct {
+	ID          uint `gorm:"primaryKey"`
+	Name        string
+	Description string
+	Overrides   []scenarioOverrideV4 `gorm:"foreignKey:ScenarioID;constraint:OnDelete:CASCADE"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+func (scenarioV4) TableName() string { return "scenarios" }
+
+type scenarioOverrideV4 struct {
+	ID         uint `gorm:"primaryKey"`
+	ScenarioID uint `gorm:"uniqueIndex:idx_scenario_match"`
+	MatchID    uint `gorm:"uniqueIndex:idx_scenario_match"`
+	HomeGoals  int
+	AwayGoals  int
+}
+
+func (scenarioOverrideV4) TableName() string { return "scenario_overrides" }
+
+// Tables as of migration 5
+type seasonV5 struct {
+	ID        uint `gorm:"primaryKey"`
+	Name      string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (seasonV5) TableName() string { return "seasons" }
+
+type matchSeasonV5 struct {
+	SeasonID uint `gorm:"index"`
+	Date     time.Time
+}
+
+func (matchSeasonV5) TableName() string { return "matches" }
+
+type scenarioSeasonV5 struct {
+	SeasonID uint `gorm:"index"`
+}
+
+func (scenarioSeasonV5) TableName() string { return "scenarios" }
+
+// Tables as of migration 6
+type matchVersionV6 struct {
+	Version int `gorm:"not null;default:0"`
+}
+
+func (matchVersionV6) TableName() string { return "matches" }
+
+type matchAuditV6 struct {
+	ID           uint `gorm:"primaryKey"`
+	MatchID      uint `gorm:"index"`
+	SeasonID     uint `gorm:"index"`
+	Week         int
+	Source       string
+	Actor        string
+	OldHomeGoals int
+	OldAwayGoals int
+	OldPlayed    bool
+	NewHomeGoals int
+	NewAwayGoals int
+	NewPlayed    bool
+	Undone       bool
+	CreatedAt    time.Time
+}
+
+func (matchAuditV6) TableName() string { return "match_audits" }
+
+// Tables as of migration 7
+type leagueEventV7 struct {
+	ID         uint `gorm:"primaryKey"`
+	SeasonID   uint `gorm:"index"`
+	Type       string
+	MatchID    uint `gorm:"index"`
+	Week       int
+	HomeTeamID uint
+	AwayTeamID uint
+	HomeGoals  int
+	AwayGoals  int
+	Date       time.Time
+	TeamID     uint
+	Points     int
+	Reason     string
+	Actor      string
+	CreatedAt  time.Time
+}
+
+func (leagueEventV7) TableName() string { return "league_events" }
+
+// Tables as of migration 8
+type matchLookupV8 struct {
+	SeasonID   uint `gorm:"index:idx_matches_season_week,priority:1"`
+	Week       int  `gorm:"index:idx_matches_season_week,priority:2"`
+	HomeTeamID uint `gorm:"index"`
+	AwayTeamID uint `gorm:"index"`
+}
+
+func (matchLookupV8) TableName() string { return "matches" }
+
+// Tables as of migration 9
+type predictionRecordV9 struct {
+	SeasonID uint   `gorm:"index"`
+	MatchID  uint   `gorm:"uniqueIndex:idx_prediction_match_engine,priority:1"`
+	Engine   string `gorm:"uniqueIndex:idx_prediction_match_engine,priority:2"`
+}
+
+func (predictionRecordV9) TableName() string { return "prediction_records" }
+
+// Migrations lists every schema change in order
+var Migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "create teams and matches",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&teamV1{}, &matchV1{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&matchV1{}, &teamV1{})
+		},
+	},
+	{
+		Version: 2,
+		Name:    "add team ratings",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&teamRatingsV2{}, &ratingFitV2{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return dropAll(
+				func() error { return tx.Migrator().DropTable(&ratingFitV2{}) },
+				func() error { return dropColumns(tx, "teams", "attack", "defence") },
+			)
+		},
+	},
+	{
+		Version: 3,
+		Name:    "create prediction records",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&predictionRecordV3{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&predictionRecordV3{})
+		},
+	},
+	{
+		Version: 4,
+		Name:    "create scenarios",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&scenarioV4{}, &scenarioOverrideV4{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&scenarioOverrideV4{}, &scenarioV4{})
+		},
+	},
+	{
+		Version: 5,
+		Name:    "add seasons",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&seasonV5{}, &matchSeasonV5{}, &scenarioSeasonV5{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return dropAll(
+				func() error { return tx.Migrator().DropIndex(&scenarioSeasonV5{}, "SeasonID") },
+				func() error { return dropColumns(tx, "scenarios", "season_id") },
+				func() error { return tx.Migrator().DropIndex(&matchSeasonV5{}, "SeasonID") },
+				func() error { return dropColumns(tx, "matches", "season_id", "date") },
+				func() error { return tx.Migrator().DropTable(&seasonV5{}) },
+			)
+		},
+	},
+	{
+		Version: 6,
+		Name:    "add match versions and audit log",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&matchVersionV6{}, &matchAuditV6{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return dropAll(
+				func() error { return tx.Migrator().DropTable(&matchAuditV6{}) },
+				func() error { return dropColumns(tx, "matches", "version") },
+			)
+		},
+	},
+	{
+		Version: 7,
+		Name:    "create league events",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&leagueEventV7{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&leagueEventV7{})
+		},
+	},
+	{
+		Version: 8,
+		Name:    "add match lookup indexes",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&matchLookupV8{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return dropAll(
+				func() error { return tx.Migrator().DropIndex(&matchLookupV8{}, "idx_matches_season_week") },
+				func() error { return tx.Migrator().DropIndex(&matchLookupV8{}, "HomeTeamID") },
+				func() error { return tx.Migrator().DropIndex(&matchLookupV8{}, "AwayTeamID") },
+			)
+		},
+	},
+	{
+		Version: 9,
+		Name:    "one prediction record per match and engine",
+		Up: func(tx *gorm.DB) error {
+			if !tx.Migrator().HasColumn(&predictionRecordV9{}, "SeasonID") {
+				err := tx.Migrator().AddColumn(&predictionRecordV9{}, "SeasonID")
+				if err != nil {
+					return err
+				}
+			}
+			err := tx.Exec(`UPDATE prediction_records SET season_id = COALESCE(
+				(SELECT season_id FROM matches WHERE matches.id = prediction_records.match_id), 0)`).Error
+			if err != nil {
+				return err
+			}
+
+			// Re-entered results appended another record, only the latest counts
+			err = tx.Exec(`DELETE FROM prediction_records WHERE id NOT IN
+				(SELECT MAX(id) FROM prediction_records GROUP BY match_id, engine)`).Error
+			if err != nil {
+				return err
+			}
+			return tx.AutoMigrate(&predictionRecordV9{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return dropAll(
+				func() error { return tx.Migrator().DropIndex(&predictionRecordV9{}, "idx_prediction_match_engine") },
+				func() error { return tx.Migrator().DropIndex(&predictionRecordV9{}, "SeasonID") },
+				func() error { return dropColumns(tx, "prediction_records", "season_id") },
+			)
+		},
+	},
+}
+
+// LatestVersion returns the schema version this binary expects
+func LatestVersion() int {
+	return Migrations[len(Migrations)-1].Version
+}
+
+// dropAll runs the steps of a down migration in order
+func dropAll(steps ...func() error) error {
+	for _, step := range steps {
+		err := step()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dropColumns drops columns with ALTER TABLE, which both SQLite and
+// PostgreSQL support and which keeps the other indexes of the table
+func dropColumns(tx *gorm.DB, table string, columns ...string) error {
+	for _, column := range columns {
+		err := tx.Exec("ALTER TABLE ? DROP COLUMN ?", clause.Table{Name: table}, clause.Column{Name: column}).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// appliedMigrations returns the applied migrations by version
+func appliedMigrations(db *gorm.DB) (map[int]schemaMigration, error) {
+	err := db.AutoMigrate(&schemaMigration{})
+	if err != nil {
+		return nil, err
+	}
+	var rows []schemaMigration
+	err = db.Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	applied := make(map[int]schemaMigration, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+	return applied, nil
+}
+
+// checkVersion fails when migrations newer than the binary were applied
+func checkVersion(applied map[int]schemaMigration) error {
+	for version := range applied {
+		if version > LatestVersion() {
+			return fmt.Errorf("%w: version %d, this binary knows up to %d", ErrSchemaTooNew, version, LatestVersion())
+		}
+	}
+	return nil
+}
+
+// migrateUp applies every pending migration up to the target version, each
+// in its own transaction
+func migrateUp(db *gorm.DB, target int) error {
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return err
+	}
+	err = checkVersion(applied)
+	if err != nil {
+		return err
+	}
+
+	for _, migration := range Migrations {
+		if migration.Version > target {
+			break
+		}
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+		err = db.Transaction(func(tx *gorm.DB) error {
+			err := migration.Up(tx)
+			if err != nil {
+				return err
+			}
+			return tx.Create(&schemaMigration{Version: migration.Version, Name: migration.Name, AppliedAt: time.Now()}).Error
+		})
+		if err != nil {
+			return fmt.Errorf("migration %d %s: %w", migration.Version, migration.Name, err)
+		}
+	}
+	return nil
+}
+
+// migrateDown rolls back the latest applied migrations down to the target
+// version, each in its own transaction
+func migrateDown(db *gorm.DB, target int) error {
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return err
+	}
+	err = checkVersion(applied)
+	if err != nil {
+		return err
+	}
+
+	for i := len(Migrations) - 1; i >= 0; i-- {
+		migration := Migrations[i]
+		if migration.Version <= target {
+			break
+		}
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+		err = db.Transaction(func(tx *gorm.DB) error {
+			err := migration.Down(tx)
+			if err != nil {
+				return err
+			}
+			return tx.Delete(&schemaMigration{}, migration.Version).Error
+		})
+		if err != nil {
+			return fmt.Errorf("rolling back migration %d %s: %w", migration.Version, migration.Name, err)
+		}
+	}
+	return nil
+}
+
+// MigrateUp applies the pending migrations up to the target version
+func (s *GormDB) MigrateUp(target int) error {
+	return migrateUp(s.db, target)
+}
+
+// MigrateDown rolls back the applied migrations newer than the target version
+func (s *GormDB) MigrateDown(target int) error {
+	return migrateDown(s.db, target)
+}
+
+// MigrationStatus lists every known migration and whether it was applied,
+// followed by applied migrations this binary does not know
+func (s *GormDB) MigrationStatus() ([]MigrationStatus, error) {
+	applied, err := appliedMigrations(s.db)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(Migrations))
+	for _, migration := range Migrations {
+		status := MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if row, ok := applied[migration.Version]; ok {
+			status.Applied = true
+			status.AppliedAt = &row.AppliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	for version, row := range applied {
+		if version > LatestVersion() {
+			statuses = append(statuses, MigrationStatus{Version: version, Name: row.Name, Applied: true, AppliedAt: &row.AppliedAt})
+		}
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Version < statuses[j].Version
+	})
+	return statuses, nil
+}
+
+// CurrentVersion returns the latest applied migration, 0 for an empty database
+func (s *GormDB) CurrentVersion() (int, error) {
+	applied, err := appliedMigrations(s.db)
+	if err != nil {
+		return 0, err
+	}
+	current := 0
+	for version := range applied {
+		current = max(current, version)
+	}
+	return current, nil
+}
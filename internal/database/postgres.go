@@ -0,0 +1,11 @@
+package database
+
+import (
+	"gorm.io/driver/postgres"
+)
+
+// NewPostgresDB creates a new database instance backed by PostgreSQL, the DSN
+// is either a postgres:// URL or a "host=... dbname=..." string
+func NewPostgresDB(dsn string) *GormDB {
+	return &GormDB{dialector: postgres.Open(dsn)}
+}
@@ -0,0 +1,173 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cahitcaginkaratas/backend_insider/internal/models"
+	"gorm.io/gorm"
+)
+
+// Match sort orders, a leading "-" sorts descending. Ties are broken by
+// match ID in the same direction.
+const (
+	SortWeek = "week"
+	SortDate = "date"
+	SortID   = "id"
+)
+
+// Venues a team can play at, see MatchQuery.Venue
+const (
+	VenueHome = "home"
+	VenueAway = "away"
+)
+
+// MatchQuery narrows, orders and pages the matches returned by FindMatches.
+// Zero values do not filter.
+type MatchQuery struct {
+	SeasonID uint
+	TeamID   uint
+	Venue    string // VenueHome or VenueAway, only with TeamID
+	FromWeek int
+	ToWeek   int
+	Played   *bool
+	From     time.Time // matches on or after From
+	Until    time.Time // matches before Until
+
+	Sort  string       // SortWeek, SortDate or SortID with an optional "-", SortWeek if empty
+	After *MatchCursor // continue after this match in the sort order
+	Limit int          // at most Limit matches, all if 0
+}
+
+// MatchCursor is the position of a match in a sort order
+type MatchCursor struct {
+	Week int
+	Date time.Time
+	ID   uint
+}
+
+// NewMatchCursor returns the position of a match
+func NewMatchCursor(match *models.Match) *MatchCursor {
+	return &MatchCursor{Week: match.Week, Date: match.Date, ID: match.ID}
+}
+
+// ParseMatchSort splits a sort order into its field and direction
+func ParseMatchSort(sort string) (field string, desc bool, err error) {
+	if sort == "" {
+		return SortWeek, false, nil
+	}
+	field = strings.TrimPrefix(sort, "-")
+	if field != SortWeek && field != SortDate && field != SortID {
+		return "", false, fmt.Errorf("unknown sort %q, use week, date or id", sort)
+	}
+	return field, field != sort, nil
+}
+
+// matches reports whether a match passes the filters of the query
+func (q *MatchQuery) matches(match *models.Match) bool {
+	if q.SeasonID != 0 && match.SeasonID != q.SeasonID {
+		return false
+	}
+	if q.TeamID != 0 {
+		home := match.HomeTeamID == q.TeamID && q.Venue != VenueAway
+		away := match.AwayTeamID == q.TeamID && q.Venue != VenueHome
+		if !home && !away {
+			return false
+		}
+	}
+	if q.FromWeek != 0 && match.Week < q.FromWeek || q.ToWeek != 0 && match.Week > q.ToWeek {
+		return false
+	}
+	if q.Played != nil && match.Played != *q.Played {
+		return false
+	}
+	if !q.From.IsZero() && match.Date.Before(q.From) || !q.Until.IsZero() && !match.Date.Before(q.Until) {
+		return false
+	}
+	return true
+}
+
+// compareMatches orders two matches by the sort field and then by ID,
+// negative when a comes first
+func compareMatches(a, b *MatchCursor, field string, desc bool) int {
+	result := 0
+	switch field {
+	case SortWeek:
+		result = a.Week - b.Week
+	case SortDate:
+		result = a.Date.Compare(b.Date)
+	}
+	if result == 0 {
+		switch {
+		case a.ID < b.ID:
+			result = -1
+		case a.ID > b.ID:
+			result = 1
+		}
+	}
+	if desc {
+		return -result
+	}
+	return result
+}
+
+// scope applies the filters, order, cursor and limit of the query to a
+// GORM query on the matches table
+func (q *MatchQuery) scope(db *gorm.DB) (*gorm.DB, error) {
+	field, desc, err := ParseMatchSort(q.Sort)
+	if err != nil {
+		return nil, err
+	}
+
+	if q.SeasonID != 0 {
+		db = db.Where("season_id = ?", q.SeasonID)
+	}
+	switch {
+	case q.TeamID != 0 && q.Venue == VenueHome:
+		db = db.Where("home_team_id = ?", q.TeamID)
+	case q.TeamID != 0 && q.Venue == VenueAway:
+		db = db.Where("away_team_id = ?", q.TeamID)
+	case q.TeamID != 0:
+		db = db.Where("(home_team_id = ? OR away_team_id = ?)", q.TeamID, q.TeamID)
+	}
+	if q.FromWeek != 0 {
+		db = db.Where("week >= ?", q.FromWeek)
+	}
+	if q.ToWeek != 0 {
+		db = db.Where("week <= ?", q.ToWeek)
+	}
+	if q.Played != nil {
+		db = db.Where("played = ?", *q.Played)
+	}
+	if !q.From.IsZero() {
+		db = db.Where("date >= ?", q.From.UTC())
+	}
+	if !q.Until.IsZero() {
+		db = db.Where("date < ?", q.Until.UTC())
+	}
+
+	direction, after := "", ">"
+	if desc {
+		direction, after = " DESC", "<"
+	}
+	if q.After != nil {
+		switch field {
+		case SortWeek:
+			db = db.Where("(week "+after+" ? OR week = ? AND id "+after+" ?)", q.After.Week, q.After.Week, q.After.ID)
+		case SortDate:
+			date := q.After.Date.UTC()
+			db = db.Where("(date "+after+" ? OR date = ? AND id "+after+" ?)", date, date, q.After.ID)
+		default:
+			db = db.Where("id "+after+" ?", q.After.ID)
+		}
+	}
+	if field != SortID {
+		db = db.Order(field + direction)
+	}
+	db = db.Order("id" + direction)
+	if q.Limit > 0 {
+		db = db.Limit(q.Limit)
+	}
+	return db, nil
+}
@@ -0,0 +1,130 @@
+package database
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cahitcaginkaratas/backend_insider/internal/models"
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed seed.yaml
+var defaultSeed []byte
+
+// SeedData lists the teams a new league starts with
+type SeedData struct {
+	Teams []SeedTeam `json:"teams" yaml:"teams"`
+}
+
+// SeedTeam is a team of the seed data
+type SeedTeam struct {
+	Name     string `json:"name" yaml:"name"`
+	Strength int    `json:"strength" yaml:"strength"` // 1-100 scale
+}
+
+// DefaultSeed returns the seed data built into the binary
+func DefaultSeed() (*SeedData, error) {
+	return parseSeed(defaultSeed, yaml.Unmarshal)
+}
+
+// LoadSeed reads seed data from a JSON file (.json) or a YAML file
+func LoadSeed(path string) (*SeedData, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	unmarshal := yaml.Unmarshal
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		unmarshal = json.Unmarshal
+	}
+	seed, err := parseSeed(data, unmarshal)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return seed, nil
+}
+
+// parseSeed decodes and validates seed data
+func parseSeed(data []byte, unmarshal func([]byte, interface{}) error) (*SeedData, error) {
+	var seed SeedData
+	err := unmarshal(data, &seed)
+	if err != nil {
+		return nil, err
+	}
+	if len(seed.Teams) < 2 {
+		return nil, fmt.Errorf("seed needs at least 2 teams, got %d", len(seed.Teams))
+	}
+	// Names differing only in case or spacing would be the same team to a reader
+	names := make(map[string]bool, len(seed.Teams))
+	for i, team := range seed.Teams {
+		name := strings.ToLower(strings.TrimSpace(team.Name))
+		if name == "" {
+			return nil, fmt.Errorf("team %d has no name", i+1)
+		}
+		if names[name] {
+			return nil, fmt.Errorf("team %q is listed twice", team.Name)
+		}
+		names[name] = true
+		if team.Strength < 1 || team.Strength > 100 {
+			return nil, fmt.Errorf("team %q has strength %d, want 1 to 100", team.Name, team.Strength)
+		}
+	}
+	return &seed, nil
+}
+
+// FirstSeason returns the current season, creating the first one when the
+// database has none
+func FirstSeason(db Database) (*models.Season, error) {
+	season, err := db.GetCurrentSeason()
+	if err != nil || season != nil {
+		return season, err
+	}
+	season = models.NewSeason("Season 1")
+	err = db.SaveSeason(season)
+	if err != nil {
+		return nil, err
+	}
+	return season, nil
+}
+
+// Seed creates the teams of the seed data and their fixtures in the current
+// season, or in a first season
+func Seed(db Database, seed *SeedData) error {
+	teams := make([]models.Team, 0, len(seed.Teams))
+	for _, seedTeam := range seed.Teams {
+		team := models.NewTeam(seedTeam.Name, seedTeam.Strength)
+		err := db.SaveTeam(team)
+		if err != nil {
+			return err
+		}
+		teams = append(teams, *team)
+	}
+
+	season, err := FirstSeason(db)
+	if err != nil {
+		return err
+	}
+	return ScheduleFixtures(db, season.ID, teams)
+}
+
+// ScheduleFixtures generates and saves the fixtures of teams in a season
+func ScheduleFixtures(db Database, seasonID uint, teams []models.Team) error {
+	league := models.NewLeague()
+	for i := range teams {
+		league.AddTeam(&teams[i])
+	}
+	league.GenerateFixtures()
+
+	for _, match := range league.Matches {
+		match.SeasonID = seasonID
+		err := db.SaveMatch(&match)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
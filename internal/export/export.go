@@ -0,0 +1,173 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/cahitcaginkaratas/backend_insider/internal/database"
+	"github.com/cahitcaginkaratas/backend_insider/internal/models"
+)
+
+// source is the database an export reads from, with the names of all teams
+type source struct {
+	db    database.Database
+	names map[uint]string
+}
+
+// Dataset is an exported view of the league with a fixed column layout.
+// Columns are only ever added at the end, so existing exports keep working.
+type Dataset struct {
+	Columns []string
+	rows    func(src *source, season models.Season, row func(values ...interface{}) error) error
+}
+
+// Datasets lists what can be exported
+var Datasets = map[string]Dataset{
+	"table": {
+		Columns: []string{"season_id", "season", "position", "team_id", "team", "played", "won", "drawn", "lost",
+			"goals_for", "goals_against", "goal_difference", "points"},
+		rows: tableRows,
+	},
+	"matches": {
+		Columns: []string{"season_id", "season", "match_id", "week", "date", "home_team_id", "home_team",
+			"away_team_id", "away_team", "home_goals", "away_goals", "played", "version"},
+		rows: matchRows,
+	},
+	"events": {
+		Columns: []string{"season_id", "season", "event_id", "type", "created_at", "match_id", "week",
+			"home_team", "away_team", "home_goals", "away_goals", "team", "points", "reason", "actor"},
+		rows: eventRows,
+	},
+}
+
+// Export writes a dataset of the seasons in a format. Only one season is
+// read from the database at a time, rows are written as they are produced.
+// Seasons and the rows of every season come in a fixed order, so exporting
+// the same data twice gives the same file.
+func Export(db database.Database, w io.Writer, what, format string, seasons []models.Season) error {
+	dataset, ok := Datasets[what]
+	if !ok {
+		return fmt.Errorf("unknown dataset %q", what)
+	}
+	writer, err := NewRowWriter(w, format)
+	if err != nil {
+		return err
+	}
+
+	teams, err := db.GetTeams()
+	if err != nil {
+		return err
+	}
+	src := &source{db: db, names: make(map[uint]string, len(teams))}
+	for _, team := range teams {
+		src.names[team.ID] = team.Name
+	}
+
+	err = writer.Header(dataset.Columns)
+	if err != nil {
+		return err
+	}
+	row := func(values ...interface{}) error {
+		return writer.Row(values)
+	}
+	seasons = append([]models.Season(nil), seasons...)
+	sort.Slice(seasons, func(i, j int) bool { return seasons[i].ID < seasons[j].ID })
+	for _, season := range seasons {
+		err = dataset.rows(src, season, row)
+		if err != nil {
+			return err
+		}
+	}
+	return writer.Close()
+}
+
+// tableRows writes the table of a season with its points deductions
+func tableRows(src *source, season models.Season, row func(values ...interface{}) error) error {
+	matches, err := src.db.GetMatchesBySeason(season.ID)
+	if err != nil {
+		return err
+	}
+	events, err := src.db.GetEvents(season.ID)
+	if err != nil {
+		return err
+	}
+
+	teams, err := src.db.GetSeasonTeams(season.ID)
+	if err != nil {
+		return err
+	}
+	stats := models.NewTable(teams, matches)
+	models.ApplyDeductions(stats, models.Deductions(events))
+	for _, team := range stats {
+		err = row(season.ID, season.Name, team.Position, team.TeamID, team.TeamName, team.Played, team.Won,
+			team.Drawn, team.Lost, team.GoalsFor, team.GoalsAgainst, team.GoalDifference, team.Points)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// matchRows writes the fixtures and results of a season by week, unplayed
+// matches have no goals and matches without a date no date
+func matchRows(src *source, season models.Season, row func(values ...interface{}) error) error {
+	matches, err := src.db.GetMatchesBySeason(season.ID)
+	if err != nil {
+		return err
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Week != matches[j].Week {
+			return matches[i].Week < matches[j].Week
+		}
+		return matches[i].ID < matches[j].ID
+	})
+	for _, match := range matches {
+		var date, homeGoals, awayGoals interface{}
+		if !match.Date.IsZero() {
+			date = match.Date.Format("2006-01-02")
+		}
+		if match.Played {
+			homeGoals, awayGoals = match.HomeGoals, match.AwayGoals
+		}
+		err = row(season.ID, season.Name, match.ID, match.Week, date, match.HomeTeamID, src.names[match.HomeTeamID],
+			match.AwayTeamID, src.names[match.AwayTeamID], homeGoals, awayGoals, match.Played, match.Version)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// eventRows writes the event stream of a season. Match columns are empty for
+// points deductions, goals are only filled in for results and the team and
+// points only for deductions.
+func eventRows(src *source, season models.Season, row func(values ...interface{}) error) error {
+	events, err := src.db.GetEvents(season.ID)
+	if err != nil {
+		return err
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].ID < events[j].ID })
+	for _, event := range events {
+		var matchID, week, homeTeam, awayTeam, homeGoals, awayGoals, team, points, reason interface{}
+		if event.MatchID != 0 {
+			matchID, week = event.MatchID, event.Week
+			homeTeam, awayTeam = src.names[event.HomeTeamID], src.names[event.AwayTeamID]
+		}
+		switch event.Type {
+		case models.EventMatchSimulated, models.EventResultRecorded, models.EventResultCorrected:
+			homeGoals, awayGoals = event.HomeGoals, event.AwayGoals
+		case models.EventPointsDeducted:
+			team, points = src.names[event.TeamID], event.Points
+		}
+		if event.Reason != "" {
+			reason = event.Reason
+		}
+		err = row(season.ID, season.Name, event.ID, event.Type, event.CreatedAt, matchID, week,
+			homeTeam, awayTeam, homeGoals, awayGoals, team, points, reason, event.Actor)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
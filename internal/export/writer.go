@@ -0,0 +1,138 @@
+// Package export streams league data as CSV, JSON or XLSX rows
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Formats lists the supported output formats
+var Formats = []string{"csv", "json", "xlsx"}
+
+// ContentType returns the MIME type of a format
+func ContentType(format string) string {
+	switch format {
+	case "csv":
+		return "text/csv; charset=utf-8"
+	case "xlsx":
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	default:
+		return "application/json"
+	}
+}
+
+// RowWriter writes a header and then rows of values. Values are strings,
+// ints, bools, time.Time or nil for a missing value.
+type RowWriter interface {
+	Header(columns []string) error
+	Row(values []interface{}) error
+	Close() error
+}
+
+// NewRowWriter creates the RowWriter of a format
+func NewRowWriter(w io.Writer, format string) (RowWriter, error) {
+	switch format {
+	case "csv":
+		return &csvWriter{w: csv.NewWriter(w)}, nil
+	case "json":
+		return &jsonWriter{w: w}, nil
+	case "xlsx":
+		return newXLSXWriter(w), nil
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+}
+
+// formatValue formats a value as text, missing values are empty
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case int:
+		return strconv.Itoa(v)
+	case uint:
+		return strconv.FormatUint(uint64(v), 10)
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		return v.UTC().Format(time.RFC3339)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// csvWriter writes a header line and one line per row
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func (c *csvWriter) Header(columns []string) error {
+	return c.w.Write(columns)
+}
+
+func (c *csvWriter) Row(values []interface{}) error {
+	record := make([]string, len(values))
+	for i, value := range values {
+		record[i] = formatValue(value)
+	}
+	return c.w.Write(record)
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// jsonWriter writes an array with one object per row, keys in column order
+type jsonWriter struct {
+	w       io.Writer
+	columns []string
+	rows    int
+}
+
+func (j *jsonWriter) Header(columns []string) error {
+	j.columns = columns
+	_, err := io.WriteString(j.w, "[")
+	return err
+}
+
+func (j *jsonWriter) Row(values []interface{}) error {
+	object := []byte("{")
+	if j.rows > 0 {
+		object = []byte(",\n{")
+	}
+	for i, value := range values {
+		if i > 0 {
+			object = append(object, ',')
+		}
+		key, err := json.Marshal(j.columns[i])
+		if err != nil {
+			return err
+		}
+		if date, ok := value.(time.Time); ok {
+			value = formatValue(date)
+		}
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		object = append(object, key...)
+		object = append(object, ':')
+		object = append(object, encoded...)
+	}
+	object = append(object, '}')
+	j.rows++
+	_, err := j.w.Write(object)
+	return err
+}
+
+func (j *jsonWriter) Close() error {
+	_, err := io.WriteString(j.w, "]\n")
+	return err
+}
@@ -0,0 +1,23 @@
+package export
+
+import (
+	"bufio"
+	"errors"
+	"testing"
+)
+
+// failingWriter fails every write
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestXLSXRowReportsWriteErrors(t *testing.T) {
+	// A small buffer makes the first cell reach the failing writer
+	x := &xlsxWriter{sheet: bufio.NewWriterSize(failingWriter{}, 16)}
+	err := x.Row([]interface{}{"a team name longer than the buffer", 3, true})
+	if err == nil {
+		t.Fatal("got no error writing a row to a failing writer")
+	}
+}
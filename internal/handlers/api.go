@@ -2,144 +2,437 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/cahitcaginkaratas/backend_insider/internal/database"
 	"github.com/cahitcaginkaratas/backend_insider/internal/models"
+	"github.com/cahitcaginkaratas/backend_insider/internal/openapi"
 	"github.com/gorilla/mux"
 )
 
 // APIHandler handles all API requests
 type APIHandler struct {
-	db database.Database
+	db    database.Database
+	locks *seasonLocks
+	spec  *openapi.Document // set by NewRouter
+
+	// Backups are written to backupDir, keeping the newest backupKeep
+	backupDir  string
+	backupKeep int
 }
 
 // NewAPIHandler creates a new API handler
 func NewAPIHandler(db database.Database) *APIHandler {
-	return &APIHandler{db: db}
+	return &APIHandler{
+		db:         db,
+		locks:      newSeasonLocks(),
+		backupDir:  database.DefaultBackupDir,
+		backupKeep: database.DefaultBackupKeep,
+	}
+}
+
+// SetBackupRotation sets where backups are written and how many are kept,
+// 0 keeps every backup
+func (h *APIHandler) SetBackupRotation(dir string, keep int) {
+	h.backupDir = dir
+	h.backupKeep = keep
 }
 
 // GetTeams returns all teams
 func (h *APIHandler) GetTeams(w http.ResponseWriter, r *http.Request) {
 	teams, err := h.db.GetTeams()
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		internalError(w, err)
 		return
 	}
 
 	json.NewEncoder(w).Encode(teams)
 }
 
-// GetMatches returns all matches
+// teamResponse is a team with its fixtures and table row in a season
+type teamResponse struct {
+	Team     *models.Team      `json:"team"`
+	SeasonID uint              `json:"season_id"`
+	Stats    *models.TeamStats `json:"stats"`
+	Fixtures []models.Match    `json:"fixtures"`
+}
+
+// GetTeam returns a team with its fixtures and table row in the current
+// season, or the season given with ?season=
+func (h *APIHandler) GetTeam(w http.ResponseWriter, r *http.Request) {
+	teamID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		badRequest(w, "Invalid team ID")
+		return
+	}
+
+	team, err := h.db.GetTeam(uint(teamID))
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	if team == nil {
+		notFound(w, "Team not found")
+		return
+	}
+
+	season := h.seasonFromQuery(w, r)
+	if season == nil {
+		return
+	}
+	fixtures, err := h.db.GetTeamMatches(season.ID, team.ID)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+
+	// The position needs the whole table of the season with its deductions
+	teams, err := h.db.GetSeasonTeams(season.ID)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	matches, err := h.db.GetMatchesBySeason(season.ID)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	deductions, err := seasonDeductions(h.db, season.ID)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	table, err := models.NewTableView(teams, matches, models.ViewOverall, models.DefaultFormMatches)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	models.ApplyDeductions(table, deductions)
+
+	response := teamResponse{
+		Team:     team,
+		SeasonID: season.ID,
+		Fixtures: fixtures,
+	}
+	for i := range table {
+		if table[i].TeamID == team.ID {
+			response.Stats = &table[i]
+		}
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetSeasons returns all seasons
+func (h *APIHandler) GetSeasons(w http.ResponseWriter, r *http.Request) {
+	seasons, err := h.db.GetSeasons()
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(seasons)
+}
+
+// seasonRequest is the body of a new season
+type seasonRequest struct {
+	Name     string `json:"name" openapi:"required"`
+	Fixtures bool   `json:"fixtures"`
+}
+
+// CreateSeason starts a new season, which becomes the current one. With
+// "fixtures": true every team is scheduled in a double round-robin, without
+// it the season stays empty, for example to import results into.
+func (h *APIHandler) CreateSeason(w http.ResponseWriter, r *http.Request) {
+	var request seasonRequest
+	if !decodeJSON(w, r, &request) {
+		return
+	}
+	request.Name = strings.TrimSpace(request.Name)
+	if request.Name == "" {
+		validationFailed(w, []FieldError{{Field: "name", Message: "is required"}})
+		return
+	}
+
+	season := models.NewSeason(request.Name)
+	err := h.db.WithTx(func(tx database.Database) error {
+		err := tx.SaveSeason(season)
+		if err != nil || !request.Fixtures {
+			return err
+		}
+		teams, err := tx.GetTeams()
+		if err != nil {
+			return err
+		}
+		return database.ScheduleFixtures(tx, season.ID, teams)
+	})
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(season)
+}
+
+// GetMatches returns a page of matches, filtered by season, team and venue,
+// week range, played flag and date range. The Link header points to the
+// first and the next page, see matchQueryParams.
 func (h *APIHandler) GetMatches(w http.ResponseWriter, r *http.Request) {
-	matches, err := h.db.GetMatches()
+	query, message := matchQueryParams(r.URL.Query())
+	if message != "" {
+		badRequest(w, message)
+		return
+	}
+
+	// One match more than the page tells whether there is a next page
+	limit := query.Limit
+	query.Limit++
+	matches, err := h.db.FindMatches(query)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		internalError(w, err)
 		return
 	}
 
+	next := ""
+	if len(matches) > limit {
+		matches = matches[:limit]
+		next = encodeMatchCursor(query.Sort, &matches[limit-1])
+	}
+	setPageLinks(w, r, next)
+
 	json.NewEncoder(w).Encode(matches)
 }
 
-// GetLeagueStats returns the current league table
-func (h *APIHandler) GetLeagueStats(w http.ResponseWriter, r *http.Request) {
-	stats, err := h.db.GetLeagueStats()
+// GetMatch returns a single match
+func (h *APIHandler) GetMatch(w http.ResponseWriter, r *http.Request) {
+	matchID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		badRequest(w, "Invalid match ID")
 		return
 	}
 
-	json.NewEncoder(w).Encode(stats)
+	match, err := h.db.GetMatch(uint(matchID))
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	if match == nil {
+		notFound(w, "Match not found")
+		return
+	}
+
+	json.NewEncoder(w).Encode(match)
 }
 
-// SimulateWeek simulates all matches for a specific week
-func (h *APIHandler) SimulateWeek(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	week, err := strconv.Atoi(vars["week"])
+// GetLeagueStats returns the league table of the current season, or the
+// season given with ?season=, with points deductions and clinch and
+// elimination status
+func (h *APIHandler) GetLeagueStats(w http.ResponseWriter, r *http.Request) {
+	season := h.seasonFromQuery(w, r)
+	if season == nil {
+		return
+	}
+	stats, err := h.db.GetLeagueStats(season.ID)
 	if err != nil {
-		http.Error(w, "Invalid week number", http.StatusBadRequest)
+		internalError(w, err)
 		return
 	}
 
-	matches, err := h.db.GetMatchesByWeek(week)
+	opts := models.DefaultClinchOptions(len(stats))
+	for param, target := range map[string]*int{"top": &opts.TopPlaces, "relegation": &opts.RelegationPlaces} {
+		value := r.URL.Query().Get(param)
+		if value == "" {
+			continue
+		}
+		places, err := strconv.Atoi(value)
+		if err != nil || places < 0 {
+			badRequest(w, "Invalid number of places for "+param)
+			return
+		}
+		*target = places
+	}
+
+	// Points deductions come from the event stream of the season
+	deductions, err := seasonDeductions(h.db, season.ID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		internalError(w, err)
 		return
 	}
+	models.ApplyDeductions(stats, deductions)
 
-	teams, err := h.db.GetTeams()
+	matches, err := h.db.GetMatchesBySeason(season.ID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		internalError(w, err)
 		return
 	}
+	models.ApplyClinchStatus(stats, matches, opts)
+
+	json.NewEncoder(w).Encode(stats)
+}
+
+// simulateMatch simulates and saves a match, recording the prediction made
+// before it. Played matches are skipped unless resimulate is set, the
+// overwritten result is kept in the audit log either way.
+func simulateMatch(db database.Database, match *models.Match, homeTeam, awayTeam *models.Team, resimulate bool, actor string) error {
+	if match.Played && !resimulate {
+		return nil
+	}
 
-	// Create a map of teams for quick lookup
+	old := *match
+	prediction := match.Predict(homeTeam, awayTeam)
+	match.Simulate(homeTeam, awayTeam)
+	err := database.RecordMatchChange(db, &old, match, "simulation", actor)
+	if err != nil {
+		return err
+	}
+
+	return db.SavePredictionRecord(models.NewPredictionRecord(prediction, match, "simulation"))
+}
+
+// teamsByID creates a map of teams for quick lookup
+func teamsByID(teams []models.Team) map[uint]*models.Team {
 	teamMap := make(map[uint]*models.Team)
 	for i := range teams {
 		teamMap[teams[i].ID] = &teams[i]
 	}
+	return teamMap
+}
+
+// resimulateParam parses the resimulate query parameter, false when absent
+func resimulateParam(r *http.Request) (bool, error) {
+	value := r.URL.Query().Get("resimulate")
+	if value == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(value)
+}
+
+// SimulateWeek simulates the unplayed matches of a week of the current
+// season in a single transaction, or all of them with resimulate=true
+func (h *APIHandler) SimulateWeek(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	week, err := strconv.Atoi(vars["week"])
+	if err != nil {
+		badRequest(w, "Invalid week number")
+		return
+	}
+
+	resimulate, err := resimulateParam(r)
+	if err != nil {
+		badRequest(w, "Invalid resimulate flag")
+		return
+	}
+
+	season := h.currentSeason(w)
+	if season == nil {
+		return
+	}
+	unlock := h.locks.lock(season.ID)
+	defer unlock()
 
-	// Simulate matches
-	for i := range matches {
-		homeTeam := teamMap[matches[i].HomeTeamID]
-		awayTeam := teamMap[matches[i].AwayTeamID]
-		matches[i].Simulate(homeTeam, awayTeam)
-		err = h.db.UpdateMatch(&matches[i])
+	var matches []models.Match
+	err = h.db.WithTx(func(tx database.Database) error {
+		matches, err = tx.GetSeasonWeek(season.ID, week)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
+			return err
 		}
+		if len(matches) == 0 {
+			return errWeekNotFound
+		}
+
+		teams, err := tx.GetTeams()
+		if err != nil {
+			return err
+		}
+		teamMap := teamsByID(teams)
+
+		// Simulate matches
+		for i := range matches {
+			homeTeam := teamMap[matches[i].HomeTeamID]
+			awayTeam := teamMap[matches[i].AwayTeamID]
+			err = simulateMatch(tx, &matches[i], homeTeam, awayTeam, resimulate, requestActor(r))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, errWeekNotFound) {
+		notFound(w, "Week not found")
+		return
+	}
+	if err != nil {
+		writeUpdateError(w, err)
+		return
 	}
 
 	json.NewEncoder(w).Encode(matches)
 }
 
-// SimulateAll simulates all remaining matches
+// SimulateAll simulates the remaining matches of the current season in a
+// single transaction, or the whole season again with resimulate=true
 func (h *APIHandler) SimulateAll(w http.ResponseWriter, r *http.Request) {
-	matches, err := h.db.GetMatches()
+	resimulate, err := resimulateParam(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		badRequest(w, "Invalid resimulate flag")
 		return
 	}
 
-	teams, err := h.db.GetTeams()
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	season := h.currentSeason(w)
+	if season == nil {
 		return
 	}
+	unlock := h.locks.lock(season.ID)
+	defer unlock()
 
-	// Create a map of teams for quick lookup
-	teamMap := make(map[uint]*models.Team)
-	for i := range teams {
-		teamMap[teams[i].ID] = &teams[i]
-	}
-
-	// Find max week
-	maxWeek := 0
-	for _, match := range matches {
-		if match.Week > maxWeek {
-			maxWeek = match.Week
+	var matches []models.Match
+	err = h.db.WithTx(func(tx database.Database) error {
+		teams, err := tx.GetTeams()
+		if err != nil {
+			return err
 		}
-	}
+		teamMap := teamsByID(teams)
 
-	// Simulate all weeks
-	for week := 1; week <= maxWeek; week++ {
-		weekMatches, err := h.db.GetMatchesByWeek(week)
+		matches, err = tx.GetMatchesBySeason(season.ID)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
+			return err
 		}
-		for i := range weekMatches {
-			homeTeam := teamMap[weekMatches[i].HomeTeamID]
-			awayTeam := teamMap[weekMatches[i].AwayTeamID]
-			weekMatches[i].Simulate(homeTeam, awayTeam)
-			err = h.db.UpdateMatch(&weekMatches[i])
+
+		// Find max week
+		maxWeek := 0
+		for _, match := range matches {
+			if match.Week > maxWeek {
+				maxWeek = match.Week
+			}
+		}
+
+		// Simulate all weeks
+		for week := 1; week <= maxWeek; week++ {
+			weekMatches, err := tx.GetSeasonWeek(season.ID, week)
 			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				return
+				return err
+			}
+			for i := range weekMatches {
+				homeTeam := teamMap[weekMatches[i].HomeTeamID]
+				awayTeam := teamMap[weekMatches[i].AwayTeamID]
+				err = simulateMatch(tx, &weekMatches[i], homeTeam, awayTeam, resimulate, requestActor(r))
+				if err != nil {
+					return err
+				}
 			}
 		}
+
+		// Return the updated matches rather than the ones fetched above
+		matches, err = tx.GetMatchesBySeason(season.ID)
+		return err
+	})
+	if err != nil {
+		writeUpdateError(w, err)
+		return
 	}
 
 	json.NewEncoder(w).Encode(matches)
@@ -150,76 +443,165 @@ func (h *APIHandler) UpdateMatchResult(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	matchID, err := strconv.ParseUint(vars["id"], 10, 32)
 	if err != nil {
-		http.Error(w, "Invalid match ID", http.StatusBadRequest)
+		badRequest(w, "Invalid match ID")
 		return
 	}
 
 	var result models.MatchResult
-	err = json.NewDecoder(r.Body).Decode(&result)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	if !decodeJSON(w, r, &result) {
 		return
 	}
-
-	matches, err := h.db.GetMatches()
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if details := validateGoals("", result.HomeGoals, result.AwayGoals); len(details) > 0 {
+		validationFailed(w, details)
 		return
 	}
 
-	// Find the match
-	var match *models.Match
-	for i := range matches {
-		if matches[i].ID == uint(matchID) {
-			match = &matches[i]
-			break
-		}
+	match, err := h.db.GetMatch(uint(matchID))
+	if err != nil {
+		internalError(w, err)
+		return
 	}
-
 	if match == nil {
-		http.Error(w, "Match not found", http.StatusNotFound)
+		notFound(w, "Match not found")
 		return
 	}
 
-	// Update the match result
-	match.UpdateResult(result.HomeGoals, result.AwayGoals)
-	err = h.db.UpdateMatch(match)
+	unlock := h.locks.lock(match.SeasonID)
+	defer unlock()
+
+	// Update the match result together with the prediction record, the match
+	// is read again now that the season is locked
+	err = h.db.WithTx(func(tx database.Database) error {
+		match, err = tx.GetMatch(uint(matchID))
+		if err != nil {
+			return err
+		}
+		if match == nil {
+			return errMatchNotFound
+		}
+		if result.Version != nil && *result.Version != match.Version {
+			return database.ErrVersionConflict
+		}
+
+		old := *match
+		prediction := match.Predict(&match.HomeTeam, &match.AwayTeam)
+		match.UpdateResult(result.HomeGoals, result.AwayGoals)
+		err = database.RecordMatchChange(tx, &old, match, "manual", requestActor(r))
+		if err != nil {
+			return err
+		}
+		return tx.SavePredictionRecord(models.NewPredictionRecord(prediction, match, "manual"))
+	})
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeUpdateError(w, err)
 		return
 	}
 
 	json.NewEncoder(w).Encode(match)
 }
 
-// ResetLeague resets the database and reinitializes the league
+// resetResponse is the table and the matches of a season after a reset
+type resetResponse struct {
+	Stats   []models.TeamStats `json:"stats"`
+	Matches []models.Match     `json:"matches"`
+}
+
+// ResetLeague resets the current season, see resetSeason
 func (h *APIHandler) ResetLeague(w http.ResponseWriter, r *http.Request) {
-	err := h.db.ResetDatabase()
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	season := h.currentSeason(w)
+	if season == nil {
 		return
 	}
+	h.resetSeason(w, r, season)
+}
 
-	// Get updated data
-	stats, err := h.db.GetLeagueStats()
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+// ResetSeason resets a single season, see resetSeason
+func (h *APIHandler) ResetSeason(w http.ResponseWriter, r *http.Request) {
+	season := h.seasonFromPath(w, r)
+	if season == nil {
+		return
+	}
+	h.resetSeason(w, r, season)
+}
+
+// resetSeason clears the results of a season (?mode=results, the default)
+// or replaces its fixtures with new ones (?mode=fixtures). Teams and other
+// seasons are kept. Cleared results go through the audit log and can be
+// undone, new fixtures start a new event stream for the season.
+func (h *APIHandler) resetSeason(w http.ResponseWriter, r *http.Request, season *models.Season) {
+	mode := r.URL.Query().Get("mode")
+	if mode == "" {
+		mode = "results"
+	}
+	if mode != "results" && mode != "fixtures" {
+		badRequest(w, "Invalid mode, use results or fixtures")
 		return
 	}
 
-	matches, err := h.db.GetMatches()
+	unlock := h.locks.lock(season.ID)
+	defer unlock()
+
+	var teams []models.Team
+	var matches []models.Match
+	var deductions map[uint]int
+	err := h.db.WithTx(func(tx database.Database) error {
+		var err error
+		teams, err = tx.GetSeasonTeams(season.ID)
+		if err != nil {
+			return err
+		}
+
+		if mode == "fixtures" {
+			// A season without fixtures is scheduled for every team
+			if len(teams) == 0 {
+				teams, err = tx.GetTeams()
+				if err != nil {
+					return err
+				}
+			}
+			err = tx.ClearSeason(season.ID)
+			if err != nil {
+				return err
+			}
+			err = database.ScheduleFixtures(tx, season.ID, teams)
+		} else {
+			matches, err = tx.GetMatchesBySeason(season.ID)
+			if err != nil {
+				return err
+			}
+			for i := range matches {
+				if !matches[i].Played {
+					continue
+				}
+				old := matches[i]
+				matches[i].ClearResult()
+				err = database.RecordMatchChange(tx, &old, &matches[i], "reset", requestActor(r))
+				if err != nil {
+					return err
+				}
+			}
+		}
+		if err != nil {
+			return err
+		}
+
+		matches, err = tx.GetMatchesBySeason(season.ID)
+		if err != nil {
+			return err
+		}
+		deductions, err = seasonDeductions(tx, season.ID)
+		return err
+	})
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeUpdateError(w, err)
 		return
 	}
 
-	response := struct {
-		Stats   []models.TeamStats `json:"stats"`
-		Matches []models.Match     `json:"matches"`
-	}{
-		Stats:   stats,
+	response := resetResponse{
+		Stats:   models.NewTable(teams, matches),
 		Matches: matches,
 	}
+	models.ApplyDeductions(response.Stats, deductions)
 
 	json.NewEncoder(w).Encode(response)
 }
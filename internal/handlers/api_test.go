@@ -0,0 +1,655 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/cahitcaginkaratas/backend_insider/internal/database"
+	"github.com/cahitcaginkaratas/backend_insider/internal/handlers"
+	"github.com/cahitcaginkaratas/backend_insider/internal/importer"
+	"github.com/cahitcaginkaratas/backend_insider/internal/models"
+)
+
+// newTestRouter returns the router of a handler backed by a freshly seeded
+// in-memory database
+func newTestRouter(t *testing.T) http.Handler {
+	t.Helper()
+	return handlers.NewRouter(handlers.NewAPIHandler(newTestDB(t)))
+}
+
+// newTestDB returns a freshly seeded in-memory database
+func newTestDB(t *testing.T) database.Database {
+	t.Helper()
+	db := database.NewMemoryDB()
+	if err := db.InitDB(); err != nil {
+		t.Fatalf("init: %v", err)
+	}
+	seed, err := database.DefaultSeed()
+	if err != nil {
+		t.Fatalf("load seed: %v", err)
+	}
+	if err := database.Seed(db, seed); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+	return db
+}
+
+// do sends a request to the router and checks the status code, the body is
+// decoded into out unless it is nil
+func do(t *testing.T, router http.Handler, method, path, body string, status int, out interface{}) {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != status {
+		t.Fatalf("%s %s: got status %d, want %d: %s", method, path, rec.Code, status, rec.Body)
+	}
+	if out != nil {
+		if err := json.Unmarshal(rec.Body.Bytes(), out); err != nil {
+			t.Fatalf("%s %s: decode %q: %v", method, path, rec.Body, err)
+		}
+	}
+}
+
+// errorBody is the error envelope as a client sees it
+type errorBody struct {
+	Error handlers.APIError `json:"error"`
+}
+
+func TestSimulateWeek(t *testing.T) {
+	router := newTestRouter(t)
+
+	var matches []models.Match
+	do(t, router, http.MethodPost, "/api/matches/simulate/1", "", http.StatusOK, &matches)
+	if len(matches) != 2 {
+		t.Fatalf("got %d matches, want 2", len(matches))
+	}
+	for _, match := range matches {
+		if match.Week != 1 || !match.Played || match.Version != 1 {
+			t.Errorf("match %d: week %d played %v version %d, want a played week 1 match at version 1",
+				match.ID, match.Week, match.Played, match.Version)
+		}
+	}
+
+	var table []models.TeamStats
+	do(t, router, http.MethodGet, "/api/league", "", http.StatusOK, &table)
+	played := 0
+	for _, row := range table {
+		played += row.Played
+	}
+	if played != 4 {
+		t.Errorf("table counts %d appearances, want 4", played)
+	}
+
+	var week2 []models.Match
+	do(t, router, http.MethodPost, "/api/matches/simulate/2", "", http.StatusOK, &week2)
+	do(t, router, http.MethodPost, "/api/matches/simulate/1", "", http.StatusOK, &matches)
+	for _, match := range matches {
+		if match.Week == 1 && match.Version != 1 {
+			t.Errorf("match %d was simulated again without resimulate", match.ID)
+		}
+	}
+
+	var body errorBody
+	do(t, router, http.MethodPost, "/api/matches/simulate/99", "", http.StatusNotFound, &body)
+	if body.Error.Code != handlers.CodeNotFound {
+		t.Errorf("got code %q for an unknown week, want %q", body.Error.Code, handlers.CodeNotFound)
+	}
+}
+
+func TestUpdateMatchVersionConflict(t *testing.T) {
+	router := newTestRouter(t)
+
+	var match models.Match
+	do(t, router, http.MethodPut, "/api/matches/1", `{"home_goals": 2, "away_goals": 1, "version": 0}`, http.StatusOK, &match)
+	if !match.Played || match.HomeGoals != 2 || match.AwayGoals != 1 || match.Version != 1 {
+		t.Fatalf("got %d-%d played %v version %d, want a played 2-1 at version 1",
+			match.HomeGoals, match.AwayGoals, match.Played, match.Version)
+	}
+
+	// A client that still holds version 0 must not overwrite the result
+	var body errorBody
+	do(t, router, http.MethodPut, "/api/matches/1", `{"home_goals": 0, "away_goals": 0, "version": 0}`, http.StatusConflict, &body)
+	if body.Error.Code != handlers.CodeConflict {
+		t.Errorf("got code %q, want %q", body.Error.Code, handlers.CodeConflict)
+	}
+
+	do(t, router, http.MethodGet, "/api/matches/1", "", http.StatusOK, &match)
+	if match.HomeGoals != 2 || match.AwayGoals != 1 || match.Version != 1 {
+		t.Errorf("conflicting update changed the match to %d-%d version %d", match.HomeGoals, match.AwayGoals, match.Version)
+	}
+
+	do(t, router, http.MethodPut, "/api/matches/1", `{"home_goals": 0, "away_goals": 0, "version": 1}`, http.StatusOK, &match)
+	if match.HomeGoals != 0 || match.AwayGoals != 0 || match.Version != 2 {
+		t.Errorf("got %d-%d version %d, want 0-0 at version 2", match.HomeGoals, match.AwayGoals, match.Version)
+	}
+}
+
+func TestUndoMatch(t *testing.T) {
+	router := newTestRouter(t)
+
+	var match models.Match
+	do(t, router, http.MethodPut, "/api/matches/1", `{"home_goals": 3, "away_goals": 0}`, http.StatusOK, &match)
+	do(t, router, http.MethodPut, "/api/matches/1", `{"home_goals": 1, "away_goals": 1}`, http.StatusOK, &match)
+
+	do(t, router, http.MethodPost, "/api/matches/1/undo", "", http.StatusOK, &match)
+	if !match.Played || match.HomeGoals != 3 || match.AwayGoals != 0 {
+		t.Errorf("first undo: got %d-%d played %v, want 3-0", match.HomeGoals, match.AwayGoals, match.Played)
+	}
+	do(t, router, http.MethodPost, "/api/matches/1/undo", "", http.StatusOK, &match)
+	if match.Played {
+		t.Errorf("second undo: got a played %d-%d, want an unplayed match", match.HomeGoals, match.AwayGoals)
+	}
+
+	// A result cleared by a reset comes back with undo and the reset entry is
+	// then marked as undone, the next undo reverts the result itself
+	do(t, router, http.MethodPut, "/api/matches/1", `{"home_goals": 2, "away_goals": 2}`, http.StatusOK, &match)
+	do(t, router, http.MethodPost, "/api/reset", "", http.StatusOK, nil)
+	do(t, router, http.MethodPost, "/api/matches/1/undo", "", http.StatusOK, &match)
+	if !match.Played || match.HomeGoals != 2 || match.AwayGoals != 2 {
+		t.Errorf("undo of a reset: got %d-%d played %v, want 2-2", match.HomeGoals, match.AwayGoals, match.Played)
+	}
+	var audits []models.MatchAudit
+	do(t, router, http.MethodGet, "/api/matches/1/audit", "", http.StatusOK, &audits)
+	resets := 0
+	for _, audit := range audits {
+		if audit.Source == "reset" {
+			resets++
+			if !audit.Undone {
+				t.Errorf("reset entry %d is not marked as undone", audit.ID)
+			}
+		}
+	}
+	if resets != 1 {
+		t.Errorf("got %d reset entries in the audit log, want 1", resets)
+	}
+	do(t, router, http.MethodPost, "/api/matches/1/undo", "", http.StatusOK, &match)
+	if match.Played {
+		t.Errorf("undo after the reset: got a played %d-%d, want an unplayed match", match.HomeGoals, match.AwayGoals)
+	}
+
+	var body errorBody
+	do(t, router, http.MethodPost, "/api/matches/1/undo", "", http.StatusConflict, &body)
+	if body.Error.Code != handlers.CodeConflict {
+		t.Errorf("got code %q with nothing to undo, want %q", body.Error.Code, handlers.CodeConflict)
+	}
+}
+
+// calibratedMatches returns the number of matches scored in the calibration
+// report across every engine
+func calibratedMatches(t *testing.T, router http.Handler) int {
+	t.Helper()
+	var reports []models.EngineReport
+	do(t, router, http.MethodGet, "/api/reports/calibration?source=all", "", http.StatusOK, &reports)
+	matches := 0
+	for _, report := range reports {
+		matches += report.Matches
+	}
+	return matches
+}
+
+func TestCalibrationAfterUndo(t *testing.T) {
+	router := newTestRouter(t)
+
+	var match models.Match
+	do(t, router, http.MethodPut, "/api/matches/1", `{"home_goals": 3, "away_goals": 0}`, http.StatusOK, &match)
+	if got := calibratedMatches(t, router); got != 1 {
+		t.Fatalf("got %d matches in the calibration, want 1", got)
+	}
+
+	// The prediction was scored against a result that no longer exists
+	do(t, router, http.MethodPost, "/api/matches/1/undo", "", http.StatusOK, &match)
+	if got := calibratedMatches(t, router); got != 0 {
+		t.Errorf("got %d matches in the calibration after an undo, want 0", got)
+	}
+
+	var matches []models.Match
+	do(t, router, http.MethodPost, "/api/matches/simulate-all", "", http.StatusOK, &matches)
+	if got := calibratedMatches(t, router); got != len(matches) {
+		t.Fatalf("got %d matches in the calibration, want %d", got, len(matches))
+	}
+	// Simulated results are left out unless they are asked for
+	var reports []models.EngineReport
+	do(t, router, http.MethodGet, "/api/reports/calibration", "", http.StatusOK, &reports)
+	if len(reports) != 0 {
+		t.Errorf("got %d engine reports of simulated results, want none", len(reports))
+	}
+	do(t, router, http.MethodGet, "/api/reports/calibration?source=simulation", "", http.StatusOK, &reports)
+	if len(reports) == 0 || reports[0].Matches != len(matches) {
+		t.Errorf("got %+v for the simulated results, want %d matches", reports, len(matches))
+	}
+	do(t, router, http.MethodPost, "/api/reset", "", http.StatusOK, nil)
+	if got := calibratedMatches(t, router); got != 0 {
+		t.Errorf("got %d matches in the calibration after a results reset, want 0", got)
+	}
+}
+
+func TestReplayMatchesStoredState(t *testing.T) {
+	db := newTestDB(t)
+	router := handlers.NewRouter(handlers.NewAPIHandler(db))
+
+	var match models.Match
+	do(t, router, http.MethodPost, "/api/matches/simulate/1", "", http.StatusOK, nil)
+	do(t, router, http.MethodPut, "/api/matches/1", `{"home_goals": 4, "away_goals": 0}`, http.StatusOK, &match)
+	do(t, router, http.MethodPut, "/api/matches/1", `{"home_goals": 1, "away_goals": 1}`, http.StatusOK, &match)
+	do(t, router, http.MethodPost, "/api/matches/1/undo", "", http.StatusOK, &match)
+	do(t, router, http.MethodPut, "/api/matches/3", `{"home_goals": 2, "away_goals": 2}`, http.StatusOK, &match)
+	do(t, router, http.MethodPost, "/api/matches/3/undo", "", http.StatusOK, &match)
+	do(t, router, http.MethodPost, "/api/teams/2/deductions", `{"points": 2, "reason": "test"}`, http.StatusCreated, nil)
+	do(t, router, http.MethodPost, "/api/matches/simulate/2", "", http.StatusOK, nil)
+
+	var replay struct {
+		Matches []models.Match     `json:"matches"`
+		Table   []models.TeamStats `json:"table"`
+	}
+	do(t, router, http.MethodGet, "/api/seasons/1/replay", "", http.StatusOK, &replay)
+
+	stored, err := db.GetMatchesBySeason(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replay.Matches) != len(stored) {
+		t.Fatalf("replay has %d matches, the database %d", len(replay.Matches), len(stored))
+	}
+	for i, want := range stored {
+		got := replay.Matches[i]
+		if got.ID != want.ID || got.Played != want.Played || got.HomeGoals != want.HomeGoals || got.AwayGoals != want.AwayGoals {
+			t.Errorf("match %d: replay has %d-%d played %v, the database %d-%d played %v",
+				want.ID, got.HomeGoals, got.AwayGoals, got.Played, want.HomeGoals, want.AwayGoals, want.Played)
+		}
+	}
+
+	var table []models.TeamStats
+	do(t, router, http.MethodGet, "/api/league", "", http.StatusOK, &table)
+	if len(replay.Table) != len(table) {
+		t.Fatalf("replay has %d rows, the table %d", len(replay.Table), len(table))
+	}
+	for i, want := range table {
+		got := replay.Table[i]
+		if got.TeamID != want.TeamID || got.Played != want.Played || got.Won != want.Won || got.Drawn != want.Drawn ||
+			got.GoalsFor != want.GoalsFor || got.GoalsAgainst != want.GoalsAgainst || got.Points != want.Points {
+			t.Errorf("row %d: replay has %+v, the table %+v", i+1, got, want)
+		}
+	}
+}
+
+func TestResetLeague(t *testing.T) {
+	router := newTestRouter(t)
+
+	var matches []models.Match
+	do(t, router, http.MethodPost, "/api/matches/simulate-all", "", http.StatusOK, &matches)
+
+	var reset struct {
+		Stats   []models.TeamStats `json:"stats"`
+		Matches []models.Match     `json:"matches"`
+	}
+	do(t, router, http.MethodPost, "/api/reset", "", http.StatusOK, &reset)
+	if len(reset.Matches) != len(matches) {
+		t.Errorf("got %d matches after a reset, want the %d fixtures", len(reset.Matches), len(matches))
+	}
+	for _, match := range reset.Matches {
+		if match.Played {
+			t.Errorf("match %d is still played after a reset", match.ID)
+		}
+	}
+	for _, row := range reset.Stats {
+		if row.Played != 0 || row.Points != 0 {
+			t.Errorf("%s has played %d with %d points after a reset", row.TeamName, row.Played, row.Points)
+		}
+	}
+
+	// The undo history survives a results reset, so the results can come back
+	var match models.Match
+	do(t, router, http.MethodPost, "/api/matches/1/undo", "", http.StatusOK, &match)
+	if !match.Played {
+		t.Error("undo after a reset did not restore the result")
+	}
+
+	do(t, router, http.MethodPost, "/api/reset?mode=fixtures", "", http.StatusOK, &reset)
+	if len(reset.Matches) != len(matches) {
+		t.Errorf("got %d matches after a fixtures reset, want %d", len(reset.Matches), len(matches))
+	}
+	for _, match := range reset.Matches {
+		if match.Played || match.Version != 0 {
+			t.Errorf("match %d is not a fresh fixture after a fixtures reset", match.ID)
+		}
+	}
+}
+
+func TestHeadToHeadAcrossSeasons(t *testing.T) {
+	db := newTestDB(t)
+	router := handlers.NewRouter(handlers.NewAPIHandler(db))
+
+	first, err := db.GetCurrentSeason()
+	if err != nil {
+		t.Fatalf("current season: %v", err)
+	}
+	teams, err := db.GetTeams()
+	if err != nil {
+		t.Fatalf("teams: %v", err)
+	}
+	second := models.NewSeason("Season 2")
+	if err := db.SaveSeason(second); err != nil {
+		t.Fatalf("save season: %v", err)
+	}
+	if err := database.ScheduleFixtures(db, second.ID, teams); err != nil {
+		t.Fatalf("schedule: %v", err)
+	}
+
+	// Play the first meeting of the same two teams in both seasons
+	var home, away uint
+	for _, seasonID := range []uint{first.ID, second.ID} {
+		matches, err := db.GetMatchesBySeason(seasonID)
+		if err != nil {
+			t.Fatalf("matches: %v", err)
+		}
+		var meeting *models.Match
+		for i := range matches {
+			match := &matches[i]
+			if home == 0 && match.HomeTeamID != match.AwayTeamID {
+				home, away = match.HomeTeamID, match.AwayTeamID
+			}
+			if match.HomeTeamID == home && match.AwayTeamID == away {
+				meeting = match
+				break
+			}
+		}
+		if meeting == nil {
+			t.Fatalf("season %d has no meeting of teams %d and %d", seasonID, home, away)
+		}
+		do(t, router, http.MethodPut, fmt.Sprintf("/api/matches/%d", meeting.ID), `{"home_goals": 2, "away_goals": 1}`, http.StatusOK, nil)
+	}
+
+	var h2h models.HeadToHead
+	do(t, router, http.MethodGet, fmt.Sprintf("/api/teams/%d/vs/%d", home, away), "", http.StatusOK, &h2h)
+	if h2h.Overall.Played != 2 || h2h.Overall.Won != 2 {
+		t.Errorf("got %d played and %d won across seasons, want 2 and 2", h2h.Overall.Played, h2h.Overall.Won)
+	}
+
+	do(t, router, http.MethodGet, fmt.Sprintf("/api/teams/%d/vs/%d?season=%d", home, away, first.ID), "", http.StatusOK, &h2h)
+	if h2h.Overall.Played != 1 {
+		t.Errorf("got %d played in season %d, want 1", h2h.Overall.Played, first.ID)
+	}
+}
+
+func TestRecordsWithDeductions(t *testing.T) {
+	router := newTestRouter(t)
+
+	var match models.Match
+	do(t, router, http.MethodPut, "/api/matches/1", `{"home_goals": 3, "away_goals": 0}`, http.StatusOK, &match)
+	do(t, router, http.MethodPost, fmt.Sprintf("/api/teams/%d/deductions", match.HomeTeamID), `{"points": 3, "reason": "test"}`, http.StatusCreated, nil)
+
+	// The winner's three points are deducted again, so nobody has any
+	var records models.Records
+	do(t, router, http.MethodGet, "/api/records", "", http.StatusOK, &records)
+	if len(records.MostPointsByWeek) == 0 {
+		t.Fatal("got no points by week")
+	}
+	if best := records.MostPointsByWeek[0]; best.Points != 0 {
+		t.Errorf("got %d points for %s after week %d, want 0", best.Points, best.TeamName, best.Week)
+	}
+}
+
+func TestDeductionsByWeekAndView(t *testing.T) {
+	router := newTestRouter(t)
+
+	var match models.Match
+	do(t, router, http.MethodPut, "/api/matches/1", `{"home_goals": 3, "away_goals": 0}`, http.StatusOK, &match)
+	if match.Week != 1 {
+		t.Fatalf("match 1 is in week %d, want 1", match.Week)
+	}
+	do(t, router, http.MethodPost, "/api/matches/simulate/2", "", http.StatusOK, nil)
+
+	points := func(path string) int {
+		t.Helper()
+		var table []models.TeamStats
+		do(t, router, http.MethodGet, path, "", http.StatusOK, &table)
+		for _, row := range table {
+			if row.TeamID == match.HomeTeamID {
+				return row.Points
+			}
+		}
+		t.Fatalf("%s has no row for team %d", path, match.HomeTeamID)
+		return 0
+	}
+	overall := points("/api/league/table")
+	home := points("/api/league/table?view=home")
+
+	do(t, router, http.MethodPost, fmt.Sprintf("/api/teams/%d/deductions", match.HomeTeamID), `{"points": 2, "reason": "test"}`, http.StatusCreated, nil)
+
+	if got := points("/api/league/table"); got != overall-2 {
+		t.Errorf("got %d points overall, want %d", got, overall-2)
+	}
+	if got := points("/api/league/table?view=home"); got != home {
+		t.Errorf("got %d home points, want %d without the deduction", got, home)
+	}
+	// The deduction was made after week 2, the table after week 1 is unchanged
+	if got := points("/api/league/table?week=1"); got != 3 {
+		t.Errorf("got %d points after week 1, want 3", got)
+	}
+}
+
+func TestImportIntoNewSeason(t *testing.T) {
+	db := newTestDB(t)
+	router := handlers.NewRouter(handlers.NewAPIHandler(db))
+
+	do(t, router, http.MethodPut, "/api/matches/1", `{"home_goals": 3, "away_goals": 0}`, http.StatusOK, nil)
+	current, err := db.GetCurrentSeason()
+	if err != nil {
+		t.Fatalf("current season: %v", err)
+	}
+	before, err := db.GetMatchesBySeason(current.ID)
+	if err != nil {
+		t.Fatalf("matches: %v", err)
+	}
+
+	file := `{"matches": [
+		{"date": "2023-08-12", "home_team": "Arsenal", "away_team": "Brighton", "home_goals": 2, "away_goals": 2},
+		{"date": "2023-08-19", "home_team": "Brighton", "away_team": "Chelsea", "home_goals": 1, "away_goals": 0}
+	]}`
+
+	// A dry run creates no season
+	var summary importer.Summary
+	do(t, router, http.MethodPost, "/api/import?new_season=2023&dry_run=true", file, http.StatusOK, &summary)
+	var seasons []models.Season
+	do(t, router, http.MethodGet, "/api/seasons", "", http.StatusOK, &seasons)
+	if len(seasons) != 1 {
+		t.Fatalf("got %d seasons after a dry run, want 1", len(seasons))
+	}
+
+	do(t, router, http.MethodPost, "/api/import?new_season=2023", file, http.StatusOK, &summary)
+	if summary.SeasonID == current.ID || summary.MatchesCreated != 2 {
+		t.Errorf("imported %d matches into season %d, want 2 into a new season", summary.MatchesCreated, summary.SeasonID)
+	}
+
+	// A season created on its own can be imported into as well
+	var season models.Season
+	do(t, router, http.MethodPost, "/api/seasons", `{"name": "2022"}`, http.StatusCreated, &season)
+	do(t, router, http.MethodPost, fmt.Sprintf("/api/import?season=%d", season.ID), file, http.StatusOK, &summary)
+	if summary.SeasonID != season.ID || summary.MatchesCreated != 2 {
+		t.Errorf("imported %d matches into season %d, want 2 into season %d", summary.MatchesCreated, summary.SeasonID, season.ID)
+	}
+
+	// The season that was current keeps its matches and results
+	after, err := db.GetMatchesBySeason(current.ID)
+	if err != nil {
+		t.Fatalf("matches: %v", err)
+	}
+	if len(after) != len(before) {
+		t.Fatalf("season %d has %d matches after the imports, want %d", current.ID, len(after), len(before))
+	}
+	for i := range before {
+		if after[i].ID != before[i].ID || after[i].Played != before[i].Played || after[i].HomeGoals != before[i].HomeGoals ||
+			after[i].AwayGoals != before[i].AwayGoals || after[i].Version != before[i].Version {
+			t.Errorf("match %d changed from %+v to %+v", before[i].ID, before[i], after[i])
+		}
+	}
+}
+
+func TestSeasonTablesListSeasonTeams(t *testing.T) {
+	db := newTestDB(t)
+	router := handlers.NewRouter(handlers.NewAPIHandler(db))
+
+	seeded, err := db.GetTeams()
+	if err != nil {
+		t.Fatalf("teams: %v", err)
+	}
+	current, err := db.GetCurrentSeason()
+	if err != nil {
+		t.Fatalf("current season: %v", err)
+	}
+
+	file := `{"matches": [
+		{"date": "2023-08-12", "home_team": "Zed", "away_team": "Yak", "home_goals": 1, "away_goals": 0}
+	]}`
+	var summary importer.Summary
+	do(t, router, http.MethodPost, "/api/import?new_season=2023", file, http.StatusOK, &summary)
+
+	var table []models.TeamStats
+	do(t, router, http.MethodGet, fmt.Sprintf("/api/league?season=%d", current.ID), "", http.StatusOK, &table)
+	if len(table) != len(seeded) {
+		t.Errorf("season %d table has %d rows, want %d", current.ID, len(table), len(seeded))
+	}
+	for _, row := range table {
+		if row.TeamName == "Zed" || row.TeamName == "Yak" {
+			t.Errorf("season %d table lists %s", current.ID, row.TeamName)
+		}
+	}
+
+	for _, path := range []string{"/api/league", "/api/league/table"} {
+		do(t, router, http.MethodGet, fmt.Sprintf("%s?season=%d", path, summary.SeasonID), "", http.StatusOK, &table)
+		if len(table) != 2 || table[0].TeamName != "Zed" || table[1].TeamName != "Yak" {
+			t.Errorf("%s of the imported season is %+v, want Zed and Yak", path, table)
+		}
+	}
+}
+
+// nextPage returns the rel="next" link of a response, or "" on the last page
+func nextPage(header http.Header) string {
+	for _, link := range header.Values("Link") {
+		target, rel, ok := strings.Cut(link, ">; ")
+		if ok && rel == `rel="next"` {
+			return strings.TrimPrefix(target, "<")
+		}
+	}
+	return ""
+}
+
+func TestMatchPagination(t *testing.T) {
+	router := newTestRouter(t)
+
+	get := func(path string) ([]models.Match, http.Header) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s: got status %d: %s", path, rec.Code, rec.Body)
+		}
+		var matches []models.Match
+		if err := json.Unmarshal(rec.Body.Bytes(), &matches); err != nil {
+			t.Fatalf("GET %s: decode: %v", path, err)
+		}
+		return matches, rec.Header()
+	}
+
+	all, header := get("/api/matches?sort=-date")
+	if len(all) < 6 || nextPage(header) != "" {
+		t.Fatalf("got %d matches and a next page, want every match on one page", len(all))
+	}
+
+	// Pages of 5 by the Link header give the same matches in the same order,
+	// matches on the same date are ordered by ID across page breaks
+	var walked []models.Match
+	pages := 0
+	for path := "/api/matches?sort=-date&limit=5"; path != ""; pages++ {
+		if pages > len(all) {
+			t.Fatal("the next links do not end")
+		}
+		page, header := get(path)
+		if len(page) > 5 {
+			t.Fatalf("got %d matches on page %d, want at most 5", len(page), pages+1)
+		}
+		walked = append(walked, page...)
+		path = nextPage(header)
+	}
+	if want := (len(all) + 4) / 5; pages != want {
+		t.Errorf("walked %d pages, want %d", pages, want)
+	}
+	if len(walked) != len(all) {
+		t.Fatalf("walked %d matches, want %d", len(walked), len(all))
+	}
+	for i := range all {
+		if walked[i].ID != all[i].ID {
+			t.Errorf("match %d of the walk is %d, want %d", i+1, walked[i].ID, all[i].ID)
+		}
+		if i > 0 && walked[i].Date.After(walked[i-1].Date) {
+			t.Errorf("match %d is dated after the match before it", walked[i].ID)
+		}
+	}
+
+	// A cursor only works with the sort order it was made for
+	_, header = get("/api/matches?sort=week&limit=5")
+	next, err := url.Parse(nextPage(header))
+	if err != nil || next.Query().Get("cursor") == "" {
+		t.Fatalf("got next link %v (%v), want one with a cursor", next, err)
+	}
+	var body errorBody
+	do(t, router, http.MethodGet, "/api/matches?sort=-date&cursor="+url.QueryEscape(next.Query().Get("cursor")), "", http.StatusBadRequest, &body)
+	if body.Error.Message != "The cursor belongs to another sort order" {
+		t.Errorf("got %q for a cursor of another sort order", body.Error.Message)
+	}
+
+	do(t, router, http.MethodGet, "/api/matches?venue=home", "", http.StatusBadRequest, &body)
+	if body.Error.Message != "The venue filter needs a team" {
+		t.Errorf("got %q for a venue without a team", body.Error.Message)
+	}
+}
+
+func TestErrorEnvelopes(t *testing.T) {
+	router := newTestRouter(t)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+		code   string
+	}{
+		{"unknown route", http.MethodGet, "/api/nothing", "", http.StatusNotFound, handlers.CodeNotFound},
+		{"unknown match", http.MethodGet, "/api/matches/999", "", http.StatusNotFound, handlers.CodeNotFound},
+		{"update unknown match", http.MethodPut, "/api/matches/999", `{"home_goals": 1, "away_goals": 0, "version": 0}`, http.StatusNotFound, handlers.CodeNotFound},
+		{"undo unknown match", http.MethodPost, "/api/matches/999/undo", "", http.StatusNotFound, handlers.CodeNotFound},
+		{"unknown team", http.MethodGet, "/api/teams/999", "", http.StatusNotFound, handlers.CodeNotFound},
+		{"invalid match ID", http.MethodGet, "/api/matches/abc", "", http.StatusBadRequest, handlers.CodeBadRequest},
+		{"invalid week", http.MethodPost, "/api/matches/simulate/abc", "", http.StatusBadRequest, handlers.CodeBadRequest},
+		{"invalid query", http.MethodGet, "/api/league/table?view=sideways", "", http.StatusBadRequest, handlers.CodeBadRequest},
+		{"malformed body", http.MethodPut, "/api/matches/1", `{"home_goals":`, http.StatusBadRequest, handlers.CodeBadRequest},
+		{"negative goals", http.MethodPut, "/api/matches/1", `{"home_goals": -1, "away_goals": 0}`, http.StatusUnprocessableEntity, handlers.CodeValidationFailed},
+		{"unknown field", http.MethodPut, "/api/matches/1", `{"home_goals": 1, "away_goals": 0, "extra": true}`, http.StatusUnprocessableEntity, handlers.CodeValidationFailed},
+		{"wrong method", http.MethodDelete, "/api/teams", "", http.StatusMethodNotAllowed, handlers.CodeMethodNotAllowed},
+		{"import too large", http.MethodPost, "/api/import", `{"matches": [` + strings.Repeat(" ", 16<<20) + `]}`, http.StatusRequestEntityTooLarge, handlers.CodePayloadTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body errorBody
+			do(t, router, tt.method, tt.path, tt.body, tt.status, &body)
+			if body.Error.Code != tt.code {
+				t.Errorf("got code %q, want %q", body.Error.Code, tt.code)
+			}
+			if body.Error.Message == "" {
+				t.Error("error envelope has no message")
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/cahitcaginkaratas/backend_insider/internal/database"
+	"github.com/cahitcaginkaratas/backend_insider/internal/models"
+	"github.com/gorilla/mux"
+)
+
+// errNothingToUndo is returned when a match has no change left to undo
+var errNothingToUndo = errors.New("nothing to undo")
+
+// requestActor identifies who made a request, from the X-Actor header or
+// else the client address
+func requestActor(r *http.Request) string {
+	if actor := r.Header.Get("X-Actor"); actor != "" {
+		return actor
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// GetMatchAudits returns every recorded change of a match
+func (h *APIHandler) GetMatchAudits(w http.ResponseWriter, r *http.Request) {
+	matchID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		badRequest(w, "Invalid match ID")
+		return
+	}
+
+	match, err := h.db.GetMatch(uint(matchID))
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	if match == nil {
+		notFound(w, "Match not found")
+		return
+	}
+
+	audits, err := h.db.GetMatchAudits(uint(matchID))
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(audits)
+}
+
+// UndoMatchResult reverts the latest change of a match that was not undone yet
+func (h *APIHandler) UndoMatchResult(w http.ResponseWriter, r *http.Request) {
+	matchID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		badRequest(w, "Invalid match ID")
+		return
+	}
+
+	match, err := h.db.GetMatch(uint(matchID))
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	if match == nil {
+		notFound(w, "Match not found")
+		return
+	}
+
+	unlock := h.locks.lock(match.SeasonID)
+	defer unlock()
+
+	err = h.db.WithTx(func(tx database.Database) error {
+		match, err = tx.GetMatch(uint(matchID))
+		if err != nil {
+			return err
+		}
+		if match == nil {
+			return errMatchNotFound
+		}
+
+		audits, err := tx.GetMatchAudits(match.ID)
+		if err != nil {
+			return err
+		}
+		audit := models.LastUndoable(audits)
+		if audit == nil {
+			return errNothingToUndo
+		}
+
+		old := *match
+		audit.Restore(match)
+		err = database.RecordMatchChange(tx, &old, match, "undo", requestActor(r))
+		if err != nil {
+			return err
+		}
+		audit.Undone = true
+		return tx.UpdateMatchAudit(audit)
+	})
+	if errors.Is(err, errNothingToUndo) {
+		conflict(w, "Nothing to undo")
+		return
+	}
+	if err != nil {
+		writeUpdateError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(match)
+}
+
+// RewindSeason restores every match of a season after a week to its state
+// before it was played, using the audit log
+func (h *APIHandler) RewindSeason(w http.ResponseWriter, r *http.Request) {
+	season := h.seasonFromPath(w, r)
+	if season == nil {
+		return
+	}
+	week, err := strconv.Atoi(mux.Vars(r)["week"])
+	if err != nil || week < 0 {
+		badRequest(w, "Invalid week number")
+		return
+	}
+
+	unlock := h.locks.lock(season.ID)
+	defer unlock()
+
+	var matches []models.Match
+	err = h.db.WithTx(func(tx database.Database) error {
+		matches, err = tx.GetMatchesBySeason(season.ID)
+		if err != nil {
+			return err
+		}
+		audits, err := tx.GetSeasonAudits(season.ID)
+		if err != nil {
+			return err
+		}
+
+		changed, old, undone := models.Rewind(matches, audits, week)
+		for i := range changed {
+			err = database.RecordMatchChange(tx, &old[i], &changed[i], "rewind", requestActor(r))
+			if err != nil {
+				return err
+			}
+		}
+		for i := range undone {
+			undone[i].Undone = true
+			err = tx.UpdateMatchAudit(&undone[i])
+			if err != nil {
+				return err
+			}
+		}
+
+		matches, err = tx.GetMatchesBySeason(season.ID)
+		return err
+	})
+	if err != nil {
+		writeUpdateError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(matches)
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/cahitcaginkaratas/backend_insider/internal/database"
+)
+
+// snapshotter returns the database as a Snapshotter, or writes the error
+// response and returns nil when it cannot be backed up
+func (h *APIHandler) snapshotter(w http.ResponseWriter) database.Snapshotter {
+	snapshotter, ok := h.db.(database.Snapshotter)
+	if !ok {
+		writeError(w, http.StatusNotImplemented, CodeNotImplemented, "Backups are only supported for SQLite databases")
+		return nil
+	}
+	return snapshotter
+}
+
+// backupResponse is a new backup with the old backups removed by rotation
+type backupResponse struct {
+	*database.Backup
+	Removed []string `json:"removed,omitempty"`
+}
+
+// CreateBackup writes a snapshot of the database to the backup directory
+// while the server keeps running and rotates old backups
+func (h *APIHandler) CreateBackup(w http.ResponseWriter, r *http.Request) {
+	snapshotter := h.snapshotter(w)
+	if snapshotter == nil {
+		return
+	}
+
+	backup, removed, err := snapshotter.Snapshot(h.backupDir, h.backupKeep)
+	if errors.Is(err, database.ErrBackupUnsupported) {
+		writeError(w, http.StatusNotImplemented, CodeNotImplemented, "Backups are only supported for SQLite databases")
+		return
+	}
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+
+	response := backupResponse{
+		Backup:  backup,
+		Removed: removed,
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetBackups lists the backups in the backup directory, oldest first
+func (h *APIHandler) GetBackups(w http.ResponseWriter, r *http.Request) {
+	backups, err := database.ListBackups(h.backupDir)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(backups)
+}
@@ -0,0 +1,268 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Error codes of the error envelope
+const (
+	CodeBadRequest       = "bad_request"
+	CodeValidationFailed = "validation_failed"
+	CodeNotFound         = "not_found"
+	CodeMethodNotAllowed = "method_not_allowed"
+	CodeConflict         = "conflict"
+	CodePayloadTooLarge  = "payload_too_large"
+	CodeNotImplemented   = "not_implemented"
+	CodeInternal         = "internal_error"
+)
+
+// errWeekNotFound is returned when a week has no matches
+var errWeekNotFound = errors.New("week not found")
+
+// errMatchNotFound is returned when a match was deleted before it could be updated
+var errMatchNotFound = errors.New("match not found")
+
+// errTrailingData is returned when a request body has more than one JSON value
+var errTrailingData = errors.New("unexpected data after the JSON body")
+
+// maxBodyBytes caps the size of JSON request bodies
+const maxBodyBytes = 1 << 20
+
+// APIError is the body of every error response, wrapped in {"error": ...}
+type APIError struct {
+	Code    string       `json:"code"`
+	Message string       `json:"message"`
+	Details []FieldError `json:"details,omitempty"`
+}
+
+// FieldError is a problem with one field of a request, or with one line of
+// an imported file
+type FieldError struct {
+	Field   string `json:"field,omitempty"`
+	Line    int    `json:"line,omitempty"`
+	Message string `json:"message"`
+}
+
+// errorResponse is the error envelope
+type errorResponse struct {
+	Error APIError `json:"error"`
+}
+
+// writeError writes an error response in the error envelope
+func writeError(w http.ResponseWriter, status int, code, message string, details ...FieldError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(errorResponse{
+		Error: APIError{Code: code, Message: message, Details: details},
+	})
+}
+
+// badRequest reports a malformed request, such as an invalid path or query parameter
+func badRequest(w http.ResponseWriter, message string) {
+	writeError(w, http.StatusBadRequest, CodeBadRequest, message)
+}
+
+// notFound reports a missing resource
+func notFound(w http.ResponseWriter, message string) {
+	writeError(w, http.StatusNotFound, CodeNotFound, message)
+}
+
+// conflict reports a request that conflicts with the current state
+func conflict(w http.ResponseWriter, message string) {
+	writeError(w, http.StatusConflict, CodeConflict, message)
+}
+
+// validationFailed reports a well-formed request with invalid fields
+func validationFailed(w http.ResponseWriter, details []FieldError) {
+	writeError(w, http.StatusUnprocessableEntity, CodeValidationFailed, "The request is invalid", details...)
+}
+
+// internalError logs an unexpected error and reports it without its
+// details, which may come from the database
+func internalError(w http.ResponseWriter, err error) {
+	log.Printf("Internal error: %v", err)
+	writeError(w, http.StatusInternalServerError, CodeInternal, "Internal server error")
+}
+
+// NotFound answers requests for unknown routes
+func NotFound(w http.ResponseWriter, r *http.Request) {
+	notFound(w, "No such endpoint: "+r.URL.Path)
+}
+
+// MethodNotAllowed answers requests with a method a route does not support
+func MethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, r.Method+" is not allowed on "+r.URL.Path)
+}
+
+// decodeJSON decodes a JSON request body into v, rejecting unknown fields
+// and trailing data. On failure it writes the error response and returns
+// false.
+func decodeJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	if err == nil {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		err = decoder.Decode(v)
+		if err == nil && decoder.More() {
+			err = errTrailingData
+		}
+	}
+	if err == nil {
+		// The body decoded, so it is a single valid JSON value
+		var value interface{}
+		json.Unmarshal(data, &value)
+		field := unknownField(value, reflect.TypeOf(v), "")
+		if field == "" {
+			return true
+		}
+		validationFailed(w, []FieldError{{Field: field, Message: "is not a known field"}})
+		return false
+	}
+
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.Is(err, errTrailingData):
+		badRequest(w, "Unexpected data after the JSON body")
+	case errors.Is(err, io.EOF):
+		badRequest(w, "Request body is empty")
+	case errors.As(err, &syntaxErr):
+		badRequest(w, fmt.Sprintf("Malformed JSON at offset %d: %v", syntaxErr.Offset, err))
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		badRequest(w, "Malformed JSON: unexpected end of the body")
+	case errors.As(err, &typeErr):
+		validationFailed(w, []FieldError{{Field: typeErr.Field, Message: "must be " + jsonType(typeErr.Type.Kind().String())}})
+	case errors.As(err, &maxBytesErr):
+		writeError(w, http.StatusRequestEntityTooLarge, CodePayloadTooLarge, fmt.Sprintf("Request body is larger than %d bytes", maxBodyBytes))
+	default:
+		badRequest(w, err.Error())
+	}
+	return false
+}
+
+// unknownField returns the path of the first field of a decoded JSON value
+// that type t has no field for, such as overrides[0].extra, or "" when every
+// field is known. Names match case-insensitively like encoding/json does.
+func unknownField(value interface{}, t reflect.Type, path string) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch value := value.(type) {
+	case map[string]interface{}:
+		names := make([]string, 0, len(value))
+		for name := range value {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			field := join(path, name)
+			var fieldType reflect.Type
+			switch t.Kind() {
+			case reflect.Map:
+				fieldType = t.Elem()
+			case reflect.Struct:
+				structField, ok := jsonField(t, name)
+				if !ok {
+					return field
+				}
+				fieldType = structField.Type
+			default:
+				return ""
+			}
+			if unknown := unknownField(value[name], fieldType, field); unknown != "" {
+				return unknown
+			}
+		}
+	case []interface{}:
+		if t.Kind() != reflect.Slice && t.Kind() != reflect.Array {
+			return ""
+		}
+		for i, item := range value {
+			if unknown := unknownField(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i)); unknown != "" {
+				return unknown
+			}
+		}
+	}
+	return ""
+}
+
+// jsonField finds the field of a struct that a JSON object key decodes into,
+// including the fields of embedded structs
+func jsonField(t reflect.Type, key string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				if found, ok := jsonField(embedded, key); ok {
+					return found, true
+				}
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if strings.EqualFold(name, key) {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// join appends a field name to the path of its parent object
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// jsonType names the JSON type of a Go kind, with its article
+func jsonType(kind string) string {
+	switch {
+	case strings.HasPrefix(kind, "int"), strings.HasPrefix(kind, "uint"), strings.HasPrefix(kind, "float"):
+		return "a number"
+	case kind == "bool":
+		return "a boolean"
+	case kind == "string":
+		return "a string"
+	case kind == "slice", kind == "array":
+		return "an array"
+	default:
+		return "an object"
+	}
+}
+
+// validateGoals checks the goals of a result, prefix is put before the field names
+func validateGoals(prefix string, homeGoals, awayGoals int) []FieldError {
+	var details []FieldError
+	if homeGoals < 0 {
+		details = append(details, FieldError{Field: prefix + "home_goals", Message: "must not be negative"})
+	}
+	if awayGoals < 0 {
+		details = append(details, FieldError{Field: prefix + "away_goals", Message: "must not be negative"})
+	}
+	return details
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDecodeJSONUnknownFields(t *testing.T) {
+	tests := []struct {
+		name  string
+		body  string
+		field string // the unknown field reported, "" when the body decodes
+	}{
+		{"known fields", `{"name": "x", "overrides": [{"match_id": 1, "home_goals": 2}]}`, ""},
+		{"other case", `{"Name": "x", "DESCRIPTION": "y"}`, ""},
+		{"top level", `{"name": "x", "extra": true}`, "extra"},
+		{"in a list", `{"name": "x", "overrides": [{"match_id": 1}, {"match_id": 2, "goals": 3}]}`, "overrides[1].goals"},
+		{"first in order", `{"zebra": 1, "apple": 2}`, "apple"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			var request scenarioRequest
+			ok := decodeJSON(rec, req, &request)
+			if ok != (tt.field == "") {
+				t.Fatalf("got ok %v with status %d: %s", ok, rec.Code, rec.Body)
+			}
+			if ok {
+				return
+			}
+
+			var body errorResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			details := body.Error.Details
+			if rec.Code != http.StatusUnprocessableEntity || len(details) != 1 || details[0].Field != tt.field {
+				t.Errorf("got status %d with %+v, want 422 for field %q", rec.Code, details, tt.field)
+			}
+		})
+	}
+}
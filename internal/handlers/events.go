@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cahitcaginkaratas/backend_insider/internal/database"
+	"github.com/cahitcaginkaratas/backend_insider/internal/models"
+	"github.com/gorilla/mux"
+)
+
+// seasonFromPath returns the season of the {id} path variable, or writes
+// the error response and returns nil
+func (h *APIHandler) seasonFromPath(w http.ResponseWriter, r *http.Request) *models.Season {
+	seasonID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		badRequest(w, "Invalid season ID")
+		return nil
+	}
+
+	season, err := h.db.GetSeason(uint(seasonID))
+	if err != nil {
+		internalError(w, err)
+		return nil
+	}
+	if season == nil {
+		notFound(w, "Season not found")
+		return nil
+	}
+	return season
+}
+
+// currentSeason returns the current season, or writes the error response
+// and returns nil
+func (h *APIHandler) currentSeason(w http.ResponseWriter) *models.Season {
+	season, err := h.db.GetCurrentSeason()
+	if err != nil {
+		internalError(w, err)
+		return nil
+	}
+	if season == nil {
+		notFound(w, "Season not found")
+		return nil
+	}
+	return season
+}
+
+// seasonFromQuery returns the season given with ?season=, or the current
+// season. Errors are written to w and nil is returned.
+func (h *APIHandler) seasonFromQuery(w http.ResponseWriter, r *http.Request) *models.Season {
+	value := r.URL.Query().Get("season")
+	if value == "" {
+		return h.currentSeason(w)
+	}
+
+	seasonID, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		badRequest(w, "Invalid season ID")
+		return nil
+	}
+	season, err := h.db.GetSeason(uint(seasonID))
+	if err != nil {
+		internalError(w, err)
+		return nil
+	}
+	if season == nil {
+		notFound(w, "Season not found")
+		return nil
+	}
+	return season
+}
+
+// seasonDeductions returns the points deducted from every team in a season
+func seasonDeductions(db database.Database, seasonID uint) (map[uint]int, error) {
+	events, err := db.GetEvents(seasonID)
+	if err != nil {
+		return nil, err
+	}
+	return models.Deductions(events), nil
+}
+
+// GetSeasonEvents returns the event stream of a season
+func (h *APIHandler) GetSeasonEvents(w http.ResponseWriter, r *http.Request) {
+	season := h.seasonFromPath(w, r)
+	if season == nil {
+		return
+	}
+
+	events, err := h.db.GetEvents(season.ID)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(events)
+}
+
+// replayResponse is a season rebuilt from its events with its table
+type replayResponse struct {
+	*models.LeagueState
+	Table []models.TeamStats `json:"table"`
+}
+
+// deductionRequest is the body of a points deduction
+type deductionRequest struct {
+	Points int    `json:"points" openapi:"required,minimum=1"`
+	Reason string `json:"reason"`
+}
+
+// ReplaySeason rebuilds the matches and table of a season from its events,
+// up to an event ID (?until=) and/or a point in time (?at=, RFC 3339)
+func (h *APIHandler) ReplaySeason(w http.ResponseWriter, r *http.Request) {
+	season := h.seasonFromPath(w, r)
+	if season == nil {
+		return
+	}
+
+	query := r.URL.Query()
+	var until uint64
+	if value := query.Get("until"); value != "" {
+		var err error
+		until, err = strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			badRequest(w, "Invalid event ID")
+			return
+		}
+	}
+	var at time.Time
+	if value := query.Get("at"); value != "" {
+		var err error
+		at, err = time.Parse(time.RFC3339, value)
+		if err != nil {
+			badRequest(w, "Invalid time, use RFC 3339")
+			return
+		}
+	}
+
+	teams, err := h.db.GetSeasonTeams(season.ID)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	events, err := h.db.GetEvents(season.ID)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+
+	state := models.Replay(models.EventsUntil(events, uint(until), at))
+	response := replayResponse{
+		LeagueState: state,
+		Table:       state.Table(teams),
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// DeductPoints appends a points deduction for a team to the current season
+func (h *APIHandler) DeductPoints(w http.ResponseWriter, r *http.Request) {
+	teamID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		badRequest(w, "Invalid team ID")
+		return
+	}
+
+	var deduction deductionRequest
+	if !decodeJSON(w, r, &deduction) {
+		return
+	}
+	if deduction.Points < 1 {
+		validationFailed(w, []FieldError{{Field: "points", Message: "must be at least 1"}})
+		return
+	}
+
+	team, err := h.db.GetTeam(uint(teamID))
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	if team == nil {
+		notFound(w, "Team not found")
+		return
+	}
+
+	season := h.currentSeason(w)
+	if season == nil {
+		return
+	}
+	teams, err := h.db.GetSeasonTeams(season.ID)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	found := false
+	for _, team := range teams {
+		if team.ID == uint(teamID) {
+			found = true
+			break
+		}
+	}
+	if !found {
+		conflict(w, "Team has no fixtures in the current season")
+		return
+	}
+	unlock := h.locks.lock(season.ID)
+	defer unlock()
+
+	event := models.NewDeductionEvent(season.ID, uint(teamID), deduction.Points, deduction.Reason, requestActor(r))
+	err = h.db.AppendEvent(event)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(event)
+}
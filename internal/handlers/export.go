@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/cahitcaginkaratas/backend_insider/internal/export"
+	"github.com/cahitcaginkaratas/backend_insider/internal/models"
+)
+
+// ExportData streams the table, matches or events (?what=) of every season,
+// or of one season with ?season=, as csv, json or xlsx (?format=)
+func (h *APIHandler) ExportData(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	format := query.Get("format")
+	if format == "" {
+		format = "csv"
+	}
+	if !slices.Contains(export.Formats, format) {
+		badRequest(w, "Invalid format, use csv, json or xlsx")
+		return
+	}
+	what := query.Get("what")
+	if what == "" {
+		what = "matches"
+	}
+	if _, ok := export.Datasets[what]; !ok {
+		badRequest(w, "Invalid dataset, use table, matches or events")
+		return
+	}
+
+	var seasons []models.Season
+	if value := query.Get("season"); value != "" {
+		seasonID, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			badRequest(w, "Invalid season ID")
+			return
+		}
+		season, err := h.db.GetSeason(uint(seasonID))
+		if err != nil {
+			internalError(w, err)
+			return
+		}
+		if season == nil {
+			notFound(w, "Season not found")
+			return
+		}
+		seasons = []models.Season{*season}
+	} else {
+		var err error
+		seasons, err = h.db.GetSeasons()
+		if err != nil {
+			internalError(w, err)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", export.ContentType(format))
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="league-%s.%s"`, what, format))
+
+	// The response has already started, a failure can only cut it short
+	err := export.Export(h.db, w, what, format, seasons)
+	if err != nil {
+		log.Printf("Export of %s as %s failed: %v", what, format, err)
+	}
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/cahitcaginkaratas/backend_insider/internal/importer"
+	"github.com/cahitcaginkaratas/backend_insider/internal/models"
+)
+
+// maxImportBytes caps the size of import files. A season of
+// football-data.co.uk results is around 100 KB, so this leaves room for many
+// seasons in one file while a stray upload cannot fill the memory.
+const maxImportBytes = 16 << 20
+
+// writeImportErrors reports the problems of an import file line by line
+func writeImportErrors(w http.ResponseWriter, errs importer.Errors) {
+	details := make([]FieldError, len(errs))
+	for i, err := range errs {
+		details[i] = FieldError{Line: err.Line, Message: err.Message}
+	}
+	writeError(w, http.StatusUnprocessableEntity, CodeValidationFailed, "The import file is invalid", details...)
+}
+
+// ImportData imports teams and matches from a posted CSV file
+// (Content-Type text/csv, football-data.co.uk layout) or JSON document into
+// the current season, the season given with ?season= or a new season named
+// with ?new_season=. Nothing is stored with ?dry_run=true.
+func (h *APIHandler) ImportData(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	dryRun := false
+	if value := query.Get("dry_run"); value != "" {
+		var err error
+		dryRun, err = strconv.ParseBool(value)
+		if err != nil {
+			badRequest(w, "Invalid dry_run")
+			return
+		}
+	}
+
+	newSeason := strings.TrimSpace(query.Get("new_season"))
+	if newSeason != "" && query.Get("season") != "" {
+		badRequest(w, "Use either season or new_season")
+		return
+	}
+	var season *models.Season
+	if newSeason == "" {
+		season = h.seasonFromQuery(w, r)
+		if season == nil {
+			return
+		}
+	}
+
+	var data *importer.Data
+	var err error
+	body := http.MaxBytesReader(w, r.Body, maxImportBytes)
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "text/csv") {
+		data, err = importer.ReadCSV(body)
+	} else {
+		data, err = importer.ReadJSON(body)
+	}
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		writeError(w, http.StatusRequestEntityTooLarge, CodePayloadTooLarge, fmt.Sprintf("Import file is larger than %d bytes", maxImportBytes))
+		return
+	}
+	var errs importer.Errors
+	if errors.As(err, &errs) {
+		writeImportErrors(w, errs)
+		return
+	}
+	if err != nil {
+		badRequest(w, err.Error())
+		return
+	}
+
+	var summary *importer.Summary
+	if season == nil {
+		summary, err = importer.ImportNewSeason(h.db, newSeason, data, requestActor(r), dryRun)
+	} else {
+		unlock := h.locks.lock(season.ID)
+		defer unlock()
+		summary, err = importer.Import(h.db, season.ID, data, requestActor(r), dryRun)
+	}
+	if err != nil {
+		writeUpdateError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(summary)
+}
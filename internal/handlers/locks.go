@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+
+	"github.com/cahitcaginkaratas/backend_insider/internal/database"
+)
+
+// seasonLocks serializes the mutating operations of each season
+type seasonLocks struct {
+	mu    sync.Mutex
+	locks map[uint]*sync.Mutex
+}
+
+// newSeasonLocks creates an empty set of season locks
+func newSeasonLocks() *seasonLocks {
+	return &seasonLocks{locks: make(map[uint]*sync.Mutex)}
+}
+
+// lock blocks until the season is free and returns the function that releases it
+func (l *seasonLocks) lock(seasonID uint) func() {
+	l.mu.Lock()
+	seasonLock, ok := l.locks[seasonID]
+	if !ok {
+		seasonLock = &sync.Mutex{}
+		l.locks[seasonID] = seasonLock
+	}
+	l.mu.Unlock()
+
+	seasonLock.Lock()
+	return seasonLock.Unlock
+}
+
+// writeUpdateError reports a failed update, with 404 Not Found when the match
+// was deleted and 409 Conflict when it was changed concurrently
+func writeUpdateError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errMatchNotFound) {
+		notFound(w, "Match not found")
+		return
+	}
+	if errors.Is(err, database.ErrVersionConflict) {
+		conflict(w, "Match was changed by another request, reload it and try again")
+		return
+	}
+	internalError(w, err)
+}
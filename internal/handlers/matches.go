@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/cahitcaginkaratas/backend_insider/internal/database"
+	"github.com/cahitcaginkaratas/backend_insider/internal/models"
+)
+
+// Page sizes of GET /api/matches
+const (
+	DefaultMatchLimit = 100
+	MaxMatchLimit     = 1000
+)
+
+// matchCursor is the opaque ?cursor= of the next page, it remembers the
+// sort order it belongs to
+type matchCursor struct {
+	Sort string    `json:"sort"`
+	Week int       `json:"week"`
+	Date time.Time `json:"date"`
+	ID   uint      `json:"id"`
+}
+
+// encodeMatchCursor returns the cursor for the page after a match
+func encodeMatchCursor(sort string, match *models.Match) string {
+	data, _ := json.Marshal(matchCursor{Sort: sort, Week: match.Week, Date: match.Date, ID: match.ID})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeMatchCursor reads a cursor made for the same sort order, the
+// returned message explains an invalid cursor
+func decodeMatchCursor(value, sort string) (*database.MatchCursor, string) {
+	var cursor matchCursor
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err == nil {
+		err = json.Unmarshal(data, &cursor)
+	}
+	if err != nil || cursor.ID == 0 {
+		return nil, "Invalid cursor"
+	}
+	if cursor.Sort != sort {
+		return nil, "The cursor belongs to another sort order"
+	}
+	return &database.MatchCursor{Week: cursor.Week, Date: cursor.Date, ID: cursor.ID}, ""
+}
+
+// parseDateParam parses a date (2006-01-02) or a time (RFC 3339). A date
+// ends at the end of the day when end is set.
+func parseDateParam(value string, end bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		if end {
+			t = t.Add(time.Nanosecond)
+		}
+		return t, nil
+	}
+	t, err := time.Parse(time.DateOnly, value)
+	if err == nil && end {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, err
+}
+
+// matchQueryParams reads the filters, sort order and page of GET
+// /api/matches, the returned message explains an invalid parameter
+func matchQueryParams(query url.Values) (database.MatchQuery, string) {
+	q := database.MatchQuery{Sort: query.Get("sort"), Venue: query.Get("venue"), Limit: DefaultMatchLimit}
+	if _, _, err := database.ParseMatchSort(q.Sort); err != nil {
+		return q, "Invalid sort, use week, date or id with an optional leading -"
+	}
+
+	ids := map[string]*uint{"season": &q.SeasonID, "team": &q.TeamID}
+	for _, param := range []string{"season", "team"} {
+		if value := query.Get(param); value != "" {
+			id, err := strconv.ParseUint(value, 10, 32)
+			if err != nil || id == 0 {
+				return q, fmt.Sprintf("Invalid %s ID", param)
+			}
+			*ids[param] = uint(id)
+		}
+	}
+	if q.Venue != "" && q.Venue != database.VenueHome && q.Venue != database.VenueAway {
+		return q, "Invalid venue, use home or away"
+	}
+	if q.Venue != "" && q.TeamID == 0 {
+		return q, "The venue filter needs a team"
+	}
+
+	weeks := map[string]*int{"from_week": &q.FromWeek, "to_week": &q.ToWeek}
+	for _, param := range []string{"from_week", "to_week"} {
+		if value := query.Get(param); value != "" {
+			week, err := strconv.Atoi(value)
+			if err != nil || week < 1 {
+				return q, "Invalid " + param
+			}
+			*weeks[param] = week
+		}
+	}
+	if q.ToWeek != 0 && q.FromWeek > q.ToWeek {
+		return q, "from_week is after to_week"
+	}
+
+	if value := query.Get("played"); value != "" {
+		played, err := strconv.ParseBool(value)
+		if err != nil {
+			return q, "Invalid played flag"
+		}
+		q.Played = &played
+	}
+
+	var err error
+	if value := query.Get("from"); value != "" {
+		q.From, err = parseDateParam(value, false)
+		if err != nil {
+			return q, "Invalid from date, use 2006-01-02 or RFC 3339"
+		}
+	}
+	if value := query.Get("to"); value != "" {
+		q.Until, err = parseDateParam(value, true)
+		if err != nil {
+			return q, "Invalid to date, use 2006-01-02 or RFC 3339"
+		}
+	}
+	if !q.From.IsZero() && !q.Until.IsZero() && !q.From.Before(q.Until) {
+		return q, "The from date is after the to date"
+	}
+
+	if value := query.Get("limit"); value != "" {
+		q.Limit, err = strconv.Atoi(value)
+		if err != nil || q.Limit < 1 || q.Limit > MaxMatchLimit {
+			return q, fmt.Sprintf("Invalid limit, use 1 to %d", MaxMatchLimit)
+		}
+	}
+	if value := query.Get("cursor"); value != "" {
+		var message string
+		q.After, message = decodeMatchCursor(value, q.Sort)
+		if message != "" {
+			return q, message
+		}
+	}
+	return q, ""
+}
+
+// setPageLinks sets the Link header with the first page and, when there are
+// more matches, the next page of a request
+func setPageLinks(w http.ResponseWriter, r *http.Request, next string) {
+	link := func(cursor, rel string) string {
+		query := r.URL.Query()
+		query.Del("cursor")
+		if cursor != "" {
+			query.Set("cursor", cursor)
+		}
+		page := url.URL{Path: r.URL.Path, RawQuery: query.Encode()}
+		return fmt.Sprintf("<%s>; rel=%q", page.String(), rel)
+	}
+
+	w.Header().Add("Link", link("", "first"))
+	if next != "" {
+		w.Header().Add("Link", link(next, "next"))
+	}
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/cahitcaginkaratas/backend_insider/internal/models"
+	"github.com/gorilla/mux"
+)
+
+// GetMatchPrediction returns the pre-match prediction for a single match
+func (h *APIHandler) GetMatchPrediction(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	matchID, err := strconv.ParseUint(vars["id"], 10, 32)
+	if err != nil {
+		badRequest(w, "Invalid match ID")
+		return
+	}
+
+	match, err := h.db.GetMatch(uint(matchID))
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	if match == nil {
+		notFound(w, "Match not found")
+		return
+	}
+
+	json.NewEncoder(w).Encode(match.Predict(&match.HomeTeam, &match.AwayTeam))
+}
+
+// GetWeekPredictions returns the pre-match predictions for every match of a
+// week of the current season or the season given with ?season=
+func (h *APIHandler) GetWeekPredictions(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	week, err := strconv.Atoi(vars["week"])
+	if err != nil {
+		badRequest(w, "Invalid week number")
+		return
+	}
+
+	season := h.seasonFromQuery(w, r)
+	if season == nil {
+		return
+	}
+	matches, err := h.db.GetSeasonWeek(season.ID, week)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	if len(matches) == 0 {
+		notFound(w, "Week not found")
+		return
+	}
+
+	predictions := make([]*models.Prediction, 0, len(matches))
+	for i := range matches {
+		predictions = append(predictions, matches[i].Predict(&matches[i].HomeTeam, &matches[i].AwayTeam))
+	}
+
+	json.NewEncoder(w).Encode(predictions)
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/cahitcaginkaratas/backend_insider/internal/models"
+	"github.com/cahitcaginkaratas/backend_insider/internal/ratings"
+)
+
+// fitResponse is a rating fit with the teams of a CSV file that are not in
+// the league
+type fitResponse struct {
+	*ratings.Result
+	Unmatched []string `json:"unmatched,omitempty"`
+}
+
+// FitRatings fits Dixon-Coles ratings and stores them on the teams.
+// The played matches in the database are used unless a CSV file in the
+// football-data.co.uk layout is posted with Content-Type text/csv.
+func (h *APIHandler) FitRatings(w http.ResponseWriter, r *http.Request) {
+	opts := ratings.DefaultOptions()
+	if xi := r.URL.Query().Get("xi"); xi != "" {
+		value, err := strconv.ParseFloat(xi, 64)
+		if err != nil || value < 0 {
+			badRequest(w, "Invalid xi")
+			return
+		}
+		opts.Xi = value
+	}
+
+	var observations []ratings.Observation
+	source := "database"
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "text/csv") {
+		var err error
+		observations, err = ratings.ReadCSV(r.Body)
+		if err != nil {
+			badRequest(w, err.Error())
+			return
+		}
+		source = "csv"
+	} else {
+		matches, err := h.db.GetMatches()
+		if err != nil {
+			internalError(w, err)
+			return
+		}
+		observations = ratings.FromMatches(matches)
+	}
+
+	result, err := ratings.Fit(observations, opts)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, CodeValidationFailed, err.Error())
+		return
+	}
+	result.Fit.Source = source
+
+	unmatched, err := ratings.Store(h.db, result)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	models.SetEngine(result.Engine())
+
+	response := fitResponse{
+		Result:    result,
+		Unmatched: unmatched,
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
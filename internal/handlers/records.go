@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/cahitcaginkaratas/backend_insider/internal/models"
+)
+
+// GetRecords returns season or all-time records, optionally for one team.
+// Points deductions of every season count in the points by week.
+func (h *APIHandler) GetRecords(w http.ResponseWriter, r *http.Request) {
+	var filter models.RecordFilter
+	for param, target := range map[string]*uint{"team": &filter.TeamID, "season": &filter.SeasonID} {
+		value := r.URL.Query().Get(param)
+		if value == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			badRequest(w, "Invalid "+param+" ID")
+			return
+		}
+		*target = uint(id)
+	}
+
+	teams, err := h.db.GetTeams()
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	matches, err := h.db.GetMatches()
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+
+	seasons, err := h.db.GetSeasons()
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	events := make(map[uint][]models.LeagueEvent, len(seasons))
+	for _, season := range seasons {
+		if filter.SeasonID != 0 && season.ID != filter.SeasonID {
+			continue
+		}
+		events[season.ID], err = h.db.GetEvents(season.ID)
+		if err != nil {
+			internalError(w, err)
+			return
+		}
+	}
+
+	json.NewEncoder(w).Encode(models.NewRecords(teams, matches, events, filter))
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/cahitcaginkaratas/backend_insider/internal/models"
+)
+
+// GetCalibrationReport scores the recorded pre-match predictions against the
+// actual results, grouped by simulation engine, across every season or the
+// season given with ?season=. Only entered and imported results are scored
+// unless ?source= asks for another source or all of them.
+func (h *APIHandler) GetCalibrationReport(w http.ResponseWriter, r *http.Request) {
+	buckets := 10
+	if value := r.URL.Query().Get("buckets"); value != "" {
+		var err error
+		buckets, err = strconv.Atoi(value)
+		if err != nil || buckets < 1 || buckets > 100 {
+			badRequest(w, "Invalid number of buckets")
+			return
+		}
+	}
+
+	source := r.URL.Query().Get("source")
+	if source != "" && source != "all" && !slices.Contains(models.PredictionSources, source) {
+		badRequest(w, "Invalid source, use simulation, manual, import or all")
+		return
+	}
+
+	var seasonID uint
+	if r.URL.Query().Get("season") != "" {
+		season := h.seasonFromQuery(w, r)
+		if season == nil {
+			return
+		}
+		seasonID = season.ID
+	}
+
+	records, err := h.db.GetPredictionRecords(seasonID)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+
+	records = models.FilterPredictionSource(records, source)
+	json.NewEncoder(w).Encode(models.NewCalibrationReport(records, buckets))
+}
@@ -0,0 +1,434 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cahitcaginkaratas/backend_insider/internal/database"
+	"github.com/cahitcaginkaratas/backend_insider/internal/export"
+	"github.com/cahitcaginkaratas/backend_insider/internal/importer"
+	"github.com/cahitcaginkaratas/backend_insider/internal/models"
+	"github.com/cahitcaginkaratas/backend_insider/internal/openapi"
+	"github.com/gorilla/mux"
+)
+
+// Route is an API endpoint together with its description in the OpenAPI
+// specification. Path parameters are described from the path.
+type Route struct {
+	Method  string
+	Path    string
+	Handler http.HandlerFunc
+	Summary string
+	Params  []openapi.Parameter // path parameters described differently than in pathParams
+	Query   []openapi.Parameter
+
+	Body  interface{} // JSON request body, validated before the handler runs
+	Files []string    // media types of an uploaded file the handler reads itself
+
+	Response interface{}       // JSON response body, nil without one
+	Status   int               // status of a success, http.StatusOK if 0
+	Headers  map[string]string // response headers and their description
+	Download bool              // the response is an export file
+}
+
+// Query parameters shared by several routes
+var (
+	seasonQuery     = openapi.Query("season", "Season ID, the current season if not given", openapi.Integer().Min(1))
+	resimulateQuery = openapi.Query("resimulate", "Simulate played matches again", openapi.Boolean())
+	resetModeQuery  = openapi.Query("mode", "Clear the results (default) or replace the fixtures", openapi.String("results", "fixtures"))
+)
+
+// Routes lists every API endpoint
+func (h *APIHandler) Routes() []Route {
+	datasets := make([]string, 0, len(export.Datasets))
+	for dataset := range export.Datasets {
+		datasets = append(datasets, dataset)
+	}
+	sort.Strings(datasets)
+
+	return []Route{
+		{Method: "GET", Path: "/api/openapi.json", Handler: h.GetOpenAPI, Summary: "OpenAPI specification of the API",
+			Response: map[string]interface{}{}},
+
+		{Method: "GET", Path: "/api/teams", Handler: h.GetTeams, Summary: "All teams",
+			Response: []models.Team{}},
+		{Method: "GET", Path: "/api/teams/{id}", Handler: h.GetTeam, Summary: "A team with its fixtures and table row in a season",
+			Query: []openapi.Parameter{seasonQuery}, Response: teamResponse{}},
+		{Method: "GET", Path: "/api/teams/{id}/positions", Handler: h.GetTeamPositions, Summary: "Position, points and goal difference of a team after every week",
+			Query: []openapi.Parameter{seasonQuery}, Response: []models.WeekPosition{}},
+		{Method: "GET", Path: "/api/teams/{a}/vs/{b}", Handler: h.GetHeadToHead, Summary: "Head-to-head record of two teams across all seasons and the prediction for their next meeting",
+			Query: []openapi.Parameter{
+				openapi.Query("season", "Season ID, every season if not given", openapi.Integer().Min(1)),
+			},
+			Response: models.HeadToHead{}},
+		{Method: "POST", Path: "/api/teams/{id}/deductions", Handler: h.DeductPoints, Summary: "Deduct points from a team in the current season",
+			Body: deductionRequest{}, Response: models.LeagueEvent{}, Status: http.StatusCreated},
+
+		{Method: "GET", Path: "/api/seasons", Handler: h.GetSeasons, Summary: "All seasons",
+			Response: []models.Season{}},
+		{Method: "POST", Path: "/api/seasons", Handler: h.CreateSeason, Summary: "Start a new season, optionally with fixtures of every team",
+			Body: seasonRequest{}, Response: models.Season{}, Status: http.StatusCreated},
+		{Method: "POST", Path: "/api/seasons/{id}/rewind/{week}", Handler: h.RewindSeason, Summary: "Restore every match of a season after a week using the audit log",
+			Params:   []openapi.Parameter{openapi.Path("week", "Last week to keep, 0 restores the whole season", openapi.Integer().Min(0))},
+			Response: []models.Match{}},
+		{Method: "POST", Path: "/api/seasons/{id}/reset", Handler: h.ResetSeason, Summary: "Clear the results or replace the fixtures of a season",
+			Query: []openapi.Parameter{resetModeQuery}, Response: resetResponse{}},
+		{Method: "GET", Path: "/api/seasons/{id}/events", Handler: h.GetSeasonEvents, Summary: "Event stream of a season",
+			Response: []models.LeagueEvent{}},
+		{Method: "GET", Path: "/api/seasons/{id}/replay", Handler: h.ReplaySeason, Summary: "Matches and table rebuilt from the event stream of a season",
+			Query: []openapi.Parameter{
+				openapi.Query("until", "Last event to replay", openapi.Integer().Min(0)),
+				openapi.Query("at", "Replay the events up to this time", openapi.DateTime()),
+			},
+			Response: replayResponse{}},
+		{Method: "POST", Path: "/api/reset", Handler: h.ResetLeague, Summary: "Clear the results or replace the fixtures of the current season",
+			Query: []openapi.Parameter{resetModeQuery}, Response: resetResponse{}},
+
+		{Method: "GET", Path: "/api/matches", Handler: h.GetMatches, Summary: "A page of matches, filtered and sorted",
+			Query: []openapi.Parameter{
+				openapi.Query("season", "Season ID", openapi.Integer().Min(1)),
+				openapi.Query("team", "Team ID, matches the team plays home or away", openapi.Integer().Min(1)),
+				openapi.Query("venue", "Only the home or away matches of the team", openapi.String(database.VenueHome, database.VenueAway)),
+				openapi.Query("from_week", "First week", openapi.Integer().Min(1)),
+				openapi.Query("to_week", "Last week", openapi.Integer().Min(1)),
+				openapi.Query("played", "Played or unplayed matches", openapi.Boolean()),
+				openapi.Query("from", "First day (2006-01-02) or time (RFC 3339)", openapi.String()),
+				openapi.Query("to", "Last day (2006-01-02) or time (RFC 3339)", openapi.String()),
+				openapi.Query("sort", "Sort order, - sorts descending", openapi.String("week", "-week", "date", "-date", "id", "-id")),
+				openapi.Query("limit", "Page size", openapi.Integer().Min(1).Max(MaxMatchLimit)),
+				openapi.Query("cursor", "Cursor of the next page from the Link header", openapi.String()),
+			},
+			Response: []models.Match{}, Headers: map[string]string{"Link": "The first page and, when more matches follow, the next page"}},
+		{Method: "GET", Path: "/api/matches/{id}", Handler: h.GetMatch, Summary: "A single match",
+			Response: models.Match{}},
+		{Method: "PUT", Path: "/api/matches/{id}", Handler: h.UpdateMatchResult, Summary: "Enter the result of a match, send its version to detect conflicting updates",
+			Body: models.MatchResult{}, Response: models.Match{}},
+		{Method: "GET", Path: "/api/matches/{id}/audit", Handler: h.GetMatchAudits, Summary: "Every recorded change of a match result",
+			Response: []models.MatchAudit{}},
+		{Method: "POST", Path: "/api/matches/{id}/undo", Handler: h.UndoMatchResult, Summary: "Revert the latest change of a match result",
+			Response: models.Match{}},
+		{Method: "GET", Path: "/api/matches/{id}/prediction", Handler: h.GetMatchPrediction, Summary: "Pre-match prediction of a match",
+			Response: models.Prediction{}},
+		{Method: "GET", Path: "/api/matches/predictions/{week}", Handler: h.GetWeekPredictions, Summary: "Pre-match predictions of every match of a week",
+			Query: []openapi.Parameter{seasonQuery}, Response: []models.Prediction{}},
+		{Method: "POST", Path: "/api/matches/simulate/{week}", Handler: h.SimulateWeek, Summary: "Simulate the matches of a week of the current season",
+			Query: []openapi.Parameter{resimulateQuery}, Response: []models.Match{}},
+		{Method: "POST", Path: "/api/matches/simulate-all", Handler: h.SimulateAll, Summary: "Simulate every remaining match of the current season",
+			Query: []openapi.Parameter{resimulateQuery}, Response: []models.Match{}},
+
+		{Method: "GET", Path: "/api/weeks", Handler: h.GetWeeks, Summary: "Weeks of a season with how many of their matches are played",
+			Query: []openapi.Parameter{seasonQuery}, Response: []models.WeekSummary{}},
+		{Method: "GET", Path: "/api/weeks/{week}", Handler: h.GetWeek, Summary: "Completion status and matches of one week of a season",
+			Query: []openapi.Parameter{seasonQuery}, Response: weekResponse{}},
+
+		{Method: "GET", Path: "/api/league", Handler: h.GetLeagueStats, Summary: "League table with clinch and elimination status",
+			Query: []openapi.Parameter{
+				seasonQuery,
+				openapi.Query("top", "Number of top places, such as Champions League places", openapi.Integer().Min(0)),
+				openapi.Query("relegation", "Number of relegation places", openapi.Integer().Min(0)),
+			},
+			Response: []models.TeamStats{}},
+		{Method: "GET", Path: "/api/league/table", Handler: h.GetLeagueTable, Summary: "Overall, home, away or form table",
+			Query: []openapi.Parameter{
+				seasonQuery,
+				openapi.Query("view", "Table view", openapi.String(models.ViewOverall, models.ViewHome, models.ViewAway, models.ViewForm)),
+				openapi.Query("n", "Number of matches in the form table and form strings", openapi.Integer().Min(1)),
+				openapi.Query("week", "The table as it stood after this week", openapi.Integer().Min(0)),
+			},
+			Response: []models.TeamStats{}},
+		{Method: "GET", Path: "/api/records", Handler: h.GetRecords, Summary: "Season or all-time records",
+			Query: []openapi.Parameter{
+				openapi.Query("team", "Only records of this team", openapi.Integer().Min(1)),
+				openapi.Query("season", "Only records of this season", openapi.Integer().Min(1)),
+			},
+			Response: models.Records{}},
+
+		{Method: "POST", Path: "/api/import", Handler: h.ImportData, Summary: "Import teams and matches from a CSV or JSON file",
+			Query: []openapi.Parameter{
+				seasonQuery,
+				openapi.Query("new_season", "Name of a new season to import into instead", openapi.String()),
+				openapi.Query("dry_run", "Check the file without storing anything", openapi.Boolean()),
+			},
+			Files: []string{"text/csv", "application/json"}, Response: importer.Summary{}},
+		{Method: "GET", Path: "/api/export", Handler: h.ExportData, Summary: "Export the table, matches or events",
+			Query: []openapi.Parameter{
+				openapi.Query("what", "Dataset, matches if not given", openapi.String(datasets...)),
+				openapi.Query("format", "File format, csv if not given", openapi.String(export.Formats...)),
+				openapi.Query("season", "Season ID, every season if not given", openapi.Integer().Min(1)),
+			},
+			Download: true},
+
+		{Method: "POST", Path: "/api/admin/backup", Handler: h.CreateBackup, Summary: "Back up the database and rotate old backups",
+			Response: backupResponse{}, Status: http.StatusCreated},
+		{Method: "GET", Path: "/api/admin/backups", Handler: h.GetBackups, Summary: "Backups in the backup directory, oldest first",
+			Response: []database.Backup{}},
+
+		{Method: "POST", Path: "/api/ratings/fit", Handler: h.FitRatings, Summary: "Fit team ratings to the played matches or a posted CSV file",
+			Query: []openapi.Parameter{openapi.Query("xi", "Time decay of older matches per day", openapi.Number().Min(0))},
+			Files: []string{"text/csv"}, Response: fitResponse{}},
+		{Method: "GET", Path: "/api/reports/calibration", Handler: h.GetCalibrationReport, Summary: "Calibration of the pre-match predictions per engine",
+			Query: []openapi.Parameter{
+				openapi.Query("buckets", "Number of probability buckets", openapi.Integer().Min(1).Max(100)),
+				openapi.Query("season", "Season ID, every season if not given", openapi.Integer().Min(1)),
+				openapi.Query("source", "Only results of this source, entered and imported results if not given",
+					openapi.String("simulation", "manual", "import", "all")),
+			},
+			Response: []models.EngineReport{}},
+
+		{Method: "GET", Path: "/api/scenarios", Handler: h.GetScenarios, Summary: "All what-if scenarios",
+			Response: []models.Scenario{}},
+		{Method: "POST", Path: "/api/scenarios", Handler: h.CreateScenario, Summary: "Create a scenario of the current season with overridden results",
+			Body: scenarioRequest{}, Response: models.Scenario{}, Status: http.StatusCreated},
+		{Method: "GET", Path: "/api/scenarios/{id}", Handler: h.GetScenario, Summary: "Scenario table and Monte Carlo position probabilities",
+			Query: []openapi.Parameter{
+				openapi.Query("runs", "Number of simulated seasons", openapi.Integer().Min(1).Max(maxProjectionRuns)),
+				openapi.Query("seed", "Random seed for repeatable projections", openapi.Integer()),
+			},
+			Response: scenarioResponse{}},
+		{Method: "DELETE", Path: "/api/scenarios/{id}", Handler: h.DeleteScenario, Summary: "Delete a scenario",
+			Status: http.StatusNoContent},
+		{Method: "GET", Path: "/api/scenarios/{id}/diff", Handler: h.GetScenarioDiff, Summary: "Compare a scenario with the real results",
+			Response: models.ScenarioDiff{}},
+		{Method: "PUT", Path: "/api/scenarios/{id}/matches/{matchId}", Handler: h.SetScenarioResult, Summary: "Override the result of a match in a scenario",
+			Body: models.MatchResult{}, Response: models.ScenarioOverride{}},
+		{Method: "DELETE", Path: "/api/scenarios/{id}/matches/{matchId}", Handler: h.ClearScenarioResult, Summary: "Remove an overridden result from a scenario",
+			Status: http.StatusNoContent},
+	}
+}
+
+// pathParams describes the path variables other than {id}
+var pathParams = map[string]openapi.Parameter{
+	"a":       openapi.Path("a", "ID of a team", openapi.Integer().Min(1)),
+	"b":       openapi.Path("b", "ID of the other team", openapi.Integer().Min(1)),
+	"matchId": openapi.Path("matchId", "ID of the match", openapi.Integer().Min(1)),
+	"week":    openapi.Path("week", "Week number", openapi.Integer().Min(1)),
+}
+
+// NewSpec builds the OpenAPI specification of routes
+func NewSpec(routes []Route) *openapi.Document {
+	spec := openapi.New("Football League Simulation API", "1.0",
+		"Simulates a football league, see the README for the error envelope and the import and export formats.")
+	errorSchema := spec.SchemaOf(errorResponse{})
+
+	for _, route := range routes {
+		op := &openapi.Operation{
+			OperationID: handlerName(route.Handler),
+			Summary:     route.Summary,
+			Tags:        []string{routeTag(route.Path)},
+			Responses: map[string]openapi.Response{
+				"default": {Description: "Error", Content: map[string]openapi.MediaType{"application/json": {Schema: errorSchema}}},
+			},
+		}
+		op.Parameters = append(routePathParams(route.Path, route.Params), route.Query...)
+
+		if route.Body != nil || len(route.Files) > 0 {
+			op.RequestBody = &openapi.RequestBody{Required: route.Body != nil, Content: make(map[string]openapi.MediaType)}
+			if route.Body != nil {
+				op.RequestBody.Content["application/json"] = openapi.MediaType{Schema: spec.SchemaOf(route.Body)}
+			}
+			for _, mediaType := range route.Files {
+				schema := openapi.Binary()
+				if mediaType == "application/json" {
+					schema = &openapi.Schema{Type: "object"}
+				}
+				op.RequestBody.Content[mediaType] = openapi.MediaType{Schema: schema}
+			}
+		}
+
+		status := route.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		response := openapi.Response{Description: http.StatusText(status)}
+		switch {
+		case route.Download:
+			response.Content = make(map[string]openapi.MediaType)
+			for _, format := range export.Formats {
+				response.Content[export.ContentType(format)] = openapi.MediaType{Schema: openapi.Binary()}
+			}
+		case route.Response != nil:
+			response.Content = map[string]openapi.MediaType{"application/json": {Schema: spec.SchemaOf(route.Response)}}
+		}
+		for name, description := range route.Headers {
+			if response.Headers == nil {
+				response.Headers = make(map[string]openapi.Header)
+			}
+			response.Headers[name] = openapi.Header{Description: description, Schema: openapi.String()}
+		}
+		op.Responses[strconv.Itoa(status)] = response
+
+		spec.Add(route.Method, route.Path, op)
+	}
+	return spec
+}
+
+// routePathParams describes the variables of a path, {id} is the ID of the
+// resource named before it unless params describes it
+func routePathParams(path string, params []openapi.Parameter) []openapi.Parameter {
+	described := make(map[string]openapi.Parameter)
+	for _, param := range params {
+		described[param.Name] = param
+	}
+
+	var result []openapi.Parameter
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if !strings.HasPrefix(segment, "{") {
+			continue
+		}
+		name := strings.Trim(segment, "{}")
+		if param, ok := described[name]; ok {
+			result = append(result, param)
+			continue
+		}
+		if name != "id" {
+			result = append(result, pathParams[name])
+			continue
+		}
+		resource := segments[i-1]
+		if strings.HasSuffix(resource, "ches") {
+			resource = strings.TrimSuffix(resource, "es")
+		} else {
+			resource = strings.TrimSuffix(resource, "s")
+		}
+		result = append(result, openapi.Path("id", "ID of the "+resource, openapi.Integer().Min(1)))
+	}
+	return result
+}
+
+// routeTag groups a route by the first segment after /api
+func routeTag(path string) string {
+	segment := strings.Split(strings.TrimPrefix(path, "/api/"), "/")[0]
+	return strings.TrimSuffix(segment, ".json")
+}
+
+// handlerName returns the method name of a handler such as GetTeams
+func handlerName(handler http.HandlerFunc) string {
+	name := runtime.FuncForPC(reflect.ValueOf(handler).Pointer()).Name()
+	name = name[strings.LastIndex(name, ".")+1:]
+	return strings.TrimSuffix(name, "-fm")
+}
+
+// NewRouter registers every route of the handler together with the error
+// handlers and the validation of requests against the specification
+func NewRouter(h *APIHandler) *mux.Router {
+	routes := h.Routes()
+	h.spec = NewSpec(routes)
+
+	router := mux.NewRouter()
+	router.Use(corsMiddleware)
+	router.Use(jsonMiddleware)
+	router.Use(validateRequests(h.spec))
+	router.NotFoundHandler = http.HandlerFunc(NotFound)
+	router.MethodNotAllowedHandler = http.HandlerFunc(MethodNotAllowed)
+
+	for _, route := range routes {
+		router.HandleFunc(route.Path, route.Handler).Methods(route.Method)
+	}
+	return router
+}
+
+// Spec returns the OpenAPI specification built by NewRouter
+func (h *APIHandler) Spec() *openapi.Document {
+	return h.spec
+}
+
+// GetOpenAPI returns the OpenAPI specification of the API
+func (h *APIHandler) GetOpenAPI(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(h.spec)
+}
+
+// corsMiddleware lets the frontend call the API from another origin
+func corsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-Actor")
+
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// jsonMiddleware sets the JSON content type, handlers that stream other
+// formats override it
+func jsonMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		next.ServeHTTP(w, r)
+	})
+}
+
+// validateRequests rejects requests whose parameters or JSON body do not
+// match the specification before they reach the handler. Malformed and
+// oversized bodies are left to decodeJSON.
+func validateRequests(spec *openapi.Document) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var op *openapi.Operation
+			if route := mux.CurrentRoute(r); route != nil {
+				template, _ := route.GetPathTemplate()
+				op = spec.Operation(r.Method, template)
+			}
+			if op == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if problems := spec.ValidateParameters(op, mux.Vars(r), r.URL.Query()); len(problems) > 0 {
+				writeError(w, http.StatusBadRequest, CodeBadRequest, "Invalid path or query parameters", fieldErrors(problems)...)
+				return
+			}
+
+			// Only required bodies are checked, files uploaded in a media
+			// type the operation accepts are read by the handler itself
+			if op.RequestBody != nil && op.RequestBody.Required && !isUpload(op, r) {
+				body, ok := op.RequestBody.Content["application/json"]
+				if ok {
+					data, err := io.ReadAll(io.LimitReader(r.Body, maxBodyBytes+1))
+					r.Body = io.NopCloser(io.MultiReader(bytes.NewReader(data), r.Body))
+					if err == nil && len(data) <= maxBodyBytes {
+						if problems := spec.ValidateJSON(body.Schema, data); len(problems) > 0 {
+							validationFailed(w, fieldErrors(problems))
+							return
+						}
+					}
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// isUpload reports whether the request body is a file in a media type other
+// than JSON that the operation accepts
+func isUpload(op *openapi.Operation, r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType == "application/json" {
+		return false
+	}
+	_, ok := op.RequestBody.Content[mediaType]
+	return ok
+}
+
+// fieldErrors converts validation problems to error details
+func fieldErrors(problems []openapi.Problem) []FieldError {
+	details := make([]FieldError, len(problems))
+	for i, problem := range problems {
+		details[i] = FieldError{Field: problem.Field, Message: problem.Message}
+	}
+	return details
+}
@@ -0,0 +1,63 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cahitcaginkaratas/backend_insider/internal/handlers"
+)
+
+func TestRouterMatchesSpec(t *testing.T) {
+	h := handlers.NewAPIHandler(newTestDB(t))
+	routes := h.Routes()
+
+	if problems := handlers.NewSpec(routes).CheckRouter(handlers.NewRouter(h)); len(problems) > 0 {
+		t.Errorf("router and specification differ:\n%s", strings.Join(problems, "\n"))
+	}
+
+	// A route missing from either side is reported
+	router := handlers.NewRouter(h)
+	router.HandleFunc("/api/unlisted", h.GetTeams).Methods(http.MethodGet)
+	problems := handlers.NewSpec(routes[1:]).CheckRouter(router)
+	want := []string{
+		"GET /api/unlisted is registered but not in the specification",
+		routes[0].Method + " " + routes[0].Path + " is registered but not in the specification",
+	}
+	for _, problem := range want {
+		if !contains(problems, problem) {
+			t.Errorf("problems %q do not report %q", problems, problem)
+		}
+	}
+
+	extra := append([]handlers.Route{{Method: http.MethodGet, Path: "/api/unrouted", Handler: h.GetTeams}}, routes...)
+	problems = handlers.NewSpec(extra).CheckRouter(handlers.NewRouter(h))
+	if problem := "GET /api/unrouted is in the specification but not registered"; !contains(problems, problem) {
+		t.Errorf("problems %q do not report %q", problems, problem)
+	}
+}
+
+func TestValidateJSONBodyWithCSVContentType(t *testing.T) {
+	router := newTestRouter(t)
+
+	// A CSV content type does not let a JSON body skip the validation
+	req := httptest.NewRequest(http.MethodPost, "/api/teams/1/deductions", strings.NewReader(`{"reason": "test"}`))
+	req.Header.Set("Content-Type", "text/csv")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusUnprocessableEntity || !strings.Contains(rec.Body.String(), "is required") {
+		t.Errorf("got status %d %s, want 422 with a missing points field", rec.Code, rec.Body)
+	}
+}
+
+// contains reports whether a list has a string
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
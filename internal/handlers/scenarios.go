@@ -0,0 +1,318 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cahitcaginkaratas/backend_insider/internal/models"
+	"github.com/gorilla/mux"
+)
+
+// maxProjectionRuns caps the number of Monte Carlo seasons per request, the
+// projection runs while the client waits
+const maxProjectionRuns = 50000
+
+// scenarioRequest is the body used to create a scenario
+type scenarioRequest struct {
+	Name        string                    `json:"name" openapi:"required"`
+	Description string                    `json:"description"`
+	Overrides   []models.ScenarioOverride `json:"overrides"`
+}
+
+// scenarioResponse is a scenario with its resulting table and projection
+type scenarioResponse struct {
+	*models.Scenario
+	Table      []models.TeamStats `json:"table"`
+	Projection *models.Projection `json:"projection"`
+}
+
+// GetScenarios returns all scenarios
+func (h *APIHandler) GetScenarios(w http.ResponseWriter, r *http.Request) {
+	scenarios, err := h.db.GetScenarios()
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(scenarios)
+}
+
+// CreateScenario creates a scenario branched from the current season
+func (h *APIHandler) CreateScenario(w http.ResponseWriter, r *http.Request) {
+	var request scenarioRequest
+	if !decodeJSON(w, r, &request) {
+		return
+	}
+
+	season := h.currentSeason(w)
+	if season == nil {
+		return
+	}
+	matches, err := h.db.GetMatchesBySeason(season.ID)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+
+	// Validate the name and overrides
+	var details []FieldError
+	if strings.TrimSpace(request.Name) == "" {
+		details = append(details, FieldError{Field: "name", Message: "is required"})
+	}
+	for i, override := range request.Overrides {
+		prefix := fmt.Sprintf("overrides[%d].", i)
+		if !matchExists(override.MatchID, matches) {
+			details = append(details, FieldError{Field: prefix + "match_id", Message: fmt.Sprintf("match %d is not in the current season", override.MatchID)})
+		}
+		details = append(details, validateGoals(prefix, override.HomeGoals, override.AwayGoals)...)
+	}
+	if len(details) > 0 {
+		validationFailed(w, details)
+		return
+	}
+
+	// A later override of the same match wins
+	overrides := make(map[uint]models.ScenarioOverride)
+	var order []uint
+	for _, override := range request.Overrides {
+		if _, ok := overrides[override.MatchID]; !ok {
+			order = append(order, override.MatchID)
+		}
+		overrides[override.MatchID] = models.ScenarioOverride{
+			MatchID:   override.MatchID,
+			HomeGoals: override.HomeGoals,
+			AwayGoals: override.AwayGoals,
+		}
+	}
+
+	scenario := &models.Scenario{
+		SeasonID:    season.ID,
+		Name:        request.Name,
+		Description: request.Description,
+		Overrides:   make([]models.ScenarioOverride, 0, len(order)),
+	}
+	for _, matchID := range order {
+		scenario.Overrides = append(scenario.Overrides, overrides[matchID])
+	}
+
+	err = h.db.SaveScenario(scenario)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(scenario)
+}
+
+// GetScenario returns a scenario with its resulting table and Monte Carlo projection
+func (h *APIHandler) GetScenario(w http.ResponseWriter, r *http.Request) {
+	scenario, ok := h.loadScenario(w, r)
+	if !ok {
+		return
+	}
+
+	runs := models.DefaultProjectionRuns
+	if value := r.URL.Query().Get("runs"); value != "" {
+		var err error
+		runs, err = strconv.Atoi(value)
+		if err != nil || runs < 1 || runs > maxProjectionRuns {
+			badRequest(w, "Invalid number of runs")
+			return
+		}
+	}
+	seed := time.Now().UnixNano()
+	if value := r.URL.Query().Get("seed"); value != "" {
+		var err error
+		seed, err = strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			badRequest(w, "Invalid seed")
+			return
+		}
+	}
+
+	teams, err := h.db.GetSeasonTeams(scenario.SeasonID)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	matches, err := h.db.GetMatchesBySeason(scenario.SeasonID)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+
+	deductions, err := seasonDeductions(h.db, scenario.SeasonID)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+
+	scenarioMatches := scenario.Apply(matches)
+	table := models.NewTable(teams, scenarioMatches)
+	models.ApplyDeductions(table, deductions)
+
+	// Stop simulating once the client has gone away
+	projection, err := models.ProjectSeason(r.Context(), teams, scenarioMatches, deductions, models.CurrentEngine(), runs, rand.New(rand.NewSource(seed)))
+	if err != nil {
+		log.Printf("Projection of scenario %d stopped: %v", scenario.ID, err)
+		return
+	}
+	response := scenarioResponse{
+		Scenario:   scenario,
+		Table:      table,
+		Projection: projection,
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetScenarioDiff compares a scenario with the real results
+func (h *APIHandler) GetScenarioDiff(w http.ResponseWriter, r *http.Request) {
+	scenario, ok := h.loadScenario(w, r)
+	if !ok {
+		return
+	}
+
+	teams, err := h.db.GetSeasonTeams(scenario.SeasonID)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	matches, err := h.db.GetMatchesBySeason(scenario.SeasonID)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+
+	deductions, err := seasonDeductions(h.db, scenario.SeasonID)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(scenario.Diff(teams, matches, deductions))
+}
+
+// SetScenarioResult overrides the result of a match inside a scenario
+func (h *APIHandler) SetScenarioResult(w http.ResponseWriter, r *http.Request) {
+	scenario, ok := h.loadScenario(w, r)
+	if !ok {
+		return
+	}
+
+	matchID, err := strconv.ParseUint(mux.Vars(r)["matchId"], 10, 32)
+	if err != nil {
+		badRequest(w, "Invalid match ID")
+		return
+	}
+
+	var result models.MatchResult
+	if !decodeJSON(w, r, &result) {
+		return
+	}
+	if details := validateGoals("", result.HomeGoals, result.AwayGoals); len(details) > 0 {
+		validationFailed(w, details)
+		return
+	}
+
+	matches, err := h.db.GetMatchesBySeason(scenario.SeasonID)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+
+	override := models.ScenarioOverride{
+		ScenarioID: scenario.ID,
+		MatchID:    uint(matchID),
+		HomeGoals:  result.HomeGoals,
+		AwayGoals:  result.AwayGoals,
+	}
+	if !matchExists(override.MatchID, matches) {
+		notFound(w, "Match not found")
+		return
+	}
+
+	err = h.db.SaveScenarioOverride(&override)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(override)
+}
+
+// ClearScenarioResult removes the overridden result of a match from a scenario
+func (h *APIHandler) ClearScenarioResult(w http.ResponseWriter, r *http.Request) {
+	scenario, ok := h.loadScenario(w, r)
+	if !ok {
+		return
+	}
+
+	matchID, err := strconv.ParseUint(mux.Vars(r)["matchId"], 10, 32)
+	if err != nil {
+		badRequest(w, "Invalid match ID")
+		return
+	}
+
+	err = h.db.DeleteScenarioOverride(scenario.ID, uint(matchID))
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DeleteScenario deletes a scenario
+func (h *APIHandler) DeleteScenario(w http.ResponseWriter, r *http.Request) {
+	scenario, ok := h.loadScenario(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.db.DeleteScenario(scenario.ID)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// loadScenario loads the scenario named in the URL, writing an error response if it fails
+func (h *APIHandler) loadScenario(w http.ResponseWriter, r *http.Request) (*models.Scenario, bool) {
+	scenarioID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		badRequest(w, "Invalid scenario ID")
+		return nil, false
+	}
+
+	scenario, err := h.db.GetScenario(uint(scenarioID))
+	if err != nil {
+		internalError(w, err)
+		return nil, false
+	}
+	if scenario == nil {
+		notFound(w, "Scenario not found")
+		return nil, false
+	}
+
+	return scenario, true
+}
+
+// matchExists reports whether a match is among the matches
+func matchExists(matchID uint, matches []models.Match) bool {
+	for _, match := range matches {
+		if match.ID == matchID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/cahitcaginkaratas/backend_insider/internal/models"
+	"github.com/gorilla/mux"
+)
+
+// GetLeagueTable returns the overall, home, away or form table of the
+// current season or the season given with ?season=, optionally as it stood
+// after a week
+func (h *APIHandler) GetLeagueTable(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	view := query.Get("view")
+	if view == "" {
+		view = models.ViewOverall
+	}
+	n := models.DefaultFormMatches
+	if value := query.Get("n"); value != "" {
+		var err error
+		n, err = strconv.Atoi(value)
+		if err != nil || n < 1 {
+			badRequest(w, "Invalid number of form matches")
+			return
+		}
+	}
+
+	season := h.seasonFromQuery(w, r)
+	if season == nil {
+		return
+	}
+	teams, err := h.db.GetSeasonTeams(season.ID)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	matches, err := h.db.GetMatchesBySeason(season.ID)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+
+	events, err := h.db.GetEvents(season.ID)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	deductions := models.Deductions(events)
+
+	if value := query.Get("week"); value != "" {
+		week, err := strconv.Atoi(value)
+		if err != nil || week < 0 {
+			badRequest(w, "Invalid week number")
+			return
+		}
+		lastWeek := 0
+		for _, match := range matches {
+			lastWeek = max(lastWeek, match.Week)
+		}
+		if week > lastWeek {
+			notFound(w, "Week not found")
+			return
+		}
+
+		var played []models.Match
+		for _, match := range matches {
+			if match.Week <= week {
+				played = append(played, match)
+			}
+		}
+		matches = played
+		deductions = models.DeductionsAfterWeek(events, week)
+	}
+
+	table, err := models.NewTableView(teams, matches, view, n)
+	if err != nil {
+		badRequest(w, err.Error())
+		return
+	}
+	// Deductions come off a team's total, not its home, away or form points
+	if view == models.ViewOverall {
+		models.ApplyDeductions(table, deductions)
+	}
+
+	json.NewEncoder(w).Encode(table)
+}
+
+// GetTeamPositions returns a team's position, points and goal difference
+// after every week of the current season or the season given with ?season=
+func (h *APIHandler) GetTeamPositions(w http.ResponseWriter, r *http.Request) {
+	teamID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		badRequest(w, "Invalid team ID")
+		return
+	}
+
+	team, err := h.db.GetTeam(uint(teamID))
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	if team == nil {
+		notFound(w, "Team not found")
+		return
+	}
+
+	season := h.seasonFromQuery(w, r)
+	if season == nil {
+		return
+	}
+	teams, err := h.db.GetSeasonTeams(season.ID)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	matches, err := h.db.GetMatchesBySeason(season.ID)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+
+	events, err := h.db.GetEvents(season.ID)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(models.PositionHistory(teams, matches, events, uint(teamID)))
+}
+
+// GetHeadToHead returns every meeting between two teams across all seasons,
+// or in the season given with ?season=, with their aggregate record and the
+// prediction for their next scheduled meeting
+func (h *APIHandler) GetHeadToHead(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	teamAID, err := strconv.ParseUint(vars["a"], 10, 32)
+	if err != nil {
+		badRequest(w, "Invalid team ID")
+		return
+	}
+	teamBID, err := strconv.ParseUint(vars["b"], 10, 32)
+	if err != nil {
+		badRequest(w, "Invalid team ID")
+		return
+	}
+	if teamAID == teamBID {
+		badRequest(w, "A team cannot play against itself")
+		return
+	}
+
+	teams, err := h.db.GetTeams()
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+
+	var teamA, teamB *models.Team
+	for i := range teams {
+		switch teams[i].ID {
+		case uint(teamAID):
+			teamA = &teams[i]
+		case uint(teamBID):
+			teamB = &teams[i]
+		}
+	}
+	if teamA == nil || teamB == nil {
+		notFound(w, "Team not found")
+		return
+	}
+
+	var matches []models.Match
+	if r.URL.Query().Get("season") != "" {
+		season := h.seasonFromQuery(w, r)
+		if season == nil {
+			return
+		}
+		matches, err = h.db.GetMatchesBySeason(season.ID)
+	} else {
+		matches, err = h.db.GetMatches()
+	}
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(models.NewHeadToHead(teamA, teamB, matches))
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/cahitcaginkaratas/backend_insider/internal/models"
+	"github.com/gorilla/mux"
+)
+
+// weekResponse is the summary and the matches of one week of a season
+type weekResponse struct {
+	models.WeekSummary
+	SeasonID uint           `json:"season_id"`
+	Fixtures []models.Match `json:"fixtures"`
+}
+
+// GetWeeks lists the weeks of the current season, or the season given with
+// ?season=, with how many of their matches have been played
+func (h *APIHandler) GetWeeks(w http.ResponseWriter, r *http.Request) {
+	season := h.seasonFromQuery(w, r)
+	if season == nil {
+		return
+	}
+
+	weeks, err := h.db.GetWeeks(season.ID)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(weeks)
+}
+
+// GetWeek returns the summary and matches of one week of the current
+// season, or the season given with ?season=
+func (h *APIHandler) GetWeek(w http.ResponseWriter, r *http.Request) {
+	week, err := strconv.Atoi(mux.Vars(r)["week"])
+	if err != nil {
+		badRequest(w, "Invalid week number")
+		return
+	}
+
+	season := h.seasonFromQuery(w, r)
+	if season == nil {
+		return
+	}
+
+	matches, err := h.db.GetSeasonWeek(season.ID, week)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	if len(matches) == 0 {
+		notFound(w, "Week not found")
+		return
+	}
+
+	response := weekResponse{
+		WeekSummary: models.NewWeekSummary(week, matches),
+		SeasonID:    season.ID,
+		Fixtures:    matches,
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
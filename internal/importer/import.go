@@ -0,0 +1,212 @@
+package importer
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/cahitcaginkaratas/backend_insider/internal/database"
+	"github.com/cahitcaginkaratas/backend_insider/internal/models"
+)
+
+// DefaultStrength is the strength of teams created without one
+const DefaultStrength = 50
+
+// errDryRun rolls back the transaction of a dry run
+var errDryRun = errors.New("dry run")
+
+// Summary reports what an import changed, or would change in a dry run
+type Summary struct {
+	DryRun           bool     `json:"dry_run"`
+	SeasonID         uint     `json:"season_id"`
+	TeamsCreated     []string `json:"teams_created"`
+	TeamsUpdated     []string `json:"teams_updated"`
+	MatchesCreated   int      `json:"matches_created"`
+	MatchesUpdated   int      `json:"matches_updated"`
+	MatchesUnchanged int      `json:"matches_unchanged"`
+}
+
+// Import upserts the teams by name and adds the matches to a season in one
+// transaction. A match that is already stored with the same teams on the
+// same day gets the imported score, results go through the audit log and
+// event stream with source "import". A dry run rolls everything back and
+// only reports the changes.
+func Import(db database.Database, seasonID uint, data *Data, actor string, dryRun bool) (*Summary, error) {
+	return run(db, data, actor, dryRun, func(tx database.Database) (uint, error) {
+		return seasonID, nil
+	})
+}
+
+// ImportNewSeason creates a season and imports into it in the same
+// transaction, so a failed import or a dry run leaves no empty season
+// behind. The seasons that already exist are not touched.
+func ImportNewSeason(db database.Database, name string, data *Data, actor string, dryRun bool) (*Summary, error) {
+	return run(db, data, actor, dryRun, func(tx database.Database) (uint, error) {
+		season := models.NewSeason(name)
+		err := tx.SaveSeason(season)
+		return season.ID, err
+	})
+}
+
+// run imports in a transaction into the season returned by target
+func run(db database.Database, data *Data, actor string, dryRun bool, target func(tx database.Database) (uint, error)) (*Summary, error) {
+	var summary *Summary
+	err := db.WithTx(func(tx database.Database) error {
+		seasonID, err := target(tx)
+		if err != nil {
+			return err
+		}
+		summary = &Summary{
+			DryRun:       dryRun,
+			SeasonID:     seasonID,
+			TeamsCreated: []string{},
+			TeamsUpdated: []string{},
+		}
+		err = importData(tx, seasonID, data, actor, summary)
+		if err == nil && dryRun {
+			return errDryRun
+		}
+		return err
+	})
+	if errors.Is(err, errDryRun) {
+		err = nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
+// importData imports into a transaction
+func importData(tx database.Database, seasonID uint, data *Data, actor string, summary *Summary) error {
+	teams, err := tx.GetTeams()
+	if err != nil {
+		return err
+	}
+	byName := make(map[string]*models.Team, len(teams))
+	for i := range teams {
+		byName[strings.ToLower(teams[i].Name)] = &teams[i]
+	}
+
+	// upsert creates a missing team and updates the strength of an existing one
+	upsert := func(name string, strength int) (*models.Team, error) {
+		team, ok := byName[strings.ToLower(name)]
+		if !ok {
+			if strength == 0 {
+				strength = DefaultStrength
+			}
+			team = models.NewTeam(name, strength)
+			err := tx.SaveTeam(team)
+			if err != nil {
+				return nil, err
+			}
+			byName[strings.ToLower(name)] = team
+			summary.TeamsCreated = append(summary.TeamsCreated, name)
+			return team, nil
+		}
+		if strength != 0 && strength != team.Strength {
+			team.Strength = strength
+			err := tx.UpdateTeam(team)
+			if err != nil {
+				return nil, err
+			}
+			summary.TeamsUpdated = append(summary.TeamsUpdated, team.Name)
+		}
+		return team, nil
+	}
+
+	for _, row := range data.Teams {
+		_, err = upsert(row.Name, row.Strength)
+		if err != nil {
+			return err
+		}
+	}
+
+	existing, err := tx.GetMatchesBySeason(seasonID)
+	if err != nil {
+		return err
+	}
+	stored := make(map[string]*models.Match, len(existing))
+	for i := range existing {
+		match := &existing[i]
+		stored[matchKey(match.HomeTeam.Name, match.AwayTeam.Name, match.Date)] = match
+	}
+
+	weeks := numberWeeks(data.Matches)
+	for i, row := range data.Matches {
+		home, err := upsert(row.HomeTeam, 0)
+		if err != nil {
+			return err
+		}
+		away, err := upsert(row.AwayTeam, 0)
+		if err != nil {
+			return err
+		}
+
+		match, ok := stored[matchKey(home.Name, away.Name, row.Date)]
+		if !ok {
+			match = &models.Match{
+				SeasonID:   seasonID,
+				Week:       weeks[i],
+				HomeTeamID: home.ID,
+				AwayTeamID: away.ID,
+				Date:       row.Date,
+			}
+			err = tx.SaveMatch(match)
+			if err != nil {
+				return err
+			}
+			summary.MatchesCreated++
+		}
+
+		// A fixture without a score never clears a stored result
+		if !row.Played() || (match.Played && match.HomeGoals == *row.HomeGoals && match.AwayGoals == *row.AwayGoals) {
+			if ok {
+				summary.MatchesUnchanged++
+			}
+			continue
+		}
+		old := *match
+		prediction := match.Predict(home, away)
+		match.UpdateResult(*row.HomeGoals, *row.AwayGoals)
+		err = database.RecordMatchChange(tx, &old, match, "import", actor)
+		if err != nil {
+			return err
+		}
+		err = tx.SavePredictionRecord(models.NewPredictionRecord(prediction, match, "import"))
+		if err != nil {
+			return err
+		}
+		if ok {
+			summary.MatchesUpdated++
+		}
+	}
+	return nil
+}
+
+// numberWeeks returns the week of every match. Matches without a week are
+// numbered from the dates: a team's first match is in week 1, its second in
+// week 2 and so on, and a match is in the later week of its two teams.
+func numberWeeks(rows []MatchRow) []int {
+	order := make([]int, len(rows))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return rows[order[a]].Date.Before(rows[order[b]].Date)
+	})
+
+	weeks := make([]int, len(rows))
+	played := make(map[string]int)
+	for _, i := range order {
+		row := rows[i]
+		home, away := strings.ToLower(row.HomeTeam), strings.ToLower(row.AwayTeam)
+		week := max(played[home], played[away]) + 1
+		if row.Week > 0 {
+			week = row.Week
+		}
+		played[home], played[away] = week, week
+		weeks[i] = week
+	}
+	return weeks
+}
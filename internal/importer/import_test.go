@@ -0,0 +1,171 @@
+package importer_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/cahitcaginkaratas/backend_insider/internal/database"
+	"github.com/cahitcaginkaratas/backend_insider/internal/importer"
+	"github.com/cahitcaginkaratas/backend_insider/internal/models"
+)
+
+// newSeason returns an in-memory database with one empty season and a team
+func newSeason(t *testing.T) (database.Database, uint) {
+	t.Helper()
+	db := database.NewMemoryDB()
+	if err := db.InitDB(); err != nil {
+		t.Fatal(err)
+	}
+	season := models.NewSeason("Test")
+	if err := db.SaveSeason(season); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SaveTeam(models.NewTeam("Ash", 60)); err != nil {
+		t.Fatal(err)
+	}
+	return db, season.ID
+}
+
+// read parses a JSON import file
+func read(t *testing.T, input string) *importer.Data {
+	t.Helper()
+	data, err := importer.ReadJSON(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+const importFile = `{
+  "teams": [{"name": "ash", "strength": 75}, {"name": "Elm", "strength": 40}],
+  "matches": [
+    {"week": 1, "date": "2024-08-01", "home_team": "Ash", "away_team": "Elm", "home_goals": 2, "away_goals": 1},
+    {"week": 2, "date": "2024-08-08", "home_team": "Elm", "away_team": "Ash"}
+  ]
+}`
+
+func TestImportUpsertsTeams(t *testing.T) {
+	db, seasonID := newSeason(t)
+
+	summary, err := importer.Import(db, seasonID, read(t, importFile), "test", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(summary.TeamsCreated) != 1 || summary.TeamsCreated[0] != "Elm" ||
+		len(summary.TeamsUpdated) != 1 || summary.TeamsUpdated[0] != "Ash" || summary.MatchesCreated != 2 {
+		t.Errorf("got summary %+v, want Elm created, Ash updated and 2 matches", summary)
+	}
+
+	teams, err := db.GetTeams()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(teams) != 2 || teams[0].Name != "Ash" || teams[0].Strength != 75 {
+		t.Errorf("got teams %+v, want Ash matched by name with strength 75 and Elm", teams)
+	}
+	matches, err := db.GetMatchesBySeason(seasonID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	played := 0
+	for _, match := range matches {
+		if match.Played {
+			played++
+		}
+	}
+	if len(matches) != 2 || played != 1 {
+		t.Errorf("got %d matches with %d played, want 2 with 1 played", len(matches), played)
+	}
+
+	// The imported result is scored against the prediction made before it
+	records, err := db.GetPredictionRecords(seasonID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].Source != "import" || records[0].HomeGoals != 2 || records[0].AwayGoals != 1 {
+		t.Errorf("got prediction records %+v, want one of the imported 2-1", records)
+	}
+}
+
+func TestImportDryRunRollsBack(t *testing.T) {
+	db, seasonID := newSeason(t)
+
+	summary, err := importer.Import(db, seasonID, read(t, importFile), "test", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !summary.DryRun || summary.MatchesCreated != 2 || len(summary.TeamsCreated) != 1 {
+		t.Errorf("got summary %+v, want the changes of a real import", summary)
+	}
+
+	teams, err := db.GetTeams()
+	if err != nil {
+		t.Fatal(err)
+	}
+	matches, err := db.GetMatchesBySeason(seasonID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	events, err := db.GetEvents(seasonID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(teams) != 1 || teams[0].Strength != 60 || len(matches) != 0 || len(events) != 0 {
+		t.Errorf("dry run left %d teams (strength %d), %d matches and %d events", len(teams), teams[0].Strength, len(matches), len(events))
+	}
+
+	seasons, err := db.GetSeasons()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = importer.ImportNewSeason(db, "Dry", read(t, importFile), "test", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	after, err := db.GetSeasons()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(after) != len(seasons) {
+		t.Errorf("dry run into a new season left %d seasons, want %d", len(after), len(seasons))
+	}
+}
+
+func TestImportSameFileTwice(t *testing.T) {
+	db, seasonID := newSeason(t)
+
+	_, err := importer.Import(db, seasonID, read(t, importFile), "test", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	events, err := db.GetEvents(seasonID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	summary, err := importer.Import(db, seasonID, read(t, importFile), "test", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.MatchesCreated != 0 || summary.MatchesUpdated != 0 || summary.MatchesUnchanged != 2 ||
+		len(summary.TeamsCreated) != 0 || len(summary.TeamsUpdated) != 0 {
+		t.Errorf("got summary %+v, want nothing changed", summary)
+	}
+	again, err := db.GetEvents(seasonID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(again) != len(events) {
+		t.Errorf("importing the same file again added %d events", len(again)-len(events))
+	}
+
+	// A corrected score updates the stored match
+	corrected := strings.Replace(importFile, `"home_goals": 2`, `"home_goals": 3`, 1)
+	summary, err = importer.Import(db, seasonID, read(t, corrected), "test", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.MatchesUpdated != 1 || summary.MatchesUnchanged != 1 {
+		t.Errorf("got summary %+v, want one match updated", summary)
+	}
+}
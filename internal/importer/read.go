@@ -0,0 +1,389 @@
+// Package importer reads teams and results from CSV and JSON files and
+// imports them into a season
+package importer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Data is everything read from an import file
+type Data struct {
+	Teams   []TeamRow
+	Matches []MatchRow
+}
+
+// TeamRow is a team of an import file, a zero strength keeps the current
+// strength of an existing team
+type TeamRow struct {
+	Line     int
+	Name     string
+	Strength int
+}
+
+// MatchRow is a fixture or result of an import file. A match without goals
+// is a fixture that has not been played, a zero week is numbered from the
+// dates.
+type MatchRow struct {
+	Line      int
+	Week      int
+	Date      time.Time
+	HomeTeam  string
+	AwayTeam  string
+	HomeGoals *int
+	AwayGoals *int
+}
+
+// Played reports whether the row has a score
+func (m *MatchRow) Played() bool {
+	return m.HomeGoals != nil && m.AwayGoals != nil
+}
+
+// LineError is a problem with a single line of an import file
+type LineError struct {
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+func (e LineError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+}
+
+// Errors lists every problem found in an import file
+type Errors []LineError
+
+func (e Errors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+// sorted returns the problems in line order
+func (e Errors) sorted() Errors {
+	sort.SliceStable(e, func(i, j int) bool { return e[i].Line < e[j].Line })
+	return e
+}
+
+// add records a problem with a line
+func (e *Errors) add(line int, format string, args ...interface{}) {
+	*e = append(*e, LineError{Line: line, Message: fmt.Sprintf(format, args...)})
+}
+
+// dateLayouts are the date formats accepted in import files, the first ones
+// are used by football-data.co.uk
+var dateLayouts = []string{"02/01/2006", "02/01/06", "2006-01-02", time.RFC3339}
+
+// parseDate parses a date in any of the supported layouts
+func parseDate(value string) (time.Time, error) {
+	for _, layout := range dateLayouts {
+		date, err := time.Parse(layout, value)
+		if err == nil {
+			return date, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", value)
+}
+
+// ReadCSV reads fixtures and results in the football-data.co.uk column
+// layout. The Date, HomeTeam, AwayTeam and FTHG/FTAG (or HG/AG) columns are
+// used, and Week if there is one. Rows without a score are fixtures that
+// have not been played. Problems with single lines are returned together as
+// Errors.
+func ReadCSV(r io.Reader) (*Data, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.TrimPrefix(strings.TrimSpace(name), "\ufeff")] = i
+	}
+
+	column := func(names ...string) int {
+		for _, name := range names {
+			if i, ok := columns[name]; ok {
+				return i
+			}
+		}
+		return -1
+	}
+
+	dateCol := column("Date")
+	homeCol := column("HomeTeam", "Home")
+	awayCol := column("AwayTeam", "Away")
+	homeGoalsCol := column("FTHG", "HG")
+	awayGoalsCol := column("FTAG", "AG")
+	weekCol := column("Week", "Wk")
+	for i, col := range []int{dateCol, homeCol, awayCol, homeGoalsCol, awayGoalsCol} {
+		if col < 0 {
+			return nil, fmt.Errorf("missing column %s", []string{"Date", "HomeTeam", "AwayTeam", "FTHG", "FTAG"}[i])
+		}
+	}
+
+	data := &Data{}
+	var errs Errors
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				errs.add(parseErr.Line, "%v", parseErr.Err)
+				continue
+			}
+			return nil, err
+		}
+		// Only a record that was read has field positions
+		line, _ := reader.FieldPos(0)
+
+		field := func(i int) string {
+			if i >= 0 && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue
+		}
+
+		match := MatchRow{
+			Line:     line,
+			HomeTeam: field(homeCol),
+			AwayTeam: field(awayCol),
+		}
+		match.Date, err = parseDate(field(dateCol))
+		if err != nil {
+			errs.add(line, "%v", err)
+		}
+		if week := field(weekCol); week != "" {
+			match.Week, err = strconv.Atoi(week)
+			if err != nil {
+				errs.add(line, "invalid week %q", week)
+			}
+		}
+		match.HomeGoals = parseGoals(&errs, line, "home", field(homeGoalsCol))
+		match.AwayGoals = parseGoals(&errs, line, "away", field(awayGoalsCol))
+		data.Matches = append(data.Matches, match)
+	}
+
+	errs = append(errs, data.validate()...)
+	if len(errs) > 0 {
+		return nil, errs.sorted()
+	}
+	return data, nil
+}
+
+// parseGoals parses an optional number of goals
+func parseGoals(errs *Errors, line int, side, value string) *int {
+	if value == "" {
+		return nil
+	}
+	goals, err := strconv.Atoi(value)
+	if err != nil {
+		errs.add(line, "invalid %s goals %q", side, value)
+		return new(int)
+	}
+	return &goals
+}
+
+// jsonTeam is a team in a JSON import file
+type jsonTeam struct {
+	Name     string `json:"name"`
+	Strength int    `json:"strength"`
+}
+
+// jsonMatch is a fixture or result in a JSON import file
+type jsonMatch struct {
+	Week      int    `json:"week"`
+	Date      string `json:"date"`
+	HomeTeam  string `json:"home_team"`
+	AwayTeam  string `json:"away_team"`
+	HomeGoals *int   `json:"home_goals"`
+	AwayGoals *int   `json:"away_goals"`
+}
+
+// ReadJSON reads teams and matches from a JSON object with "teams"
+// ([{"name", "strength"}]) and "matches" ([{"week", "date", "home_team",
+// "away_team", "home_goals", "away_goals"}]). Problems are reported with the
+// line each team or match starts on.
+func ReadJSON(r io.Reader) (*Data, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(content))
+
+	// lineAt returns the line of the first value at or after an offset
+	lineAt := func(offset int64) int {
+		for offset < int64(len(content)) && strings.ContainsRune(" \t\r\n,", rune(content[offset])) {
+			offset++
+		}
+		return bytes.Count(content[:offset], []byte("\n")) + 1
+	}
+	syntaxError := func(err error) error {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			return Errors{{Line: lineAt(syntaxErr.Offset - 1), Message: syntaxErr.Error()}}
+		}
+		return err
+	}
+	expect := func(delim json.Delim) error {
+		token, err := decoder.Token()
+		if err != nil {
+			return syntaxError(err)
+		}
+		if token != delim {
+			return Errors{{Line: lineAt(decoder.InputOffset() - 1), Message: fmt.Sprintf("expected %q", delim)}}
+		}
+		return nil
+	}
+
+	// readArray decodes every element of an array with decode
+	var errs Errors
+	readArray := func(decode func(line int) error) error {
+		err := expect('[')
+		if err != nil {
+			return err
+		}
+		for decoder.More() {
+			line := lineAt(decoder.InputOffset())
+			err = decode(line)
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &typeErr) {
+				errs.add(line, "%s is a %s, want %s", typeErr.Field, typeErr.Value, typeErr.Type)
+				continue
+			}
+			if err != nil {
+				return syntaxError(err)
+			}
+		}
+		return expect(']')
+	}
+
+	data := &Data{}
+	err = expect('{')
+	if err != nil {
+		return nil, err
+	}
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, syntaxError(err)
+		}
+		switch token {
+		case "teams":
+			err = readArray(func(line int) error {
+				var team jsonTeam
+				err := decoder.Decode(&team)
+				data.Teams = append(data.Teams, TeamRow{Line: line, Name: strings.TrimSpace(team.Name), Strength: team.Strength})
+				return err
+			})
+		case "matches":
+			err = readArray(func(line int) error {
+				var match jsonMatch
+				err := decoder.Decode(&match)
+				row := MatchRow{
+					Line:      line,
+					Week:      match.Week,
+					HomeTeam:  strings.TrimSpace(match.HomeTeam),
+					AwayTeam:  strings.TrimSpace(match.AwayTeam),
+					HomeGoals: match.HomeGoals,
+					AwayGoals: match.AwayGoals,
+				}
+				if err == nil {
+					var dateErr error
+					row.Date, dateErr = parseDate(match.Date)
+					if dateErr != nil {
+						errs.add(line, "%v", dateErr)
+					}
+				}
+				data.Matches = append(data.Matches, row)
+				return err
+			})
+		default:
+			var skipped json.RawMessage
+			err = decoder.Decode(&skipped)
+		}
+		if err != nil {
+			return nil, syntaxError(err)
+		}
+	}
+	err = expect('}')
+	if err != nil {
+		return nil, err
+	}
+
+	errs = append(errs, data.validate()...)
+	if len(errs) > 0 {
+		return nil, errs.sorted()
+	}
+	return data, nil
+}
+
+// validate checks the teams and matches that were read
+func (d *Data) validate() Errors {
+	var errs Errors
+
+	teams := make(map[string]int)
+	for _, team := range d.Teams {
+		if team.Name == "" {
+			errs.add(team.Line, "missing team name")
+			continue
+		}
+		if line, ok := teams[strings.ToLower(team.Name)]; ok {
+			errs.add(team.Line, "team %q is already listed on line %d", team.Name, line)
+		}
+		teams[strings.ToLower(team.Name)] = team.Line
+		if team.Strength < 0 || team.Strength > 100 {
+			errs.add(team.Line, "team %q has strength %d, want 1 to 100", team.Name, team.Strength)
+		}
+	}
+
+	matches := make(map[string]int)
+	for _, match := range d.Matches {
+		if match.HomeTeam == "" || match.AwayTeam == "" {
+			errs.add(match.Line, "missing team name")
+		} else if strings.EqualFold(match.HomeTeam, match.AwayTeam) {
+			errs.add(match.Line, "%q cannot play itself", match.HomeTeam)
+		}
+		if match.Week < 0 {
+			errs.add(match.Line, "invalid week %d", match.Week)
+		}
+		if (match.HomeGoals == nil) != (match.AwayGoals == nil) {
+			errs.add(match.Line, "score needs both home and away goals")
+		}
+		if (match.HomeGoals != nil && *match.HomeGoals < 0) || (match.AwayGoals != nil && *match.AwayGoals < 0) {
+			errs.add(match.Line, "goals must not be negative")
+		}
+
+		key := matchKey(match.HomeTeam, match.AwayTeam, match.Date)
+		if line, ok := matches[key]; ok {
+			errs.add(match.Line, "match is already listed on line %d", line)
+		}
+		matches[key] = match.Line
+	}
+
+	return errs
+}
+
+// matchKey identifies a match by its teams and day
+func matchKey(homeTeam, awayTeam string, date time.Time) string {
+	return strings.ToLower(homeTeam) + "\x00" + strings.ToLower(awayTeam) + "\x00" + date.Format("2006-01-02")
+}
@@ -0,0 +1,139 @@
+package importer_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/cahitcaginkaratas/backend_insider/internal/importer"
+)
+
+// lineErrors returns the lines of the problems of a read, or fails the test
+// when the read did not fail with line errors
+func lineErrors(t *testing.T, err error) []int {
+	t.Helper()
+	var errs importer.Errors
+	if !errors.As(err, &errs) {
+		t.Fatalf("got %v, want line errors", err)
+	}
+	lines := make([]int, len(errs))
+	for i, e := range errs {
+		lines[i] = e.Line
+	}
+	return lines
+}
+
+func sameLines(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+const csvHeader = "Date,HomeTeam,AwayTeam,FTHG,FTAG\n"
+
+func TestReadCSV(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		matches int   // matches read when the file is valid
+		lines   []int // lines with problems
+	}{
+		{"results and fixtures", csvHeader + "01/08/2024,Ash,Elm,2,1\n08/08/2024,Elm,Ash,,\n", 2, nil},
+		{"bare quote", csvHeader + "01/08/2024,A\"sh,Elm,2,1\n08/08/2024,Elm,Ash,1,1\n", 0, []int{2}},
+		{"unterminated quote", csvHeader + "01/08/2024,Ash,Elm,2,1\n08/08/2024,\"Elm,Ash,1,1\n", 0, []int{3}},
+		// A broken first field leaves the reader without any field position
+		{"bare quote first", csvHeader + "0\"1/08/2024,Ash,Elm,2,1\n08/08/2024,Elm,Ash,1,1\n", 0, []int{2}},
+		{"unterminated quote first", csvHeader + "01/08/2024,Ash,Elm,2,1\n\"08/08/2024,Elm,Ash,1,1\n", 0, []int{3}},
+		{"duplicate row", csvHeader + "01/08/2024,Ash,Elm,2,1\n01/08/2024,Ash,Elm,2,1\n", 0, []int{3}},
+		{"negative score", csvHeader + "01/08/2024,Ash,Elm,-1,1\n", 0, []int{2}},
+		{"half a goal", csvHeader + "01/08/2024,Ash,Elm,1.5,1\n", 0, []int{2}},
+		{"one sided score", csvHeader + "01/08/2024,Ash,Elm,1,\n", 0, []int{2}},
+		{"every bad line", csvHeader + "32/08/2024,Ash,Elm,1,1\n01/08/2024,Ash,Ash,1,1\n02/08/2024,Elm,Ash,x,1\n", 0, []int{2, 3, 4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := importer.ReadCSV(strings.NewReader(tt.input))
+			if tt.lines == nil {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(data.Matches) != tt.matches {
+					t.Errorf("got %d matches, want %d", len(data.Matches), tt.matches)
+				}
+				return
+			}
+			if lines := lineErrors(t, err); !sameLines(lines, tt.lines) {
+				t.Errorf("got problems on lines %v, want %v: %v", lines, tt.lines, err)
+			}
+		})
+	}
+}
+
+func TestReadCSVMissingColumn(t *testing.T) {
+	_, err := importer.ReadCSV(strings.NewReader("Date,HomeTeam,AwayTeam,FTHG\n01/08/2024,Ash,Elm,2\n"))
+	if err == nil || !strings.Contains(err.Error(), "missing column FTAG") {
+		t.Errorf("got %v, want a missing FTAG column", err)
+	}
+}
+
+func TestReadJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		matches int
+		lines   []int
+	}{
+		{"teams and matches", `{
+  "teams": [{"name": "Ash", "strength": 70}, {"name": "Elm"}],
+  "matches": [
+    {"date": "2024-08-01", "home_team": "Ash", "away_team": "Elm", "home_goals": 2, "away_goals": 1},
+    {"date": "2024-08-08", "home_team": "Elm", "away_team": "Ash"}
+  ]
+}`, 2, nil},
+		{"type error", `{
+  "matches": [
+    {"date": "2024-08-01", "home_team": "Ash", "away_team": "Elm", "home_goals": "two", "away_goals": 1}
+  ]
+}`, 0, []int{3}},
+		{"half a goal", `{
+  "matches": [
+    {"date": "2024-08-01", "home_team": "Ash", "away_team": "Elm", "home_goals": 1.5, "away_goals": 1}
+  ]
+}`, 0, []int{3}},
+		{"duplicate team and match", `{
+  "teams": [{"name": "Ash"}, {"name": "ash"}],
+  "matches": [
+    {"date": "2024-08-01", "home_team": "Ash", "away_team": "Elm"},
+    {"date": "2024-08-01", "home_team": "Ash", "away_team": "Elm", "home_goals": -1, "away_goals": 0}
+  ]
+}`, 0, []int{2, 5, 5}},
+		{"syntax error", `{
+  "matches": [
+    {"date": "2024-08-01",, "home_team": "Ash"}
+  ]
+}`, 0, []int{3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := importer.ReadJSON(strings.NewReader(tt.input))
+			if tt.lines == nil {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(data.Matches) != tt.matches {
+					t.Errorf("got %d matches, want %d", len(data.Matches), tt.matches)
+				}
+				return
+			}
+			if lines := lineErrors(t, err); !sameLines(lines, tt.lines) {
+				t.Errorf("got problems on lines %v, want %v: %v", lines, tt.lines, err)
+			}
+		})
+	}
+}
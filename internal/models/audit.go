@@ -0,0 +1,101 @@
+package models
+
+import "time"
+
+// MatchAudit records a change to a match result with the state before and
+// after it, so changes can be traced and undone
+type MatchAudit struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	MatchID      uint      `json:"match_id" gorm:"index"`
+	SeasonID     uint      `json:"season_id" gorm:"index"`
+	Week         int       `json:"week"`
+	Source       string    `json:"source"` // "simulation", "manual", "import", "reset", "undo" or "rewind"
+	Actor        string    `json:"actor"`
+	OldHomeGoals int       `json:"old_home_goals"`
+	OldAwayGoals int       `json:"old_away_goals"`
+	OldPlayed    bool      `json:"old_played"`
+	NewHomeGoals int       `json:"new_home_goals"`
+	NewAwayGoals int       `json:"new_away_goals"`
+	NewPlayed    bool      `json:"new_played"`
+	Undone       bool      `json:"undone"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// NewMatchAudit records the change from an earlier state of a match to its current state
+func NewMatchAudit(old, match *Match, source, actor string) *MatchAudit {
+	return &MatchAudit{
+		MatchID:      match.ID,
+		SeasonID:     match.SeasonID,
+		Week:         match.Week,
+		Source:       source,
+		Actor:        actor,
+		OldHomeGoals: old.HomeGoals,
+		OldAwayGoals: old.AwayGoals,
+		OldPlayed:    old.Played,
+		NewHomeGoals: match.HomeGoals,
+		NewAwayGoals: match.AwayGoals,
+		NewPlayed:    match.Played,
+	}
+}
+
+// Undoable reports whether undo can revert the change. A result cleared by a
+// season reset can be brought back, undo and rewind entries themselves are
+// never reverted.
+func (a *MatchAudit) Undoable() bool {
+	return !a.Undone && a.Source != "undo" && a.Source != "rewind"
+}
+
+// Restore sets the match back to its state before the change
+func (a *MatchAudit) Restore(match *Match) {
+	match.HomeGoals = a.OldHomeGoals
+	match.AwayGoals = a.OldAwayGoals
+	match.Played = a.OldPlayed
+}
+
+// LastUndoable returns the latest change that can be undone, or nil. The
+// audits must be in the order they were recorded.
+func LastUndoable(audits []MatchAudit) *MatchAudit {
+	for i := len(audits) - 1; i >= 0; i-- {
+		if audits[i].Undoable() {
+			return &audits[i]
+		}
+	}
+	return nil
+}
+
+// Rewind restores every match played after the given week to its state
+// before the first recorded change, which is unplayed for a scheduled
+// fixture. Matches without any recorded change are reset to unplayed. It
+// returns the matches that changed, the earlier state of each, and the
+// audits that are undone by the rewind.
+func Rewind(matches []Match, audits []MatchAudit, week int) (changed, old []Match, undone []MatchAudit) {
+	byMatch := make(map[uint][]MatchAudit)
+	for _, audit := range audits {
+		byMatch[audit.MatchID] = append(byMatch[audit.MatchID], audit)
+	}
+
+	for _, match := range matches {
+		if match.Week <= week {
+			continue
+		}
+
+		restored := match
+		restored.HomeGoals, restored.AwayGoals, restored.Played = 0, 0, false
+		matchAudits := byMatch[match.ID]
+		if len(matchAudits) > 0 {
+			matchAudits[0].Restore(&restored)
+		}
+		for _, audit := range matchAudits {
+			if audit.Undoable() {
+				undone = append(undone, audit)
+			}
+		}
+
+		if restored.HomeGoals == match.HomeGoals && restored.AwayGoals == match.AwayGoals && restored.Played == match.Played {
+			continue
+		}
+		changed = append(changed, restored)
+		old = append(old, match)
+	}
+	return changed, old, undone
+}
@@ -0,0 +1,178 @@
+package models
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// PredictionRecord stores the prediction made just before a match result was
+// set together with that result, so predictions can be scored afterwards.
+// There is one record per match and engine, the latest result replaces it.
+type PredictionRecord struct {
+	ID                uint      `json:"id" gorm:"primaryKey"`
+	SeasonID          uint      `json:"season_id" gorm:"index"`
+	MatchID           uint      `json:"match_id" gorm:"uniqueIndex:idx_prediction_match_engine,priority:1"`
+	Week              int       `json:"week"`
+	Engine            string    `json:"engine" gorm:"uniqueIndex:idx_prediction_match_engine,priority:2"`
+	Source            string    `json:"source"` // "simulation", "manual" or "import"
+	HomeWin           float64   `json:"home_win"`
+	Draw              float64   `json:"draw"`
+	AwayWin           float64   `json:"away_win"`
+	ExpectedHomeGoals float64   `json:"expected_home_goals"`
+	ExpectedAwayGoals float64   `json:"expected_away_goals"`
+	HomeGoals         int       `json:"home_goals"`
+	AwayGoals         int       `json:"away_goals"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+// NewPredictionRecord pairs a prediction with the result of the match
+func NewPredictionRecord(prediction *Prediction, match *Match, source string) *PredictionRecord {
+	return &PredictionRecord{
+		SeasonID:          match.SeasonID,
+		MatchID:           match.ID,
+		Week:              match.Week,
+		Engine:            prediction.Engine,
+		Source:            source,
+		HomeWin:           prediction.HomeWin,
+		Draw:              prediction.Draw,
+		AwayWin:           prediction.AwayWin,
+		ExpectedHomeGoals: prediction.ExpectedHomeGoals,
+		ExpectedAwayGoals: prediction.ExpectedAwayGoals,
+		HomeGoals:         match.HomeGoals,
+		AwayGoals:         match.AwayGoals,
+	}
+}
+
+// PredictionSources are the sources a prediction record can have, the
+// calibration report takes "all" for every source
+var PredictionSources = []string{"simulation", "manual", "import"}
+
+// FilterPredictionSource keeps the records of a source. Simulated results are
+// drawn from the predictions themselves and say nothing about the model, so
+// an empty source keeps the records of every other source and "all" keeps
+// every record.
+func FilterPredictionSource(records []PredictionRecord, source string) []PredictionRecord {
+	if source == "all" {
+		return records
+	}
+	kept := make([]PredictionRecord, 0, len(records))
+	for _, record := range records {
+		if record.Source == source || (source == "" && record.Source != "simulation") {
+			kept = append(kept, record)
+		}
+	}
+	return kept
+}
+
+// CalibrationBucket is one point of a reliability diagram
+type CalibrationBucket struct {
+	Lower             float64 `json:"lower"`
+	Upper             float64 `json:"upper"`
+	Count             int     `json:"count"`
+	MeanPredicted     float64 `json:"mean_predicted"`
+	ObservedFrequency float64 `json:"observed_frequency"`
+}
+
+// EngineReport scores the predictions of a single engine
+type EngineReport struct {
+	Engine                 string              `json:"engine"`
+	Matches                int                 `json:"matches"`
+	BrierScore             float64             `json:"brier_score"`
+	LogLoss                float64             `json:"log_loss"`
+	RankedProbabilityScore float64             `json:"ranked_probability_score"`
+	Buckets                []CalibrationBucket `json:"buckets"`
+}
+
+// minProbability keeps the log loss finite when an impossible outcome happens
+const minProbability = 1e-15
+
+// NewCalibrationReport scores prediction records grouped by engine. Every
+// record adds its home, draw and away probability to the calibration buckets.
+func NewCalibrationReport(records []PredictionRecord, buckets int) []EngineReport {
+	if buckets <= 0 {
+		buckets = 10
+	}
+
+	reports := make(map[string]*EngineReport)
+	sums := make(map[string][]CalibrationBucket)
+	for _, record := range records {
+		report, ok := reports[record.Engine]
+		if !ok {
+			report = &EngineReport{Engine: record.Engine}
+			reports[record.Engine] = report
+			sums[record.Engine] = make([]CalibrationBucket, buckets)
+		}
+
+		// Outcomes in ranked order: home win, draw, away win
+		predicted := [3]float64{record.HomeWin, record.Draw, record.AwayWin}
+		var observed [3]float64
+		switch {
+		case record.HomeGoals > record.AwayGoals:
+			observed[0] = 1
+		case record.HomeGoals == record.AwayGoals:
+			observed[1] = 1
+		default:
+			observed[2] = 1
+		}
+
+		report.Matches++
+		cumulativePredicted, cumulativeObserved := 0.0, 0.0
+		for k := range predicted {
+			report.BrierScore += (predicted[k] - observed[k]) * (predicted[k] - observed[k])
+			if observed[k] == 1 {
+				report.LogLoss -= math.Log(math.Max(predicted[k], minProbability))
+			}
+			if k < len(predicted)-1 {
+				cumulativePredicted += predicted[k]
+				cumulativeObserved += observed[k]
+				diff := cumulativePredicted - cumulativeObserved
+				report.RankedProbabilityScore += diff * diff / float64(len(predicted)-1)
+			}
+
+			bucket := int(predicted[k] * float64(buckets))
+			if bucket >= buckets {
+				bucket = buckets - 1
+			}
+			if bucket < 0 {
+				bucket = 0
+			}
+			sums[record.Engine][bucket].Count++
+			sums[record.Engine][bucket].MeanPredicted += predicted[k]
+			sums[record.Engine][bucket].ObservedFrequency += observed[k]
+		}
+	}
+
+	result := make([]EngineReport, 0, len(reports))
+	for engine, report := range reports {
+		report.BrierScore /= float64(report.Matches)
+		report.LogLoss /= float64(report.Matches)
+		report.RankedProbabilityScore /= float64(report.Matches)
+
+		report.Buckets = make([]CalibrationBucket, buckets)
+		for i, sum := range sums[engine] {
+			bucket := CalibrationBucket{
+				Lower: float64(i) / float64(buckets),
+				Upper: float64(i+1) / float64(buckets),
+				Count: sum.Count,
+			}
+			if sum.Count > 0 {
+				bucket.MeanPredicted = sum.MeanPredicted / float64(sum.Count)
+				bucket.ObservedFrequency = sum.ObservedFrequency / float64(sum.Count)
+			}
+			report.Buckets[i] = bucket
+		}
+
+		result = append(result, *report)
+	}
+
+	// Best engine first
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].RankedProbabilityScore != result[j].RankedProbabilityScore {
+			return result[i].RankedProbabilityScore < result[j].RankedProbabilityScore
+		}
+		return result[i].Engine < result[j].Engine
+	})
+
+	return result
+}
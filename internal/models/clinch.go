@@ -0,0 +1,473 @@
+package models
+
+import (
+	"sort"
+)
+
+// TargetStatus tells whether a team has clinched or been eliminated from
+// finishing in the top Places positions. Ties on points count against the
+// team when clinching and in its favour when eliminating, since goal
+// difference cannot be known in advance. Unknown is set when the search ran
+// out of budget before it could rule either way, Clinched and Eliminated are
+// then only set if the other search proved them.
+type TargetStatus struct {
+	Places      int  `json:"places"`
+	Clinched    bool `json:"clinched"`
+	Eliminated  bool `json:"eliminated"`
+	Unknown     bool `json:"unknown"`
+	MagicNumber *int `json:"magic_number"` // points to gain or rivals to drop, nil once eliminated
+}
+
+// ClinchOptions selects the places that count as a top finish and as relegation
+type ClinchOptions struct {
+	TopPlaces        int
+	RelegationPlaces int
+}
+
+// DefaultClinchOptions scales the top and relegation places to the league size,
+// a 20 team league gets the top 4 and 3 relegation places
+func DefaultClinchOptions(teams int) ClinchOptions {
+	return ClinchOptions{
+		TopPlaces:        min(4, teams/2),
+		RelegationPlaces: min(3, teams/4),
+	}
+}
+
+// clinchSearchBudget limits the number of search nodes per check, a check
+// that runs out of budget sets no flag and marks the status as unknown
+const clinchSearchBudget = 200000
+
+// ApplyClinchStatus sets the title, top and safety status of every row in the
+// table from the points so far and the unplayed matches. Targets that do not
+// fit the league size are left nil.
+func ApplyClinchStatus(stats []TeamStats, matches []Match, opts ClinchOptions) {
+	r := newRace(stats, matches)
+	n := len(stats)
+
+	for i := range stats {
+		stats[i].Title = r.status(i, 1)
+		stats[i].TopPlaces = r.status(i, opts.TopPlaces)
+		stats[i].Safety = r.status(i, n-opts.RelegationPlaces)
+	}
+}
+
+// race holds the points and the remaining fixtures of a league, teams are
+// referred to by their index in the table
+type race struct {
+	points    []int
+	remaining []int
+	fixtures  [][2]int
+	budget    int // search nodes per check
+}
+
+// newRace collects the remaining fixtures between the teams of a table
+func newRace(stats []TeamStats, matches []Match) *race {
+	index := make(map[uint]int, len(stats))
+	r := &race{
+		points:    make([]int, len(stats)),
+		remaining: make([]int, len(stats)),
+		budget:    clinchSearchBudget,
+	}
+	for i, row := range stats {
+		index[row.TeamID] = i
+		r.points[i] = row.Points
+	}
+
+	for _, match := range matches {
+		home, homeOk := index[match.HomeTeamID]
+		away, awayOk := index[match.AwayTeamID]
+		if match.Played || !homeOk || !awayOk {
+			continue
+		}
+		r.fixtures = append(r.fixtures, [2]int{home, away})
+		r.remaining[home]++
+		r.remaining[away]++
+	}
+
+	return r
+}
+
+// status computes the status of team t for finishing in the top k places
+func (r *race) status(t, k int) *TargetStatus {
+	n := len(r.points)
+	if k < 1 || k >= n {
+		return nil
+	}
+
+	status := &TargetStatus{Places: k}
+	possible, decided := r.canFinishTop(t, k)
+	if decided && !possible {
+		status.Eliminated = true
+		return status
+	}
+	unknown := !decided
+
+	clinched, decided := r.guaranteedTop(t, k)
+	status.Clinched = clinched && decided
+	status.Unknown = !status.Clinched && (unknown || !decided)
+
+	magic := 0
+	if !status.Clinched {
+		// Classic magic number against the k-th best rival's maximum points
+		var rivals []int
+		for i := range r.points {
+			if i != t {
+				rivals = append(rivals, r.points[i]+3*r.remaining[i])
+			}
+		}
+		sort.Sort(sort.Reverse(sort.IntSlice(rivals)))
+		magic = max(1, rivals[k-1]-r.points[t]+1)
+	}
+	status.MagicNumber = &magic
+
+	return status
+}
+
+// canFinishTop reports whether results exist in which fewer than k other
+// teams finish with more points than team t. The second value is false when
+// the search ran out of budget.
+func (r *race) canFinishTop(t, k int) (bool, bool) {
+	// Team t wins all of its remaining matches
+	best := r.points[t] + 3*r.remaining[t]
+
+	var forced, candidates []int
+	caps := make([]int, len(r.points))
+	for i := range r.points {
+		if i == t {
+			continue
+		}
+		caps[i] = best - r.points[i]
+		if caps[i] < 0 {
+			forced = append(forced, i)
+		} else {
+			candidates = append(candidates, i)
+		}
+	}
+	if len(forced) >= k {
+		return false, true
+	}
+
+	// Teams with the fewest points to spare are the best ones to let through
+	sort.SliceStable(candidates, func(a, b int) bool {
+		return caps[candidates[a]] < caps[candidates[b]]
+	})
+
+	budget := r.budget
+	decided := true
+	free := min(k-1-len(forced), len(candidates))
+	possible := false
+	forEachSubset(candidates, free, func(through []int) bool {
+		unbounded := make(map[int]bool, k)
+		for _, i := range forced {
+			unbounded[i] = true
+		}
+		for _, i := range through {
+			unbounded[i] = true
+		}
+
+		// Teams let through beat everyone else, the rest have to stay within their caps
+		var fixtures [][2]int
+		for _, fixture := range r.fixtures {
+			if fixture[0] == t || fixture[1] == t || unbounded[fixture[0]] || unbounded[fixture[1]] {
+				continue
+			}
+			fixtures = append(fixtures, fixture)
+		}
+
+		feasible, ok := fitsCaps(fixtures, caps, &budget)
+		if !ok {
+			decided = false
+		}
+		if feasible {
+			possible = true
+			return false
+		}
+		return budget > 0
+	})
+
+	if possible {
+		return true, true
+	}
+	return false, decided && budget > 0
+}
+
+// guaranteedTop reports whether team t finishes in the top k places whatever
+// the remaining results are. The second value is false when the search ran
+// out of budget.
+func (r *race) guaranteedTop(t, k int) (bool, bool) {
+	// Team t loses all of its remaining matches
+	worst := r.points[t]
+
+	var candidates []int
+	for i := range r.points {
+		if i != t && r.points[i]+3*r.remaining[i] >= worst {
+			candidates = append(candidates, i)
+		}
+	}
+	if len(candidates) < k {
+		return true, true
+	}
+
+	// Teams closest to t are the most likely to overtake it
+	sort.SliceStable(candidates, func(a, b int) bool {
+		return r.points[candidates[a]]+3*r.remaining[candidates[a]] > r.points[candidates[b]]+3*r.remaining[candidates[b]]
+	})
+
+	budget := r.budget
+	decided := true
+	overtaken := false
+	forEachSubset(candidates, k, func(group []int) bool {
+		inGroup := make(map[int]bool, k)
+		for _, i := range group {
+			inGroup[i] = true
+		}
+
+		// The group wins every match against teams outside it, the matches
+		// inside the group have to give every member enough points
+		needs := make([]int, len(r.points))
+		var fixtures [][2]int
+		for _, i := range group {
+			needs[i] = worst - r.points[i]
+		}
+		for _, fixture := range r.fixtures {
+			home, away := fixture[0], fixture[1]
+			switch {
+			case inGroup[home] && inGroup[away]:
+				fixtures = append(fixtures, fixture)
+			case inGroup[home]:
+				needs[home] -= 3
+			case inGroup[away]:
+				needs[away] -= 3
+			}
+		}
+
+		feasible, ok := reachesNeeds(fixtures, needs, &budget)
+		if !ok {
+			decided = false
+		}
+		if feasible {
+			overtaken = true
+			return false
+		}
+		return budget > 0
+	})
+
+	if overtaken {
+		return false, true
+	}
+	return true, decided && budget > 0
+}
+
+// forEachSubset calls fn with every subset of items of the given size, in
+// lexicographic order of positions, until fn returns false
+func forEachSubset(items []int, size int, fn func(subset []int) bool) {
+	subset := make([]int, 0, size)
+	var walk func(start int) bool
+	walk = func(start int) bool {
+		if len(subset) == size {
+			return fn(append([]int(nil), subset...))
+		}
+		for i := start; i <= len(items)-(size-len(subset)); i++ {
+			subset = append(subset, items[i])
+			if !walk(i + 1) {
+				return false
+			}
+			subset = subset[:len(subset)-1]
+		}
+		return true
+	}
+	walk(0)
+}
+
+// outcomes are the points of the home and away team for a win, draw and loss
+var outcomes = [3][2]int{{1, 1}, {3, 0}, {0, 3}}
+
+// fitsCaps reports whether the fixtures can be played so that no team gains
+// more than its cap. The second value is false when the budget ran out.
+func fitsCaps(fixtures [][2]int, caps []int, budget *int) (bool, bool) {
+	// Every match hands out at least two points, if even that does not fit
+	// the caps there is no way to play the fixtures
+	if !drawsFit(fixtures, caps) {
+		return false, true
+	}
+
+	gained := make([]int, len(caps))
+	var search func(i int) (bool, bool)
+	search = func(i int) (bool, bool) {
+		if i == len(fixtures) {
+			return true, true
+		}
+		*budget--
+		if *budget <= 0 {
+			return false, false
+		}
+
+		home, away := fixtures[i][0], fixtures[i][1]
+		decided := true
+		for _, outcome := range outcomes {
+			if gained[home]+outcome[0] > caps[home] || gained[away]+outcome[1] > caps[away] {
+				continue
+			}
+			gained[home] += outcome[0]
+			gained[away] += outcome[1]
+			feasible, ok := search(i + 1)
+			gained[home] -= outcome[0]
+			gained[away] -= outcome[1]
+			if feasible {
+				return true, true
+			}
+			if !ok {
+				decided = false
+				break
+			}
+		}
+		return false, decided
+	}
+
+	return search(0)
+}
+
+// drawsFit checks with a max flow whether two points per fixture can be split
+// between its teams without exceeding any cap. Any real set of results that
+// fits the caps passes this check, so failing it proves none exists.
+func drawsFit(fixtures [][2]int, caps []int) bool {
+	if len(fixtures) == 0 {
+		return true
+	}
+
+	// Nodes: source, fixtures, teams, sink
+	source := 0
+	teamNode := func(i int) int { return 1 + len(fixtures) + i }
+	sink := 1 + len(fixtures) + len(caps)
+	flow := newFlowNetwork(sink + 1)
+	for f, fixture := range fixtures {
+		flow.addEdge(source, 1+f, 2)
+		flow.addEdge(1+f, teamNode(fixture[0]), 2)
+		flow.addEdge(1+f, teamNode(fixture[1]), 2)
+	}
+	for i, limit := range caps {
+		if limit > 0 {
+			flow.addEdge(teamNode(i), sink, limit)
+		}
+	}
+
+	return flow.maxFlow(source, sink) == 2*len(fixtures)
+}
+
+// reachesNeeds reports whether the fixtures can be played so that every team
+// gains at least what it needs. The second value is false when the budget ran out.
+func reachesNeeds(fixtures [][2]int, needs []int, budget *int) (bool, bool) {
+	left := make([]int, len(needs))
+	for _, fixture := range fixtures {
+		left[fixture[0]]++
+		left[fixture[1]]++
+	}
+
+	missing := func(i int) int { return max(0, needs[i]) }
+	var search func(i int) (bool, bool)
+	search = func(i int) (bool, bool) {
+		// Every match hands out at most three points
+		total := 0
+		for team := range needs {
+			if missing(team) > 3*left[team] {
+				return false, true
+			}
+			total += missing(team)
+		}
+		if total > 3*(len(fixtures)-i) {
+			return false, true
+		}
+		if i == len(fixtures) {
+			return true, true
+		}
+		*budget--
+		if *budget <= 0 {
+			return false, false
+		}
+
+		home, away := fixtures[i][0], fixtures[i][1]
+		left[home]--
+		left[away]--
+		defer func() {
+			left[home]++
+			left[away]++
+		}()
+
+		decided := true
+		for _, outcome := range outcomes {
+			needs[home] -= outcome[0]
+			needs[away] -= outcome[1]
+			feasible, ok := search(i + 1)
+			needs[home] += outcome[0]
+			needs[away] += outcome[1]
+			if feasible {
+				return true, true
+			}
+			if !ok {
+				decided = false
+				break
+			}
+		}
+		return false, decided
+	}
+
+	return search(0)
+}
+
+// flowNetwork is a small max flow network solved with Edmonds-Karp
+type flowNetwork struct {
+	capacity [][]int
+}
+
+// newFlowNetwork creates a network with the given number of nodes
+func newFlowNetwork(nodes int) *flowNetwork {
+	capacity := make([][]int, nodes)
+	for i := range capacity {
+		capacity[i] = make([]int, nodes)
+	}
+	return &flowNetwork{capacity: capacity}
+}
+
+// addEdge adds capacity from one node to another
+func (f *flowNetwork) addEdge(from, to, capacity int) {
+	f.capacity[from][to] += capacity
+}
+
+// maxFlow returns the maximum flow from source to sink
+func (f *flowNetwork) maxFlow(source, sink int) int {
+	total := 0
+	parent := make([]int, len(f.capacity))
+	for {
+		for i := range parent {
+			parent[i] = -1
+		}
+		parent[source] = source
+		queue := []int{source}
+		for len(queue) > 0 && parent[sink] == -1 {
+			node := queue[0]
+			queue = queue[1:]
+			for next, capacity := range f.capacity[node] {
+				if capacity > 0 && parent[next] == -1 {
+					parent[next] = node
+					queue = append(queue, next)
+				}
+			}
+		}
+		if parent[sink] == -1 {
+			return total
+		}
+
+		bottleneck := -1
+		for node := sink; node != source; node = parent[node] {
+			capacity := f.capacity[parent[node]][node]
+			if bottleneck == -1 || capacity < bottleneck {
+				bottleneck = capacity
+			}
+		}
+		for node := sink; node != source; node = parent[node] {
+			f.capacity[parent[node]][node] -= bottleneck
+			f.capacity[node][parent[node]] += bottleneck
+		}
+		total += bottleneck
+	}
+}
@@ -0,0 +1,128 @@
+package models
+
+import "testing"
+
+// table builds the rows of a league table from the points of teams 1, 2, ...
+func table(points ...int) []TeamStats {
+	stats := make([]TeamStats, len(points))
+	for i, p := range points {
+		stats[i] = TeamStats{TeamID: uint(i + 1), Points: p}
+	}
+	return stats
+}
+
+// fixture is an unplayed match between two teams of a table
+func fixture(home, away uint) Match {
+	return Match{HomeTeamID: home, AwayTeamID: away}
+}
+
+// TestClinchStatus checks the status of the first team of the table
+func TestClinchStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		points     []int
+		matches    []Match
+		places     int
+		clinched   bool
+		eliminated bool
+	}{
+		{
+			name:     "out of reach",
+			points:   []int{10, 3, 3},
+			matches:  []Match{fixture(2, 3)},
+			places:   1,
+			clinched: true,
+		},
+		{
+			name:       "cannot catch up",
+			points:     []int{0, 10, 10},
+			matches:    []Match{fixture(1, 2)},
+			places:     2,
+			eliminated: true,
+		},
+		{
+			name:    "still open",
+			points:  []int{6, 6, 6},
+			matches: []Match{fixture(1, 2), fixture(2, 3), fixture(3, 1)},
+			places:  1,
+		},
+		{
+			// Either winner overtakes team 1, only a draw keeps it level
+			name:    "alive through a draw",
+			points:  []int{6, 5, 5},
+			matches: []Match{fixture(2, 3)},
+			places:  1,
+		},
+		{
+			name:       "no draw helps",
+			points:     []int{4, 5, 5},
+			matches:    []Match{fixture(2, 3)},
+			places:     1,
+			eliminated: true,
+		},
+		{
+			// A draw takes both rivals to 7 points, a level top two counts against team 1
+			name:    "caught by a draw",
+			points:  []int{7, 6, 6, 0},
+			matches: []Match{fixture(2, 3)},
+			places:  2,
+		},
+		{
+			name:     "no result lifts both rivals",
+			points:   []int{7, 6, 5, 0},
+			matches:  []Match{fixture(2, 3)},
+			places:   2,
+			clinched: true,
+		},
+		{
+			// Team 1 loses to team 2 and a draw lifts both bottom teams level with it
+			name:    "caught at the bottom by a draw",
+			points:  []int{2, 9, 1, 1},
+			matches: []Match{fixture(3, 4), fixture(1, 2)},
+			places:  3,
+		},
+		{
+			name:     "safe once the bottom two meet",
+			points:   []int{4, 9, 1, 0},
+			matches:  []Match{fixture(3, 4)},
+			places:   3,
+			clinched: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			status := newRace(table(test.points...), test.matches).status(0, test.places)
+			if status == nil {
+				t.Fatal("got no status")
+			}
+			if status.Clinched != test.clinched || status.Eliminated != test.eliminated || status.Unknown {
+				t.Errorf("got clinched %v eliminated %v unknown %v, want clinched %v eliminated %v",
+					status.Clinched, status.Eliminated, status.Unknown, test.clinched, test.eliminated)
+			}
+			if status.Eliminated != (status.MagicNumber == nil) {
+				t.Errorf("got magic number %v with eliminated %v", status.MagicNumber, status.Eliminated)
+			}
+		})
+	}
+}
+
+func TestClinchStatusOutOfBudget(t *testing.T) {
+	// Team 1 has clinched the top two, but proving it takes a search
+	r := newRace(table(7, 6, 5, 0), []Match{fixture(2, 3)})
+	r.budget = 1
+	status := r.status(0, 2)
+	if status.Clinched || status.Eliminated || !status.Unknown {
+		t.Errorf("got clinched %v eliminated %v unknown %v, want unknown",
+			status.Clinched, status.Eliminated, status.Unknown)
+	}
+
+	// Team 1 is only alive through a draw, which takes a search to find
+	r = newRace(table(6, 5, 5), []Match{fixture(2, 3)})
+	r.budget = 1
+	status = r.status(0, 1)
+	if status.Clinched || status.Eliminated || !status.Unknown {
+		t.Errorf("got clinched %v eliminated %v unknown %v, want unknown",
+			status.Clinched, status.Eliminated, status.Unknown)
+	}
+}
@@ -0,0 +1,191 @@
+package models
+
+import (
+	"math"
+	"sync"
+)
+
+// MaxGoals is the highest number of goals per side covered by a score matrix
+const MaxGoals = 10
+
+// ScoreMatrix holds the probability of every scoreline, indexed [homeGoals][awayGoals]
+type ScoreMatrix [MaxGoals + 1][MaxGoals + 1]float64
+
+// Engine turns two teams into a distribution over scorelines
+type Engine interface {
+	Name() string
+	ScoreMatrix(homeTeam, awayTeam *Team) ScoreMatrix
+}
+
+var (
+	engineMu      sync.RWMutex
+	currentEngine Engine = StrengthEngine{}
+)
+
+// CurrentEngine returns the engine used by Match.Simulate
+func CurrentEngine() Engine {
+	engineMu.RLock()
+	defer engineMu.RUnlock()
+	return currentEngine
+}
+
+// SetEngine replaces the engine used by Match.Simulate
+func SetEngine(engine Engine) {
+	engineMu.Lock()
+	defer engineMu.Unlock()
+	currentEngine = engine
+}
+
+// Sample picks a scoreline from the matrix using a uniform random number in [0, 1)
+func (sm *ScoreMatrix) Sample(random float64) (homeGoals, awayGoals int) {
+	cumulative := 0.0
+	for h := range sm {
+		for a := range sm[h] {
+			cumulative += sm[h][a]
+			if random < cumulative {
+				return h, a
+			}
+		}
+	}
+
+	// Rounding left a tiny gap at the end, fall back to the most likely scoreline
+	return sm.MostLikely()
+}
+
+// MostLikely returns the scoreline with the highest probability
+func (sm *ScoreMatrix) MostLikely() (homeGoals, awayGoals int) {
+	best := -1.0
+	for h := range sm {
+		for a := range sm[h] {
+			if sm[h][a] > best {
+				best = sm[h][a]
+				homeGoals, awayGoals = h, a
+			}
+		}
+	}
+	return homeGoals, awayGoals
+}
+
+// normalize rescales the matrix so that all probabilities add up to one
+func (sm *ScoreMatrix) normalize() {
+	total := 0.0
+	for h := range sm {
+		for a := range sm[h] {
+			total += sm[h][a]
+		}
+	}
+	if total == 0 {
+		return
+	}
+	for h := range sm {
+		for a := range sm[h] {
+			sm[h][a] /= total
+		}
+	}
+}
+
+// StrengthEngine is the original simulation based on the 1-100 team strength
+type StrengthEngine struct{}
+
+// Name returns the engine identifier
+func (StrengthEngine) Name() string {
+	return "strength"
+}
+
+// ScoreMatrix returns the scoreline distribution of the strength based simulation
+func (StrengthEngine) ScoreMatrix(homeTeam, awayTeam *Team) ScoreMatrix {
+	// Calculate base probabilities
+	homeWinProb := homeTeam.CalculateWinProbability(awayTeam)
+	awayWinProb := awayTeam.CalculateWinProbability(homeTeam)
+
+	// Add home advantage
+	homeAdvantage := 0.1 // 10% home advantage
+	homeWinProb = math.Min(homeWinProb+homeAdvantage, 1)
+	awayWinProb = math.Max(math.Min(awayWinProb-homeAdvantage/2, 1-homeWinProb), 0)
+	drawProb := 1.0 - homeWinProb - awayWinProb
+
+	// The winner scores 1-4 goals and the loser fewer, a draw is 0-0 to 2-2
+	var sm ScoreMatrix
+	for winner := 1; winner <= 4; winner++ {
+		for loser := 0; loser < winner; loser++ {
+			p := 1.0 / 4 / float64(winner)
+			sm[winner][loser] += homeWinProb * p
+			sm[loser][winner] += awayWinProb * p
+		}
+	}
+	for goals := 0; goals < 3; goals++ {
+		sm[goals][goals] += drawProb / 3
+	}
+
+	return sm
+}
+
+// PoissonEngine simulates goals with the Dixon-Coles bivariate Poisson model
+// using the fitted attack and defence ratings stored on the teams
+type PoissonEngine struct {
+	HomeAdvantage float64 `json:"home_advantage"`
+	Rho           float64 `json:"rho"`
+}
+
+// NewPoissonEngine creates a Poisson engine from a rating fit
+func NewPoissonEngine(fit *RatingFit) PoissonEngine {
+	return PoissonEngine{
+		HomeAdvantage: fit.HomeAdvantage,
+		Rho:           fit.Rho,
+	}
+}
+
+// Name returns the engine identifier
+func (PoissonEngine) Name() string {
+	return "dixon-coles"
+}
+
+// ExpectedGoals returns the scoring rates of the home and away team
+func (e PoissonEngine) ExpectedGoals(homeTeam, awayTeam *Team) (homeRate, awayRate float64) {
+	homeRate = math.Exp(e.HomeAdvantage + homeTeam.Attack - awayTeam.Defence)
+	awayRate = math.Exp(awayTeam.Attack - homeTeam.Defence)
+	return homeRate, awayRate
+}
+
+// ScoreMatrix returns the Dixon-Coles scoreline distribution
+func (e PoissonEngine) ScoreMatrix(homeTeam, awayTeam *Team) ScoreMatrix {
+	homeRate, awayRate := e.ExpectedGoals(homeTeam, awayTeam)
+	homeProbs := poissonProbabilities(homeRate)
+	awayProbs := poissonProbabilities(awayRate)
+
+	var sm ScoreMatrix
+	for h := range sm {
+		for a := range sm[h] {
+			sm[h][a] = DixonColesTau(h, a, homeRate, awayRate, e.Rho) * homeProbs[h] * awayProbs[a]
+		}
+	}
+	sm.normalize()
+
+	return sm
+}
+
+// DixonColesTau is the low-score correction applied to 0-0, 1-0, 0-1 and 1-1
+func DixonColesTau(homeGoals, awayGoals int, homeRate, awayRate, rho float64) float64 {
+	switch {
+	case homeGoals == 0 && awayGoals == 0:
+		return 1 - homeRate*awayRate*rho
+	case homeGoals == 0 && awayGoals == 1:
+		return 1 + homeRate*rho
+	case homeGoals == 1 && awayGoals == 0:
+		return 1 + awayRate*rho
+	case homeGoals == 1 && awayGoals == 1:
+		return 1 - rho
+	default:
+		return 1
+	}
+}
+
+// poissonProbabilities returns P(X = k) for k = 0..MaxGoals
+func poissonProbabilities(rate float64) [MaxGoals + 1]float64 {
+	var probs [MaxGoals + 1]float64
+	probs[0] = math.Exp(-rate)
+	for k := 1; k <= MaxGoals; k++ {
+		probs[k] = probs[k-1] * rate / float64(k)
+	}
+	return probs
+}
@@ -0,0 +1,211 @@
+package models
+
+import (
+	"sort"
+	"time"
+)
+
+// League event types
+const (
+	EventFixtureScheduled = "FixtureScheduled"
+	EventMatchSimulated   = "MatchSimulated"
+	EventResultRecorded   = "ResultRecorded"
+	EventResultCorrected  = "ResultCorrected"
+	EventResultCleared    = "ResultCleared"
+	EventPointsDeducted   = "PointsDeducted"
+)
+
+// LeagueEvent is one entry of the append-only event stream of a season. The
+// event ID orders the stream. Events are written in the same transaction as
+// the match rows they describe, which remain the source of truth; the stream
+// is an audit copy that can be replayed, and the only record of deductions.
+type LeagueEvent struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	SeasonID   uint      `json:"season_id" gorm:"index"`
+	Type       string    `json:"type"`
+	MatchID    uint      `json:"match_id,omitempty" gorm:"index"`
+	Week       int       `json:"week,omitempty"`
+	HomeTeamID uint      `json:"home_team_id,omitempty"`
+	AwayTeamID uint      `json:"away_team_id,omitempty"`
+	HomeGoals  int       `json:"home_goals"`
+	AwayGoals  int       `json:"away_goals"`
+	Date       time.Time `json:"date"`
+	TeamID     uint      `json:"team_id,omitempty"`
+	Points     int       `json:"points,omitempty"`
+	Reason     string    `json:"reason,omitempty"`
+	Actor      string    `json:"actor"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// NewFixtureEvent records that a match was put on the schedule
+func NewFixtureEvent(match *Match, actor string) *LeagueEvent {
+	return &LeagueEvent{
+		SeasonID:   match.SeasonID,
+		Type:       EventFixtureScheduled,
+		MatchID:    match.ID,
+		Week:       match.Week,
+		HomeTeamID: match.HomeTeamID,
+		AwayTeamID: match.AwayTeamID,
+		Date:       match.Date,
+		Actor:      actor,
+	}
+}
+
+// NewMatchEvent records the change of a match from its old state to its
+// current state. The type follows from the change: a first result is
+// simulated or recorded, a changed result is corrected and a result that
+// was taken back is cleared.
+func NewMatchEvent(old, match *Match, source, actor string) *LeagueEvent {
+	eventType := EventResultRecorded
+	switch {
+	case !match.Played:
+		eventType = EventResultCleared
+	case old.Played:
+		eventType = EventResultCorrected
+	case source == "simulation":
+		eventType = EventMatchSimulated
+	}
+	return &LeagueEvent{
+		SeasonID:   match.SeasonID,
+		Type:       eventType,
+		MatchID:    match.ID,
+		Week:       match.Week,
+		HomeTeamID: match.HomeTeamID,
+		AwayTeamID: match.AwayTeamID,
+		HomeGoals:  match.HomeGoals,
+		AwayGoals:  match.AwayGoals,
+		Reason:     source,
+		Actor:      actor,
+	}
+}
+
+// NewDeductionEvent records a points deduction for a team
+func NewDeductionEvent(seasonID, teamID uint, points int, reason, actor string) *LeagueEvent {
+	return &LeagueEvent{
+		SeasonID: seasonID,
+		Type:     EventPointsDeducted,
+		TeamID:   teamID,
+		Points:   points,
+		Reason:   reason,
+		Actor:    actor,
+	}
+}
+
+// LeagueState is the state of a season rebuilt from its events
+type LeagueState struct {
+	LastEventID uint         `json:"last_event_id"`
+	Events      int          `json:"events"`
+	Matches     []Match      `json:"matches"`
+	Deductions  map[uint]int `json:"deductions"`
+}
+
+// Replay rebuilds the state of a season from its events in stream order
+func Replay(events []LeagueEvent) *LeagueState {
+	state := &LeagueState{Matches: make([]Match, 0)}
+
+	matches := make(map[uint]*Match)
+	for _, event := range events {
+		state.LastEventID = event.ID
+		state.Events++
+
+		switch event.Type {
+		case EventFixtureScheduled:
+			matches[event.MatchID] = &Match{
+				ID:         event.MatchID,
+				SeasonID:   event.SeasonID,
+				Week:       event.Week,
+				HomeTeamID: event.HomeTeamID,
+				AwayTeamID: event.AwayTeamID,
+				Date:       event.Date,
+			}
+		case EventMatchSimulated, EventResultRecorded, EventResultCorrected, EventResultCleared:
+			match, ok := matches[event.MatchID]
+			if !ok {
+				continue
+			}
+			match.HomeGoals = event.HomeGoals
+			match.AwayGoals = event.AwayGoals
+			match.Played = event.Type != EventResultCleared
+		}
+	}
+	state.Deductions = Deductions(events)
+
+	for _, match := range matches {
+		state.Matches = append(state.Matches, *match)
+	}
+	sort.Slice(state.Matches, func(i, j int) bool {
+		return state.Matches[i].ID < state.Matches[j].ID
+	})
+	return state
+}
+
+// Table calculates the league table of the replayed season
+func (s *LeagueState) Table(teams []Team) []TeamStats {
+	stats := NewTable(teams, s.Matches)
+	ApplyDeductions(stats, s.Deductions)
+	return stats
+}
+
+// Deductions sums the points deducted from every team
+func Deductions(events []LeagueEvent) map[uint]int {
+	deductions := make(map[uint]int)
+	for _, event := range events {
+		if event.Type == EventPointsDeducted {
+			deductions[event.TeamID] += event.Points
+		}
+	}
+	return deductions
+}
+
+// DeductionsAfterWeek sums the points deducted from every team that count in
+// the table after a week. A deduction counts from the latest week with a
+// played match at its place in the stream, so the tables of earlier weeks
+// stay as they were.
+func DeductionsAfterWeek(events []LeagueEvent, week int) map[uint]int {
+	deductions := make(map[uint]int)
+	played := make(map[uint]int)
+	for _, event := range events {
+		switch event.Type {
+		case EventMatchSimulated, EventResultRecorded, EventResultCorrected:
+			played[event.MatchID] = event.Week
+		case EventResultCleared:
+			delete(played, event.MatchID)
+		case EventPointsDeducted:
+			from := 0
+			for _, playedWeek := range played {
+				from = max(from, playedWeek)
+			}
+			if from <= week {
+				deductions[event.TeamID] += event.Points
+			}
+		}
+	}
+	return deductions
+}
+
+// ApplyDeductions subtracts deducted points from the table and sorts it again
+func ApplyDeductions(stats []TeamStats, deductions map[uint]int) {
+	if len(deductions) == 0 {
+		return
+	}
+	for i := range stats {
+		stats[i].Points -= deductions[stats[i].TeamID]
+	}
+	SortTable(stats)
+}
+
+// EventsUntil returns the events up to and including an event ID and a
+// time, zero values mean no limit
+func EventsUntil(events []LeagueEvent, lastID uint, at time.Time) []LeagueEvent {
+	var until []LeagueEvent
+	for _, event := range events {
+		if lastID != 0 && event.ID > lastID {
+			break
+		}
+		if !at.IsZero() && event.CreatedAt.After(at) {
+			break
+		}
+		until = append(until, event)
+	}
+	return until
+}
@@ -0,0 +1,75 @@
+package models
+
+import "testing"
+
+func TestDeductionsAfterWeek(t *testing.T) {
+	result := func(eventType string, matchID uint, week int) LeagueEvent {
+		return LeagueEvent{Type: eventType, MatchID: matchID, Week: week}
+	}
+	deduction := func(teamID uint, points int) LeagueEvent {
+		return LeagueEvent{Type: EventPointsDeducted, TeamID: teamID, Points: points}
+	}
+	events := []LeagueEvent{
+		deduction(1, 1), // before any result, counts from the start
+		result(EventResultRecorded, 1, 1),
+		result(EventMatchSimulated, 2, 2),
+		deduction(2, 3), // after week 2
+		result(EventResultCleared, 2, 2),
+		deduction(1, 2), // week 2 was taken back, so after week 1
+		result(EventResultCorrected, 3, 4),
+		deduction(2, 4), // after week 4
+	}
+
+	tests := []struct {
+		week int
+		want map[uint]int
+	}{
+		{0, map[uint]int{1: 1}},
+		{1, map[uint]int{1: 3}},
+		{2, map[uint]int{1: 3, 2: 3}},
+		{3, map[uint]int{1: 3, 2: 3}},
+		{4, map[uint]int{1: 3, 2: 7}},
+	}
+	for _, tt := range tests {
+		got := DeductionsAfterWeek(events, tt.week)
+		if len(got) != len(tt.want) {
+			t.Errorf("week %d: got %v, want %v", tt.week, got, tt.want)
+			continue
+		}
+		for team, points := range tt.want {
+			if got[team] != points {
+				t.Errorf("week %d: got %v, want %v", tt.week, got, tt.want)
+				break
+			}
+		}
+	}
+
+	if got := Deductions(events); got[1] != 3 || got[2] != 7 {
+		t.Errorf("got total deductions %v, want 3 and 7", got)
+	}
+}
+
+func TestPositionHistoryDeductions(t *testing.T) {
+	teams := []Team{{ID: 1, Name: "Ash"}, {ID: 2, Name: "Birch"}}
+	matches := []Match{
+		{ID: 1, Week: 1, HomeTeamID: 1, AwayTeamID: 2, HomeGoals: 1, AwayGoals: 0, Played: true},
+		{ID: 2, Week: 2, HomeTeamID: 2, AwayTeamID: 1, HomeGoals: 0, AwayGoals: 0, Played: true},
+	}
+	events := []LeagueEvent{
+		{Type: EventResultRecorded, MatchID: 1, Week: 1},
+		{Type: EventResultRecorded, MatchID: 2, Week: 2},
+		{Type: EventPointsDeducted, TeamID: 1, Points: 4},
+	}
+
+	history := PositionHistory(teams, matches, events, 1)
+	if len(history) != 2 {
+		t.Fatalf("got %d weeks, want 2", len(history))
+	}
+	// The deduction was made after week 2, so week 1 keeps Ash on top
+	if week := history[0]; week.Points != 3 || week.Position != 1 {
+		t.Errorf("week 1: got %d points in position %d, want 3 in position 1", week.Points, week.Position)
+	}
+	if week := history[1]; week.Points != 0 || week.Position != 2 {
+		t.Errorf("week 2: got %d points in position %d, want 0 in position 2", week.Points, week.Position)
+	}
+}
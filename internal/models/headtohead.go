@@ -0,0 +1,123 @@
+package models
+
+// HeadToHeadRecord is the aggregate record of a team against one opponent
+type HeadToHeadRecord struct {
+	Played       int `json:"played"`
+	Won          int `json:"won"`
+	Drawn        int `json:"drawn"`
+	Lost         int `json:"lost"`
+	GoalsFor     int `json:"goals_for"`
+	GoalsAgainst int `json:"goals_against"`
+}
+
+// add adds a result seen from the team's perspective
+func (r *HeadToHeadRecord) add(goalsFor, goalsAgainst int) {
+	r.Played++
+	r.GoalsFor += goalsFor
+	r.GoalsAgainst += goalsAgainst
+	switch {
+	case goalsFor > goalsAgainst:
+		r.Won++
+	case goalsFor < goalsAgainst:
+		r.Lost++
+	default:
+		r.Drawn++
+	}
+}
+
+// HeadToHead lists every meeting between two teams. All records are seen
+// from the perspective of team A, Home counts the meetings at team A's ground.
+type HeadToHead struct {
+	TeamA       Team             `json:"team_a"`
+	TeamB       Team             `json:"team_b"`
+	Overall     HeadToHeadRecord `json:"overall"`
+	Home        HeadToHeadRecord `json:"home"`
+	Away        HeadToHeadRecord `json:"away"`
+	BiggestWinA *Match           `json:"biggest_win_a"`
+	BiggestWinB *Match           `json:"biggest_win_b"`
+	Meetings    []Match          `json:"meetings"`
+	NextMeeting *Match           `json:"next_meeting"`
+	Prediction  *Prediction      `json:"prediction"`
+}
+
+// NewHeadToHead collects the meetings between two teams and predicts their
+// next scheduled meeting with the current engine
+func NewHeadToHead(teamA, teamB *Team, matches []Match) *HeadToHead {
+	h2h := &HeadToHead{
+		TeamA:    *teamA,
+		TeamB:    *teamB,
+		Meetings: make([]Match, 0),
+	}
+
+	biggestA, biggestB := -1, -1
+	for _, match := range SortMatchesByDate(matches) {
+		aAtHome := match.HomeTeamID == teamA.ID && match.AwayTeamID == teamB.ID
+		aAway := match.HomeTeamID == teamB.ID && match.AwayTeamID == teamA.ID
+		if !aAtHome && !aAway {
+			continue
+		}
+
+		if !match.Played {
+			if h2h.NextMeeting == nil {
+				next := match
+				h2h.NextMeeting = &next
+			}
+			continue
+		}
+
+		goalsA, goalsB := match.HomeGoals, match.AwayGoals
+		if aAway {
+			goalsA, goalsB = goalsB, goalsA
+		}
+		h2h.Overall.add(goalsA, goalsB)
+		if aAtHome {
+			h2h.Home.add(goalsA, goalsB)
+		} else {
+			h2h.Away.add(goalsA, goalsB)
+		}
+
+		switch {
+		case goalsA > goalsB && (biggestA < 0 || isBiggerWin(match, h2h.Meetings[biggestA])):
+			biggestA = len(h2h.Meetings)
+		case goalsB > goalsA && (biggestB < 0 || isBiggerWin(match, h2h.Meetings[biggestB])):
+			biggestB = len(h2h.Meetings)
+		}
+		h2h.Meetings = append(h2h.Meetings, match)
+	}
+
+	if biggestA >= 0 {
+		h2h.BiggestWinA = &h2h.Meetings[biggestA]
+	}
+	if biggestB >= 0 {
+		h2h.BiggestWinB = &h2h.Meetings[biggestB]
+	}
+
+	if h2h.NextMeeting != nil {
+		homeTeam, awayTeam := teamA, teamB
+		if h2h.NextMeeting.HomeTeamID == teamB.ID {
+			homeTeam, awayTeam = teamB, teamA
+		}
+		h2h.Prediction = h2h.NextMeeting.Predict(homeTeam, awayTeam)
+	}
+
+	return h2h
+}
+
+// isBiggerWin compares two wins by margin, then by goals scored, and prefers
+// the later match on a tie
+func isBiggerWin(match, current Match) bool {
+	margin := abs(match.HomeGoals - match.AwayGoals)
+	currentMargin := abs(current.HomeGoals - current.AwayGoals)
+	if margin != currentMargin {
+		return margin > currentMargin
+	}
+	return max(match.HomeGoals, match.AwayGoals) >= max(current.HomeGoals, current.AwayGoals)
+}
+
+// abs returns the absolute value of an integer
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -29,30 +29,52 @@ func (l *League) AddTeam(team *Team) {
 	})
 }
 
-// GenerateFixtures generates all matches for the league
+// bye marks the empty slot of the round-robin when the number of teams is odd
+const bye = -1
+
+// GenerateFixtures generates a double round-robin with the circle method:
+// the first team stays in place while the others rotate, so every pair
+// meets once in each half, at home in one half and away in the other. With
+// an odd number of teams one team has a bye every week.
 func (l *League) GenerateFixtures() {
-	numTeams := len(l.Teams)
-	weeks := (numTeams - 1) * 2
-	matchesPerWeek := numTeams / 2
+	if len(l.Teams) < 2 {
+		return
+	}
 
-	for week := 1; week <= weeks; week++ {
-		for i := 0; i < matchesPerWeek; i++ {
-			homeIdx := (week + i) % (numTeams - 1)
-			awayIdx := (numTeams - 1 - i) % (numTeams - 1)
+	slots := make([]int, len(l.Teams))
+	for i := range slots {
+		slots[i] = i
+	}
+	if len(slots)%2 == 1 {
+		slots = append(slots, bye)
+	}
+	rounds := len(slots) - 1
 
-			if i == 0 {
-				awayIdx = numTeams - 1
+	for round := 0; round < rounds; round++ {
+		for i := 0; i < len(slots)/2; i++ {
+			home, away := slots[i], slots[len(slots)-1-i]
+			if home == bye || away == bye {
+				continue
 			}
-
-			// Swap home and away for second half of season
-			if week > weeks/2 {
-				homeIdx, awayIdx = awayIdx, homeIdx
+			// Alternate the fixed team, and the other pairs by side of the
+			// circle, so home games are spread over the season
+			if i == 0 && round%2 == 1 || i > 0 && i%2 == 1 {
+				home, away = away, home
 			}
-
-			match := NewMatch(week, &l.Teams[homeIdx], &l.Teams[awayIdx])
-			l.Matches = append(l.Matches, *match)
+			first := NewMatch(round+1, &l.Teams[home], &l.Teams[away])
+			second := NewMatch(round+1+rounds, &l.Teams[away], &l.Teams[home])
+			l.Matches = append(l.Matches, *first, *second)
 		}
+
+		// Rotate every slot but the first one place clockwise
+		last := slots[len(slots)-1]
+		copy(slots[2:], slots[1:len(slots)-1])
+		slots[1] = last
 	}
+
+	sort.SliceStable(l.Matches, func(i, j int) bool {
+		return l.Matches[i].Week < l.Matches[j].Week
+	})
 }
 
 // UpdateStats updates the league statistics based on match results
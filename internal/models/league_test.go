@@ -0,0 +1,57 @@
+package models
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestGenerateFixtures(t *testing.T) {
+	for _, numTeams := range []int{4, 5, 6} {
+		t.Run(fmt.Sprintf("%d teams", numTeams), func(t *testing.T) {
+			league := NewLeague()
+			for i := 1; i <= numTeams; i++ {
+				league.AddTeam(&Team{ID: uint(i), Name: fmt.Sprintf("Team %d", i)})
+			}
+			league.GenerateFixtures()
+
+			// An odd number of teams plays with a bye, so one more week per half
+			weeks := 2 * (numTeams - 1)
+			if numTeams%2 == 1 {
+				weeks = 2 * numTeams
+			}
+			if want := numTeams * (numTeams - 1); len(league.Matches) != want {
+				t.Fatalf("got %d matches, want %d", len(league.Matches), want)
+			}
+
+			type pair struct{ home, away uint }
+			meetings := make(map[pair]int)
+			playing := make(map[int]map[uint]bool)
+			for _, match := range league.Matches {
+				if match.HomeTeamID == match.AwayTeamID {
+					t.Errorf("week %d: team %d plays itself", match.Week, match.HomeTeamID)
+				}
+				if match.Week < 1 || match.Week > weeks {
+					t.Errorf("match in week %d, want 1 to %d", match.Week, weeks)
+				}
+				if playing[match.Week] == nil {
+					playing[match.Week] = make(map[uint]bool)
+				}
+				for _, team := range []uint{match.HomeTeamID, match.AwayTeamID} {
+					if playing[match.Week][team] {
+						t.Errorf("week %d: team %d plays twice", match.Week, team)
+					}
+					playing[match.Week][team] = true
+				}
+				meetings[pair{match.HomeTeamID, match.AwayTeamID}]++
+			}
+
+			for home := uint(1); home <= uint(numTeams); home++ {
+				for away := uint(1); away <= uint(numTeams); away++ {
+					if home != away && meetings[pair{home, away}] != 1 {
+						t.Errorf("team %d hosts team %d %d times, want once", home, away, meetings[pair{home, away}])
+					}
+				}
+			}
+		})
+	}
+}
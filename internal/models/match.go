@@ -8,14 +8,17 @@ import (
 // Match represents a football match between two teams
 type Match struct {
 	ID         uint      `json:"id" gorm:"primaryKey"`
-	Week       int       `json:"week"`
+	SeasonID   uint      `json:"season_id" gorm:"index;index:idx_matches_season_week,priority:1"`
+	Week       int       `json:"week" gorm:"index:idx_matches_season_week,priority:2"`
 	HomeTeam   Team      `json:"home_team" gorm:"foreignKey:HomeTeamID"`
-	HomeTeamID uint      `json:"home_team_id"`
+	HomeTeamID uint      `json:"home_team_id" gorm:"index"`
 	AwayTeam   Team      `json:"away_team" gorm:"foreignKey:AwayTeamID"`
-	AwayTeamID uint      `json:"away_team_id"`
+	AwayTeamID uint      `json:"away_team_id" gorm:"index"`
 	HomeGoals  int       `json:"home_goals"`
 	AwayGoals  int       `json:"away_goals"`
 	Played     bool      `json:"played"`
+	Date       time.Time `json:"date"`
+	Version    int       `json:"version" gorm:"not null;default:0"`
 	CreatedAt  time.Time `json:"created_at"`
 	UpdatedAt  time.Time `json:"updated_at"`
 }
@@ -24,8 +27,9 @@ type Match struct {
 type MatchResult struct {
 	HomeTeamID uint `json:"home_team_id"`
 	AwayTeamID uint `json:"away_team_id"`
-	HomeGoals  int  `json:"home_goals"`
-	AwayGoals  int  `json:"away_goals"`
+	HomeGoals  int  `json:"home_goals" openapi:"required,minimum=0"`
+	AwayGoals  int  `json:"away_goals" openapi:"required,minimum=0"`
+	Version    *int `json:"version,omitempty"` // version the client last saw, optional
 }
 
 // NewMatch creates a new match instance
@@ -38,36 +42,23 @@ func NewMatch(week int, homeTeam, awayTeam *Team) *Match {
 	}
 }
 
-// Simulate simulates the match result based on team strengths
+// Simulate simulates the match result with the current engine
 func (m *Match) Simulate(homeTeam, awayTeam *Team) {
-	// Calculate base probabilities
-	homeWinProb := homeTeam.CalculateWinProbability(awayTeam)
-	awayWinProb := awayTeam.CalculateWinProbability(homeTeam)
-	drawProb := 1.0 - homeWinProb - awayWinProb
-
-	// Add some randomness to make it more interesting
-	homeAdvantage := 0.1 // 10% home advantage
-	homeWinProb += homeAdvantage
-	awayWinProb -= homeAdvantage / 2
-	drawProb -= homeAdvantage / 2
-
-	// Generate random result
-	// This is a simplified simulation - in a real system, you'd want more sophisticated logic
-	random := rand.Float64()
+	scores := CurrentEngine().ScoreMatrix(homeTeam, awayTeam)
+	m.HomeGoals, m.AwayGoals = scores.Sample(rand.Float64())
+	m.Played = true
+}
 
-	switch {
-	case random < homeWinProb:
-		m.HomeGoals = rand.Intn(4) + 1
-		m.AwayGoals = rand.Intn(m.HomeGoals)
-	case random < homeWinProb+awayWinProb:
-		m.AwayGoals = rand.Intn(4) + 1
-		m.HomeGoals = rand.Intn(m.AwayGoals)
-	default:
-		m.HomeGoals = rand.Intn(3)
-		m.AwayGoals = m.HomeGoals
-	}
+// Predict returns the pre-match prediction of the engine used by Simulate
+func (m *Match) Predict(homeTeam, awayTeam *Team) *Prediction {
+	return NewPrediction(m, homeTeam, awayTeam, CurrentEngine())
+}
 
-	m.Played = true
+// ClearResult turns the match back into a fixture that has not been played
+func (m *Match) ClearResult() {
+	m.HomeGoals = 0
+	m.AwayGoals = 0
+	m.Played = false
 }
 
 // UpdateResult updates the match result manually
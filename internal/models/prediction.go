@@ -0,0 +1,124 @@
+package models
+
+import (
+	"sort"
+)
+
+// PredictedScorelines is the number of most likely scorelines in a prediction
+const PredictedScorelines = 5
+
+// Scoreline is a single score with its probability
+type Scoreline struct {
+	HomeGoals   int     `json:"home_goals"`
+	AwayGoals   int     `json:"away_goals"`
+	Probability float64 `json:"probability"`
+}
+
+// Odds are fair decimal odds without bookmaker margin, 0 when an outcome is impossible
+type Odds struct {
+	Home float64 `json:"home"`
+	Draw float64 `json:"draw"`
+	Away float64 `json:"away"`
+}
+
+// Prediction is the pre-match view of the engine on a match
+type Prediction struct {
+	MatchID           uint        `json:"match_id"`
+	Week              int         `json:"week"`
+	HomeTeamID        uint        `json:"home_team_id"`
+	HomeTeam          string      `json:"home_team"`
+	AwayTeamID        uint        `json:"away_team_id"`
+	AwayTeam          string      `json:"away_team"`
+	Engine            string      `json:"engine"`
+	HomeWin           float64     `json:"home_win"`
+	Draw              float64     `json:"draw"`
+	AwayWin           float64     `json:"away_win"`
+	ExpectedHomeGoals float64     `json:"expected_home_goals"`
+	ExpectedAwayGoals float64     `json:"expected_away_goals"`
+	Scorelines        []Scoreline `json:"scorelines"`
+	Odds              Odds        `json:"odds"`
+}
+
+// NewPrediction builds a prediction from the scoreline distribution of an engine
+func NewPrediction(match *Match, homeTeam, awayTeam *Team, engine Engine) *Prediction {
+	scores := engine.ScoreMatrix(homeTeam, awayTeam)
+	homeWin, draw, awayWin := scores.Outcomes()
+	expectedHome, expectedAway := scores.ExpectedGoals()
+
+	return &Prediction{
+		MatchID:           match.ID,
+		Week:              match.Week,
+		HomeTeamID:        homeTeam.ID,
+		HomeTeam:          homeTeam.Name,
+		AwayTeamID:        awayTeam.ID,
+		AwayTeam:          awayTeam.Name,
+		Engine:            engine.Name(),
+		HomeWin:           homeWin,
+		Draw:              draw,
+		AwayWin:           awayWin,
+		ExpectedHomeGoals: expectedHome,
+		ExpectedAwayGoals: expectedAway,
+		Scorelines:        scores.TopScorelines(PredictedScorelines),
+		Odds: Odds{
+			Home: fairOdds(homeWin),
+			Draw: fairOdds(draw),
+			Away: fairOdds(awayWin),
+		},
+	}
+}
+
+// Outcomes returns the probabilities of a home win, a draw and an away win
+func (sm *ScoreMatrix) Outcomes() (homeWin, draw, awayWin float64) {
+	for h := range sm {
+		for a := range sm[h] {
+			switch {
+			case h > a:
+				homeWin += sm[h][a]
+			case h < a:
+				awayWin += sm[h][a]
+			default:
+				draw += sm[h][a]
+			}
+		}
+	}
+	return homeWin, draw, awayWin
+}
+
+// ExpectedGoals returns the expected number of goals of both teams
+func (sm *ScoreMatrix) ExpectedGoals() (homeGoals, awayGoals float64) {
+	for h := range sm {
+		for a := range sm[h] {
+			homeGoals += float64(h) * sm[h][a]
+			awayGoals += float64(a) * sm[h][a]
+		}
+	}
+	return homeGoals, awayGoals
+}
+
+// TopScorelines returns the n most likely scorelines, most likely first
+func (sm *ScoreMatrix) TopScorelines(n int) []Scoreline {
+	scorelines := make([]Scoreline, 0, len(sm)*len(sm[0]))
+	for h := range sm {
+		for a := range sm[h] {
+			if sm[h][a] > 0 {
+				scorelines = append(scorelines, Scoreline{HomeGoals: h, AwayGoals: a, Probability: sm[h][a]})
+			}
+		}
+	}
+
+	sort.SliceStable(scorelines, func(i, j int) bool {
+		return scorelines[i].Probability > scorelines[j].Probability
+	})
+	if len(scorelines) > n {
+		scorelines = scorelines[:n]
+	}
+	return scorelines
+}
+
+// fairOdds converts a probability to decimal odds
+func fairOdds(probability float64) float64 {
+	if probability <= 0 {
+		return 0
+	}
+	return 1 / probability
+}
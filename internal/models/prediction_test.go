@@ -0,0 +1,82 @@
+package models
+
+import (
+	"math"
+	"testing"
+)
+
+// fixedEngine returns the same score matrix for every match
+type fixedEngine ScoreMatrix
+
+func (fixedEngine) Name() string { return "fixed" }
+
+func (e fixedEngine) ScoreMatrix(homeTeam, awayTeam *Team) ScoreMatrix { return ScoreMatrix(e) }
+
+func TestNewPrediction(t *testing.T) {
+	home := &Team{ID: 1, Name: "Ash", Strength: 80, Attack: 0.3, Defence: 0.1}
+	away := &Team{ID: 2, Name: "Birch", Strength: 55, Attack: -0.1, Defence: -0.2}
+	match := &Match{ID: 7, Week: 3}
+
+	var oneGoal ScoreMatrix
+	oneGoal[1][0] = 0.75
+	oneGoal[0][1] = 0.25
+
+	engines := []Engine{
+		StrengthEngine{},
+		PoissonEngine{HomeAdvantage: 0.25, Rho: -0.1},
+		fixedEngine(oneGoal),
+	}
+	for _, engine := range engines {
+		t.Run(engine.Name(), func(t *testing.T) {
+			prediction := NewPrediction(match, home, away, engine)
+			if prediction.MatchID != 7 || prediction.Week != 3 || prediction.HomeTeam != "Ash" || prediction.AwayTeam != "Birch" {
+				t.Errorf("got prediction %+v for the wrong match", prediction)
+			}
+
+			if sum := prediction.HomeWin + prediction.Draw + prediction.AwayWin; math.Abs(sum-1) > 1e-6 {
+				t.Errorf("outcome probabilities sum to %v, want 1", sum)
+			}
+			odds := []struct {
+				name              string
+				probability, odds float64
+			}{
+				{"home", prediction.HomeWin, prediction.Odds.Home},
+				{"draw", prediction.Draw, prediction.Odds.Draw},
+				{"away", prediction.AwayWin, prediction.Odds.Away},
+			}
+			for _, o := range odds {
+				want := 0.0
+				if o.probability > 0 {
+					want = 1 / o.probability
+				}
+				if math.Abs(o.odds-want) > 1e-9 {
+					t.Errorf("%s odds are %v for probability %v, want %v", o.name, o.odds, o.probability, want)
+				}
+			}
+
+			scorelines := prediction.Scorelines
+			if len(scorelines) == 0 || len(scorelines) > PredictedScorelines {
+				t.Fatalf("got %d scorelines, want 1 to %d", len(scorelines), PredictedScorelines)
+			}
+			scores := engine.ScoreMatrix(home, away)
+			homeGoals, awayGoals := scores.MostLikely()
+			if scorelines[0].HomeGoals != homeGoals || scorelines[0].AwayGoals != awayGoals {
+				t.Errorf("most likely scoreline is %d-%d, want %d-%d", scorelines[0].HomeGoals, scorelines[0].AwayGoals, homeGoals, awayGoals)
+			}
+			for i, scoreline := range scorelines {
+				if scoreline.Probability <= 0 || scoreline.Probability != scores[scoreline.HomeGoals][scoreline.AwayGoals] {
+					t.Errorf("scoreline %d-%d has probability %v", scoreline.HomeGoals, scoreline.AwayGoals, scoreline.Probability)
+				}
+				if i > 0 && scoreline.Probability > scorelines[i-1].Probability {
+					t.Errorf("scoreline %d is more likely than the one before it", i+1)
+				}
+			}
+		})
+	}
+
+	// Impossible outcomes and scorelines are left out
+	prediction := NewPrediction(match, home, away, fixedEngine(oneGoal))
+	if prediction.Odds.Draw != 0 || prediction.Odds.Home != 1/0.75 || len(prediction.Scorelines) != 2 {
+		t.Errorf("got odds %+v and %d scorelines, want no draw odds and 2 scorelines", prediction.Odds, len(prediction.Scorelines))
+	}
+}
@@ -0,0 +1,102 @@
+package models
+
+import (
+	"context"
+	"math/rand"
+)
+
+// DefaultProjectionRuns is the number of Monte Carlo seasons simulated by default
+const DefaultProjectionRuns = 10000
+
+// TeamProjection is the Monte Carlo outlook of a single team
+type TeamProjection struct {
+	TeamID                uint      `json:"team_id"`
+	TeamName              string    `json:"team_name"`
+	ExpectedPoints        float64   `json:"expected_points"`
+	Champion              float64   `json:"champion"`
+	PositionProbabilities []float64 `json:"position_probabilities"` // index 0 is first place
+}
+
+// Projection is the result of simulating the rest of a season many times
+type Projection struct {
+	Engine string           `json:"engine"`
+	Runs   int              `json:"runs"`
+	Teams  []TeamProjection `json:"teams"`
+}
+
+// ProjectSeason simulates every unplayed match runs times with the engine and
+// returns how often each team finished in each position. Points deductions
+// are taken off before the first run. The projection is sorted like the
+// current table. It stops with the context error once ctx is done.
+func ProjectSeason(ctx context.Context, teams []Team, matches []Match, deductions map[uint]int, engine Engine, runs int, rng *rand.Rand) (*Projection, error) {
+	if runs <= 0 {
+		runs = DefaultProjectionRuns
+	}
+
+	table := NewTable(teams, matches)
+	ApplyDeductions(table, deductions)
+	teamsByID := make(map[uint]*Team, len(teams))
+	for i := range teams {
+		teamsByID[teams[i].ID] = &teams[i]
+	}
+
+	// Score distributions only depend on the two teams, compute them once
+	var remaining []Match
+	var scores []ScoreMatrix
+	for _, match := range matches {
+		homeTeam, homeOk := teamsByID[match.HomeTeamID]
+		awayTeam, awayOk := teamsByID[match.AwayTeamID]
+		if match.Played || !homeOk || !awayOk {
+			continue
+		}
+		remaining = append(remaining, match)
+		scores = append(scores, engine.ScoreMatrix(homeTeam, awayTeam))
+	}
+
+	projection := &Projection{
+		Engine: engine.Name(),
+		Runs:   runs,
+		Teams:  make([]TeamProjection, len(table)),
+	}
+	index := make(map[uint]int, len(table))
+	for i, row := range table {
+		projection.Teams[i] = TeamProjection{
+			TeamID:                row.TeamID,
+			TeamName:              row.TeamName,
+			PositionProbabilities: make([]float64, len(table)),
+		}
+		index[row.TeamID] = i
+	}
+
+	final := make([]TeamStats, len(table))
+	for run := 0; run < runs; run++ {
+		err := ctx.Err()
+		if err != nil {
+			return nil, err
+		}
+		copy(final, table)
+		for i, match := range remaining {
+			homeGoals, awayGoals := scores[i].Sample(rng.Float64())
+			final[index[match.HomeTeamID]].addResult(homeGoals, awayGoals)
+			final[index[match.AwayTeamID]].addResult(awayGoals, homeGoals)
+		}
+		SortTable(final)
+
+		for position, row := range final {
+			team := &projection.Teams[index[row.TeamID]]
+			team.ExpectedPoints += float64(row.Points)
+			team.PositionProbabilities[position]++
+		}
+	}
+
+	for i := range projection.Teams {
+		team := &projection.Teams[i]
+		team.ExpectedPoints /= float64(runs)
+		for position := range team.PositionProbabilities {
+			team.PositionProbabilities[position] /= float64(runs)
+		}
+		team.Champion = team.PositionProbabilities[0]
+	}
+
+	return projection, nil
+}
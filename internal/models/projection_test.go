@@ -0,0 +1,32 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"testing"
+)
+
+func TestProjectSeasonStopsWhenCancelled(t *testing.T) {
+	teams := []Team{{ID: 1, Name: "Home", Strength: 60}, {ID: 2, Name: "Away", Strength: 50}}
+	matches := []Match{{ID: 1, Week: 1, HomeTeamID: 1, AwayTeamID: 2}}
+
+	projection, err := ProjectSeason(context.Background(), teams, matches, nil, StrengthEngine{}, 100, rand.New(rand.NewSource(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	total := 0.0
+	for _, team := range projection.Teams {
+		total += team.Champion
+	}
+	if total < 0.999 || total > 1.001 {
+		t.Errorf("champion probabilities add up to %.3f, want 1", total)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = ProjectSeason(ctx, teams, matches, nil, StrengthEngine{}, 100, rand.New(rand.NewSource(1)))
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v from a cancelled projection, want context.Canceled", err)
+	}
+}
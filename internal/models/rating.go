@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+)
+
+// RatingFit stores the league-wide parameters of a Dixon-Coles fit,
+// the per-team attack and defence ratings are stored on the teams
+type RatingFit struct {
+	ID            uint      `json:"id" gorm:"primaryKey"`
+	Source        string    `json:"source"` // "database" or "csv"
+	HomeAdvantage float64   `json:"home_advantage"`
+	Rho           float64   `json:"rho"`
+	Xi            float64   `json:"xi"` // time decay per day
+	LogLikelihood float64   `json:"log_likelihood"`
+	Matches       int       `json:"matches"`
+	Iterations    int       `json:"iterations"`
+	Converged     bool      `json:"converged"`
+	ReferenceDate time.Time `json:"reference_date"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// TeamRating is the fitted attack and defence rating of a single team
+type TeamRating struct {
+	TeamID  uint    `json:"team_id,omitempty"`
+	Name    string  `json:"name"`
+	Matches int     `json:"matches"`
+	Attack  float64 `json:"attack"`
+	Defence float64 `json:"defence"`
+}
@@ -0,0 +1,239 @@
+package models
+
+import (
+	"sort"
+)
+
+// RecordFilter narrows records down to one team and/or one season, zero means all
+type RecordFilter struct {
+	TeamID   uint `json:"team_id,omitempty"`
+	SeasonID uint `json:"season_id,omitempty"`
+}
+
+// Streak is a run of consecutive matches of a team. Current is true when the
+// run is still going on after the team's last played match.
+type Streak struct {
+	TeamID       uint   `json:"team_id"`
+	TeamName     string `json:"team_name"`
+	Length       int    `json:"length"`
+	StartMatchID uint   `json:"start_match_id"`
+	EndMatchID   uint   `json:"end_match_id"`
+	StartSeason  uint   `json:"start_season_id"`
+	StartWeek    int    `json:"start_week"`
+	EndSeason    uint   `json:"end_season_id"`
+	EndWeek      int    `json:"end_week"`
+	Current      bool   `json:"current"`
+}
+
+// StageRecord is the most points any team had after a week of a season
+type StageRecord struct {
+	Week     int    `json:"week"`
+	SeasonID uint   `json:"season_id"`
+	TeamID   uint   `json:"team_id"`
+	TeamName string `json:"team_name"`
+	Points   int    `json:"points"`
+}
+
+// Records are the season or all-time records computed from the played matches
+type Records struct {
+	Filter             RecordFilter  `json:"filter"`
+	Matches            int           `json:"matches"`
+	BiggestWin         *Match        `json:"biggest_win"`
+	HighestScoring     *Match        `json:"highest_scoring"`
+	LongestWinning     *Streak       `json:"longest_winning"`
+	LongestUnbeaten    *Streak       `json:"longest_unbeaten"`
+	LongestLosing      *Streak       `json:"longest_losing"`
+	LongestWinless     *Streak       `json:"longest_winless"`
+	LongestCleanSheets *Streak       `json:"longest_clean_sheets"`
+	MostPointsByWeek   []StageRecord `json:"most_points_by_week"`
+}
+
+// streakKinds decide whether a result extends a winning, unbeaten, losing,
+// winless and clean sheet streak, in that order
+var streakKinds = []func(goalsFor, goalsAgainst int) bool{
+	func(f, a int) bool { return f > a },
+	func(f, a int) bool { return f >= a },
+	func(f, a int) bool { return f < a },
+	func(f, a int) bool { return f <= a },
+	func(f, a int) bool { return a == 0 },
+}
+
+// NewRecords computes records from the played matches. Streaks run across
+// seasons unless the filter selects a single season. Events are the event
+// streams by season, their deductions count in the points by week.
+func NewRecords(teams []Team, matches []Match, events map[uint][]LeagueEvent, filter RecordFilter) *Records {
+	records := &Records{
+		Filter:           filter,
+		MostPointsByWeek: make([]StageRecord, 0),
+	}
+
+	teamNames := make(map[uint]string, len(teams))
+	for _, team := range teams {
+		teamNames[team.ID] = team.Name
+	}
+
+	var played []Match
+	for _, match := range SortMatchesByDate(matches) {
+		if !match.Played {
+			continue
+		}
+		if filter.SeasonID != 0 && match.SeasonID != filter.SeasonID {
+			continue
+		}
+		if filter.TeamID != 0 && match.HomeTeamID != filter.TeamID && match.AwayTeamID != filter.TeamID {
+			continue
+		}
+		played = append(played, match)
+	}
+	records.Matches = len(played)
+
+	// Single match records, later matches win ties. With a team filter the
+	// biggest win has to be a win of that team.
+	for i := range played {
+		match := &played[i]
+		won := match.HomeGoals != match.AwayGoals
+		if filter.TeamID == match.HomeTeamID {
+			won = match.HomeGoals > match.AwayGoals
+		} else if filter.TeamID == match.AwayTeamID {
+			won = match.AwayGoals > match.HomeGoals
+		}
+		if won && (records.BiggestWin == nil || isBiggerWin(*match, *records.BiggestWin)) {
+			records.BiggestWin = match
+		}
+		if records.HighestScoring == nil || match.HomeGoals+match.AwayGoals >= records.HighestScoring.HomeGoals+records.HighestScoring.AwayGoals {
+			records.HighestScoring = match
+		}
+	}
+
+	// Streaks per team
+	longest := make([]*Streak, len(streakKinds))
+	running := make(map[uint][]*Streak)
+	update := func(teamID uint, match *Match, goalsFor, goalsAgainst int) {
+		if filter.TeamID != 0 && teamID != filter.TeamID {
+			return
+		}
+		streaks, ok := running[teamID]
+		if !ok {
+			streaks = make([]*Streak, len(streakKinds))
+			running[teamID] = streaks
+		}
+		for k, extends := range streakKinds {
+			if !extends(goalsFor, goalsAgainst) {
+				streaks[k] = nil
+				continue
+			}
+			if streaks[k] == nil {
+				streaks[k] = &Streak{
+					TeamID:       teamID,
+					TeamName:     teamNames[teamID],
+					StartMatchID: match.ID,
+					StartSeason:  match.SeasonID,
+					StartWeek:    match.Week,
+				}
+			}
+			streak := streaks[k]
+			streak.Length++
+			streak.EndMatchID = match.ID
+			streak.EndSeason = match.SeasonID
+			streak.EndWeek = match.Week
+			if longest[k] == nil || streak.Length >= longest[k].Length {
+				longest[k] = streak
+			}
+		}
+	}
+	for i := range played {
+		match := &played[i]
+		update(match.HomeTeamID, match, match.HomeGoals, match.AwayGoals)
+		update(match.AwayTeamID, match, match.AwayGoals, match.HomeGoals)
+	}
+	for k := range longest {
+		if longest[k] == nil {
+			continue
+		}
+		streak := *longest[k]
+		streak.Current = running[streak.TeamID][k] == longest[k]
+		longest[k] = &streak
+	}
+	records.LongestWinning = longest[0]
+	records.LongestUnbeaten = longest[1]
+	records.LongestLosing = longest[2]
+	records.LongestWinless = longest[3]
+	records.LongestCleanSheets = longest[4]
+
+	records.MostPointsByWeek = mostPointsByWeek(played, teamNames, events, filter.TeamID)
+	return records
+}
+
+// mostPointsByWeek returns for every week the most points a team had
+// collected in a season up to and including that week. Like the position
+// history, a deduction counts from the week it was made in.
+func mostPointsByWeek(played []Match, teamNames map[uint]string, events map[uint][]LeagueEvent, teamID uint) []StageRecord {
+	type teamSeason struct {
+		seasonID, teamID uint
+	}
+	pointsByWeek := make(map[teamSeason]map[int]int)
+	lastWeek := 0
+	add := func(seasonID, team uint, week, points int) {
+		if teamID != 0 && team != teamID {
+			return
+		}
+		key := teamSeason{seasonID, team}
+		if pointsByWeek[key] == nil {
+			pointsByWeek[key] = make(map[int]int)
+		}
+		pointsByWeek[key][week] += points
+	}
+	for _, match := range played {
+		lastWeek = max(lastWeek, match.Week)
+		switch {
+		case match.HomeGoals > match.AwayGoals:
+			add(match.SeasonID, match.HomeTeamID, match.Week, 3)
+			add(match.SeasonID, match.AwayTeamID, match.Week, 0)
+		case match.HomeGoals < match.AwayGoals:
+			add(match.SeasonID, match.HomeTeamID, match.Week, 0)
+			add(match.SeasonID, match.AwayTeamID, match.Week, 3)
+		default:
+			add(match.SeasonID, match.HomeTeamID, match.Week, 1)
+			add(match.SeasonID, match.AwayTeamID, match.Week, 1)
+		}
+	}
+
+	// Deterministic order so ties go to the earliest season and lowest team ID
+	keys := make([]teamSeason, 0, len(pointsByWeek))
+	for key := range pointsByWeek {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].seasonID != keys[j].seasonID {
+			return keys[i].seasonID < keys[j].seasonID
+		}
+		return keys[i].teamID < keys[j].teamID
+	})
+
+	best := make([]StageRecord, 0, lastWeek)
+	cumulative := make([]int, len(keys))
+	for week := 1; week <= lastWeek; week++ {
+		deductions := make(map[uint]map[uint]int, len(events))
+		for seasonID, seasonEvents := range events {
+			deductions[seasonID] = DeductionsAfterWeek(seasonEvents, week)
+		}
+		var record *StageRecord
+		for i, key := range keys {
+			cumulative[i] += pointsByWeek[key][week]
+			points := cumulative[i] - deductions[key.seasonID][key.teamID]
+			if record == nil || points > record.Points {
+				record = &StageRecord{
+					Week:     week,
+					SeasonID: key.seasonID,
+					TeamID:   key.teamID,
+					TeamName: teamNames[key.teamID],
+					Points:   points,
+				}
+			}
+		}
+		if record != nil {
+			best = append(best, *record)
+		}
+	}
+	return best
+}
@@ -0,0 +1,134 @@
+package models
+
+import (
+	"time"
+)
+
+// Scenario is a what-if branch of a season in which any number of match
+// results are overridden without touching the real matches
+type Scenario struct {
+	ID          uint               `json:"id" gorm:"primaryKey"`
+	SeasonID    uint               `json:"season_id" gorm:"index"`
+	Name        string             `json:"name"`
+	Description string             `json:"description"`
+	Overrides   []ScenarioOverride `json:"overrides" gorm:"foreignKey:ScenarioID;constraint:OnDelete:CASCADE"`
+	CreatedAt   time.Time          `json:"created_at"`
+	UpdatedAt   time.Time          `json:"updated_at"`
+}
+
+// ScenarioOverride is a hypothetical result of a match inside a scenario
+type ScenarioOverride struct {
+	ID         uint `json:"id" gorm:"primaryKey"`
+	ScenarioID uint `json:"scenario_id" gorm:"uniqueIndex:idx_scenario_match"`
+	MatchID    uint `json:"match_id" gorm:"uniqueIndex:idx_scenario_match" openapi:"required"`
+	HomeGoals  int  `json:"home_goals" openapi:"required,minimum=0"`
+	AwayGoals  int  `json:"away_goals" openapi:"required,minimum=0"`
+}
+
+// Apply returns a copy of the matches with the scenario results filled in
+func (s *Scenario) Apply(matches []Match) []Match {
+	overrides := make(map[uint]ScenarioOverride, len(s.Overrides))
+	for _, override := range s.Overrides {
+		overrides[override.MatchID] = override
+	}
+
+	result := make([]Match, len(matches))
+	for i, match := range matches {
+		if override, ok := overrides[match.ID]; ok {
+			match.UpdateResult(override.HomeGoals, override.AwayGoals)
+		}
+		result[i] = match
+	}
+	return result
+}
+
+// MatchDiff compares the real result of a match with the scenario result,
+// the real goals are nil when the match has not been played
+type MatchDiff struct {
+	MatchID           uint   `json:"match_id"`
+	Week              int    `json:"week"`
+	HomeTeam          string `json:"home_team"`
+	AwayTeam          string `json:"away_team"`
+	ActualHomeGoals   *int   `json:"actual_home_goals"`
+	ActualAwayGoals   *int   `json:"actual_away_goals"`
+	ScenarioHomeGoals int    `json:"scenario_home_goals"`
+	ScenarioAwayGoals int    `json:"scenario_away_goals"`
+}
+
+// TableDiff compares the position and points of a team in reality and in the scenario
+type TableDiff struct {
+	TeamID           uint   `json:"team_id"`
+	TeamName         string `json:"team_name"`
+	ActualPosition   int    `json:"actual_position"`
+	ScenarioPosition int    `json:"scenario_position"`
+	PositionChange   int    `json:"position_change"` // positive means higher up the table
+	ActualPoints     int    `json:"actual_points"`
+	ScenarioPoints   int    `json:"scenario_points"`
+	PointsChange     int    `json:"points_change"`
+}
+
+// ScenarioDiff lists everything a scenario changes compared to reality
+type ScenarioDiff struct {
+	ScenarioID uint        `json:"scenario_id"`
+	Matches    []MatchDiff `json:"matches"`
+	Table      []TableDiff `json:"table"`
+}
+
+// Diff compares the scenario with the real matches, both tables with the
+// points deductions of the season
+func (s *Scenario) Diff(teams []Team, matches []Match, deductions map[uint]int) *ScenarioDiff {
+	diff := &ScenarioDiff{
+		ScenarioID: s.ID,
+		Matches:    make([]MatchDiff, 0, len(s.Overrides)),
+	}
+
+	matchesByID := make(map[uint]Match, len(matches))
+	for _, match := range matches {
+		matchesByID[match.ID] = match
+	}
+	for _, override := range s.Overrides {
+		match, ok := matchesByID[override.MatchID]
+		if !ok {
+			continue
+		}
+		matchDiff := MatchDiff{
+			MatchID:           match.ID,
+			Week:              match.Week,
+			HomeTeam:          match.HomeTeam.Name,
+			AwayTeam:          match.AwayTeam.Name,
+			ScenarioHomeGoals: override.HomeGoals,
+			ScenarioAwayGoals: override.AwayGoals,
+		}
+		if match.Played {
+			homeGoals, awayGoals := match.HomeGoals, match.AwayGoals
+			matchDiff.ActualHomeGoals = &homeGoals
+			matchDiff.ActualAwayGoals = &awayGoals
+		}
+		diff.Matches = append(diff.Matches, matchDiff)
+	}
+
+	actual := NewTable(teams, matches)
+	ApplyDeductions(actual, deductions)
+	actualPositions := make(map[uint]int, len(actual))
+	for i, row := range actual {
+		actualPositions[row.TeamID] = i
+	}
+
+	scenarioTable := NewTable(teams, s.Apply(matches))
+	ApplyDeductions(scenarioTable, deductions)
+	for position, row := range scenarioTable {
+		actualRow := actual[actualPositions[row.TeamID]]
+		diff.Table = append(diff.Table, TableDiff{
+			TeamID:           row.TeamID,
+			TeamName:         row.TeamName,
+			ActualPosition:   actualPositions[row.TeamID] + 1,
+			ScenarioPosition: position + 1,
+			PositionChange:   actualPositions[row.TeamID] - position,
+			ActualPoints:     actualRow.Points,
+			ScenarioPoints:   row.Points,
+			PointsChange:     row.Points - actualRow.Points,
+		})
+	}
+
+	return diff
+}
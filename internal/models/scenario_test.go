@@ -0,0 +1,71 @@
+package models
+
+import "testing"
+
+func TestScenarioApplyAndDiff(t *testing.T) {
+	teams := []Team{{ID: 1, Name: "Ash"}, {ID: 2, Name: "Birch"}, {ID: 3, Name: "Cedar"}}
+	match := func(id uint, week int, home, away Team) Match {
+		return Match{ID: id, Week: week, HomeTeamID: home.ID, AwayTeamID: away.ID, HomeTeam: home, AwayTeam: away}
+	}
+	matches := []Match{
+		match(1, 1, teams[0], teams[1]),
+		match(2, 1, teams[2], teams[0]),
+		match(3, 2, teams[1], teams[2]),
+	}
+	matches[0].UpdateResult(1, 0)
+	matches[2].UpdateResult(2, 2)
+
+	scenario := &Scenario{ID: 9, Overrides: []ScenarioOverride{
+		{MatchID: 1, HomeGoals: 0, AwayGoals: 3},  // a played match gets another result
+		{MatchID: 2, HomeGoals: 2, AwayGoals: 0},  // an unplayed match gets a result
+		{MatchID: 99, HomeGoals: 1, AwayGoals: 0}, // a match of no concern is ignored
+	}}
+
+	applied := scenario.Apply(matches)
+	if len(applied) != len(matches) {
+		t.Fatalf("got %d matches, want %d", len(applied), len(matches))
+	}
+	want := []struct {
+		played               bool
+		homeGoals, awayGoals int
+	}{{true, 0, 3}, {true, 2, 0}, {true, 2, 2}}
+	for i, w := range want {
+		if applied[i].Played != w.played || applied[i].HomeGoals != w.homeGoals || applied[i].AwayGoals != w.awayGoals {
+			t.Errorf("match %d is %d-%d played %v, want %d-%d", applied[i].ID, applied[i].HomeGoals, applied[i].AwayGoals, applied[i].Played, w.homeGoals, w.awayGoals)
+		}
+	}
+	if matches[0].HomeGoals != 1 || matches[1].Played {
+		t.Error("Apply changed the real matches")
+	}
+
+	// Cedar has a point deducted in reality and in the scenario
+	diff := scenario.Diff(teams, matches, map[uint]int{3: 1})
+	if diff.ScenarioID != 9 || len(diff.Matches) != 2 {
+		t.Fatalf("got %d match diffs for scenario %d, want 2 for scenario 9", len(diff.Matches), diff.ScenarioID)
+	}
+	first, second := diff.Matches[0], diff.Matches[1]
+	if first.HomeTeam != "Ash" || first.ActualHomeGoals == nil || *first.ActualHomeGoals != 1 || *first.ActualAwayGoals != 0 ||
+		first.ScenarioHomeGoals != 0 || first.ScenarioAwayGoals != 3 {
+		t.Errorf("got diff %+v for match 1, want 1-0 in reality and 0-3 in the scenario", first)
+	}
+	if second.ActualHomeGoals != nil || second.ActualAwayGoals != nil || second.ScenarioHomeGoals != 2 {
+		t.Errorf("got diff %+v for match 2, want no real result and 2-0 in the scenario", second)
+	}
+
+	// Reality: Ash 3, Birch 1, Cedar 0. Scenario: Birch 4, Cedar 3, Ash 0.
+	tableWant := []TableDiff{
+		{TeamName: "Birch", ActualPosition: 2, ScenarioPosition: 1, PositionChange: 1, ActualPoints: 1, ScenarioPoints: 4, PointsChange: 3},
+		{TeamName: "Cedar", ActualPosition: 3, ScenarioPosition: 2, PositionChange: 1, ActualPoints: 0, ScenarioPoints: 3, PointsChange: 3},
+		{TeamName: "Ash", ActualPosition: 1, ScenarioPosition: 3, PositionChange: -2, ActualPoints: 3, ScenarioPoints: 0, PointsChange: -3},
+	}
+	if len(diff.Table) != len(tableWant) {
+		t.Fatalf("got %d table rows, want %d", len(diff.Table), len(tableWant))
+	}
+	for i, w := range tableWant {
+		got := diff.Table[i]
+		got.TeamID = 0
+		if got != w {
+			t.Errorf("row %d is %+v, want %+v", i+1, got, w)
+		}
+	}
+}
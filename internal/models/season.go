@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+)
+
+// Season groups the matches of one league season, the season with the
+// highest ID is the current one
+type Season struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// NewSeason creates a new season instance
+func NewSeason(name string) *Season {
+	return &Season{Name: name}
+}
@@ -0,0 +1,229 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Table views
+const (
+	ViewOverall = "overall"
+	ViewHome    = "home"
+	ViewAway    = "away"
+	ViewForm    = "form"
+)
+
+// DefaultFormMatches is the number of matches in a form table and form string
+const DefaultFormMatches = 5
+
+// NewTable calculates the league table from the played matches. Teams are
+// ranked by points, then goal difference, then goals scored.
+func NewTable(teams []Team, matches []Match) []TeamStats {
+	stats := make([]TeamStats, len(teams))
+	index := make(map[uint]int, len(teams))
+	for i, team := range teams {
+		stats[i] = TeamStats{
+			TeamID:   team.ID,
+			TeamName: team.Name,
+		}
+		index[team.ID] = i
+	}
+
+	for _, match := range matches {
+		if !match.Played {
+			continue
+		}
+		homeIdx, homeOk := index[match.HomeTeamID]
+		awayIdx, awayOk := index[match.AwayTeamID]
+		if homeOk {
+			stats[homeIdx].addResult(match.HomeGoals, match.AwayGoals)
+		}
+		if awayOk {
+			stats[awayIdx].addResult(match.AwayGoals, match.HomeGoals)
+		}
+	}
+
+	SortTable(stats)
+	return stats
+}
+
+// NewTableView calculates the overall, home-only, away-only or form table
+// with the same tiebreakers as NewTable. The form table only counts the last
+// n played matches of each team. Every row gets a form string such as "WWDLW"
+// of the team's last n results home and away, whatever the view, with the
+// most recent result last.
+func NewTableView(teams []Team, matches []Match, view string, n int) ([]TeamStats, error) {
+	if view != ViewOverall && view != ViewHome && view != ViewAway && view != ViewForm {
+		return nil, fmt.Errorf("unknown table view %q", view)
+	}
+	if n < 1 {
+		return nil, fmt.Errorf("invalid number of form matches %d", n)
+	}
+
+	// Results of every team in the order they were played
+	type result struct {
+		goalsFor, goalsAgainst int
+	}
+	all := make(map[uint][]result, len(teams))
+	results := make(map[uint][]result, len(teams))
+	for _, match := range SortMatchesByDate(matches) {
+		if !match.Played {
+			continue
+		}
+		home := result{match.HomeGoals, match.AwayGoals}
+		away := result{match.AwayGoals, match.HomeGoals}
+		all[match.HomeTeamID] = append(all[match.HomeTeamID], home)
+		all[match.AwayTeamID] = append(all[match.AwayTeamID], away)
+		if view != ViewAway {
+			results[match.HomeTeamID] = append(results[match.HomeTeamID], home)
+		}
+		if view != ViewHome {
+			results[match.AwayTeamID] = append(results[match.AwayTeamID], away)
+		}
+	}
+
+	stats := make([]TeamStats, len(teams))
+	for i, team := range teams {
+		stats[i] = TeamStats{
+			TeamID:   team.ID,
+			TeamName: team.Name,
+		}
+
+		teamResults := results[team.ID]
+		recent := all[team.ID][max(0, len(all[team.ID])-n):]
+		if view == ViewForm {
+			teamResults = recent
+		}
+		for _, r := range teamResults {
+			stats[i].addResult(r.goalsFor, r.goalsAgainst)
+		}
+		for _, r := range recent {
+			stats[i].Form += resultLetter(r.goalsFor, r.goalsAgainst)
+		}
+	}
+
+	SortTable(stats)
+	return stats, nil
+}
+
+// resultLetter returns W, D or L for a result seen from the team's perspective
+func resultLetter(goalsFor, goalsAgainst int) string {
+	switch {
+	case goalsFor > goalsAgainst:
+		return "W"
+	case goalsFor < goalsAgainst:
+		return "L"
+	default:
+		return "D"
+	}
+}
+
+// SortMatchesByDate returns a copy of the matches in the order they were
+// played: by season, then week, then date, then ID
+func SortMatchesByDate(matches []Match) []Match {
+	sorted := append([]Match(nil), matches...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].SeasonID != sorted[j].SeasonID {
+			return sorted[i].SeasonID < sorted[j].SeasonID
+		}
+		if sorted[i].Week != sorted[j].Week {
+			return sorted[i].Week < sorted[j].Week
+		}
+		if !sorted[i].Date.Equal(sorted[j].Date) {
+			return sorted[i].Date.Before(sorted[j].Date)
+		}
+		return sorted[i].ID < sorted[j].ID
+	})
+	return sorted
+}
+
+// NewTableAfterWeek calculates the league table as it stood after the given week
+func NewTableAfterWeek(teams []Team, matches []Match, week int) []TeamStats {
+	var played []Match
+	for _, match := range matches {
+		if match.Week <= week {
+			played = append(played, match)
+		}
+	}
+	return NewTable(teams, played)
+}
+
+// WeekPosition is a team's place in the table after a week
+type WeekPosition struct {
+	Week           int `json:"week"`
+	Position       int `json:"position"`
+	Played         int `json:"played"`
+	Points         int `json:"points"`
+	GoalDifference int `json:"goal_difference"`
+}
+
+// PositionHistory returns the position, points and goal difference of a team
+// after every week up to the last week with a played match. Points
+// deductions count from the week they were made in, see DeductionsAfterWeek.
+func PositionHistory(teams []Team, matches []Match, events []LeagueEvent, teamID uint) []WeekPosition {
+	lastWeek := 0
+	for _, match := range matches {
+		if match.Played && match.Week > lastWeek {
+			lastWeek = match.Week
+		}
+	}
+
+	history := make([]WeekPosition, 0, lastWeek)
+	for week := 1; week <= lastWeek; week++ {
+		table := NewTableAfterWeek(teams, matches, week)
+		ApplyDeductions(table, DeductionsAfterWeek(events, week))
+		for _, row := range table {
+			if row.TeamID != teamID {
+				continue
+			}
+			history = append(history, WeekPosition{
+				Week:           week,
+				Position:       row.Position,
+				Played:         row.Played,
+				Points:         row.Points,
+				GoalDifference: row.GoalDifference,
+			})
+		}
+	}
+	return history
+}
+
+// addResult adds a single match result seen from the team's perspective
+func (s *TeamStats) addResult(goalsFor, goalsAgainst int) {
+	s.Played++
+	s.GoalsFor += goalsFor
+	s.GoalsAgainst += goalsAgainst
+	s.GoalDifference = s.GoalsFor - s.GoalsAgainst
+
+	switch {
+	case goalsFor > goalsAgainst:
+		s.Won++
+		s.Points += 3
+	case goalsFor < goalsAgainst:
+		s.Lost++
+	default:
+		s.Drawn++
+		s.Points++
+	}
+}
+
+// SortTable sorts by points, then goal difference, then goals scored and
+// numbers the positions
+func SortTable(stats []TeamStats) {
+	sort.SliceStable(stats, func(i, j int) bool {
+		if stats[i].Points != stats[j].Points {
+			return stats[i].Points > stats[j].Points
+		}
+		if stats[i].GoalDifference != stats[j].GoalDifference {
+			return stats[i].GoalDifference > stats[j].GoalDifference
+		}
+		if stats[i].GoalsFor != stats[j].GoalsFor {
+			return stats[i].GoalsFor > stats[j].GoalsFor
+		}
+		return stats[i].TeamName < stats[j].TeamName
+	})
+
+	for i := range stats {
+		stats[i].Position = i + 1
+	}
+}
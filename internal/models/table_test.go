@@ -0,0 +1,103 @@
+package models
+
+import "testing"
+
+func TestNewTableView(t *testing.T) {
+	teams := []Team{
+		{ID: 1, Name: "Ash"},
+		{ID: 2, Name: "Birch"},
+		{ID: 3, Name: "Cedar"},
+		{ID: 4, Name: "Dale"},
+		{ID: 5, Name: "Alder"},
+	}
+	result := func(week int, home, away uint, homeGoals, awayGoals int) Match {
+		return Match{ID: uint(week), Week: week, HomeTeamID: home, AwayTeamID: away, HomeGoals: homeGoals, AwayGoals: awayGoals, Played: true}
+	}
+	// Listed out of order, the views go by week
+	matches := []Match{
+		result(4, 2, 1, 0, 1),
+		result(1, 1, 2, 2, 0),
+		result(2, 3, 1, 1, 1),
+		result(3, 2, 3, 3, 1),
+		result(6, 3, 2, 2, 2),
+		result(5, 1, 3, 0, 2),
+		{ID: 7, Week: 7, HomeTeamID: 4, AwayTeamID: 5},
+	}
+
+	type row struct {
+		team   string
+		points int
+		form   string
+	}
+	tests := []struct {
+		view string
+		n    int
+		want []row
+	}{
+		{ViewOverall, DefaultFormMatches, []row{
+			{"Ash", 7, "WDWL"}, {"Cedar", 5, "DLWD"}, {"Birch", 4, "LWLD"}, {"Alder", 0, ""}, {"Dale", 0, ""},
+		}},
+		// Birch and Ash both have 3 home points, Birch the better goal difference
+		{ViewHome, DefaultFormMatches, []row{
+			{"Birch", 3, "LWLD"}, {"Ash", 3, "WDWL"}, {"Cedar", 2, "DLWD"}, {"Alder", 0, ""}, {"Dale", 0, ""},
+		}},
+		{ViewAway, DefaultFormMatches, []row{
+			{"Ash", 4, "WDWL"}, {"Cedar", 3, "DLWD"}, {"Birch", 1, "LWLD"}, {"Alder", 0, ""}, {"Dale", 0, ""},
+		}},
+		// The last two matches: Cedar won and drew, Ash won and lost
+		{ViewForm, 2, []row{
+			{"Cedar", 4, "WD"}, {"Ash", 3, "WL"}, {"Birch", 1, "LD"}, {"Alder", 0, ""}, {"Dale", 0, ""},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.view, func(t *testing.T) {
+			table, err := NewTableView(teams, matches, tt.view, tt.n)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(table) != len(tt.want) {
+				t.Fatalf("got %d rows, want %d", len(table), len(tt.want))
+			}
+			for i, want := range tt.want {
+				got := table[i]
+				if got.TeamName != want.team || got.Points != want.points || got.Form != want.form || got.Position != i+1 {
+					t.Errorf("position %d is %s with %d points and form %q, want %s with %d and %q",
+						i+1, got.TeamName, got.Points, got.Form, want.team, want.points, want.form)
+				}
+			}
+		})
+	}
+
+	// Home and away split every played match between them
+	home, _ := NewTableView(teams, matches, ViewHome, DefaultFormMatches)
+	away, _ := NewTableView(teams, matches, ViewAway, DefaultFormMatches)
+	overall := NewTable(teams, matches)
+	played := func(table []TeamStats) int {
+		total := 0
+		for _, row := range table {
+			total += row.Played
+		}
+		return total
+	}
+	if played(home)+played(away) != played(overall) {
+		t.Errorf("home and away count %d and %d matches, overall %d", played(home), played(away), played(overall))
+	}
+
+	// Both won away by one goal, Pine scored more
+	pair := []Team{{ID: 1, Name: "Oak"}, {ID: 2, Name: "Pine"}}
+	awayWins := []Match{result(1, 2, 1, 0, 1), result(2, 1, 2, 1, 2)}
+	table, err := NewTableView(pair, awayWins, ViewAway, DefaultFormMatches)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if table[0].TeamName != "Pine" || table[0].Points != table[1].Points || table[0].GoalDifference != table[1].GoalDifference {
+		t.Errorf("got away table %+v, want Pine ahead on goals scored", table)
+	}
+
+	if _, err := NewTableView(teams, matches, "sideways", DefaultFormMatches); err == nil {
+		t.Error("got no error for an unknown view")
+	}
+	if _, err := NewTableView(teams, matches, ViewForm, 0); err == nil {
+		t.Error("got no error for a form of 0 matches")
+	}
+}
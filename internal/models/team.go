@@ -9,12 +9,15 @@ type Team struct {
 	ID        uint      `json:"id" gorm:"primaryKey"`
 	Name      string    `json:"name"`
 	Strength  int       `json:"strength"` // 1-100 scale for team strength
+	Attack    float64   `json:"attack"`   // fitted log attack rating
+	Defence   float64   `json:"defence"`  // fitted log defence rating
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
 // TeamStats represents the statistics for a team in the league
 type TeamStats struct {
+	Position       int    `json:"position" gorm:"-"`
 	TeamID         uint   `json:"team_id" gorm:"primaryKey"`
 	TeamName       string `json:"team_name"`
 	Played         int    `json:"played"`
@@ -25,6 +28,12 @@ type TeamStats struct {
 	GoalsAgainst   int    `json:"goals_against"`
 	GoalDifference int    `json:"goal_difference"`
 	Points         int    `json:"points"`
+	Form           string `json:"form,omitempty" gorm:"-"` // most recent result last
+
+	// Clinch and elimination status, see ApplyClinchStatus
+	Title     *TargetStatus `json:"title,omitempty" gorm:"-"`
+	TopPlaces *TargetStatus `json:"top_places,omitempty" gorm:"-"`
+	Safety    *TargetStatus `json:"safety,omitempty" gorm:"-"`
 }
 
 // NewTeam creates a new team instance
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"sort"
+)
+
+// WeekSummary reports how many matches of a week have been played
+type WeekSummary struct {
+	Week     int  `json:"week"`
+	Matches  int  `json:"matches"`
+	Played   int  `json:"played"`
+	Complete bool `json:"complete"` // every match of the week has been played
+}
+
+// NewWeekSummary summarizes the matches of a single week
+func NewWeekSummary(week int, matches []Match) WeekSummary {
+	summary := WeekSummary{Week: week, Matches: len(matches)}
+	for _, match := range matches {
+		if match.Played {
+			summary.Played++
+		}
+	}
+	summary.Complete = summary.Matches > 0 && summary.Played == summary.Matches
+	return summary
+}
+
+// WeekSummaries summarizes every week that has matches, ordered by week
+func WeekSummaries(matches []Match) []WeekSummary {
+	byWeek := make(map[int][]Match)
+	for _, match := range matches {
+		byWeek[match.Week] = append(byWeek[match.Week], match)
+	}
+
+	summaries := make([]WeekSummary, 0, len(byWeek))
+	for week, weekMatches := range byWeek {
+		summaries = append(summaries, NewWeekSummary(week, weekMatches))
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].Week < summaries[j].Week })
+	return summaries
+}
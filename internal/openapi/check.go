@@ -0,0 +1,117 @@
+package openapi
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// pathVariable matches a variable of a path template such as {id} or {id:[0-9]+}
+var pathVariable = regexp.MustCompile(`\{([^}:]+)(:[^}]*)?\}`)
+
+// CheckRouter compares the routes registered on a router with the
+// operations of the document and describes every difference
+func (d *Document) CheckRouter(router *mux.Router) []string {
+	var problems []string
+	registered := make(map[string]bool)
+	err := router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		template, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s is registered without a method", template))
+			return nil
+		}
+
+		// The document uses plain {name} variables
+		path := pathVariable.ReplaceAllString(template, "{$1}")
+		for _, method := range methods {
+			registered[method+" "+path] = true
+			op := d.Operation(method, path)
+			if op == nil {
+				problems = append(problems, fmt.Sprintf("%s %s is registered but not in the specification", method, path))
+				continue
+			}
+			problems = append(problems, checkPathParameters(method, path, op)...)
+		}
+		return nil
+	})
+	if err != nil {
+		problems = append(problems, err.Error())
+	}
+
+	for path, item := range d.Paths {
+		for _, method := range item.Methods() {
+			if !registered[method+" "+path] {
+				problems = append(problems, fmt.Sprintf("%s %s is in the specification but not registered", method, path))
+			}
+		}
+	}
+	sort.Strings(problems)
+	return problems
+}
+
+// checkPathParameters compares the variables of a path with the path
+// parameters of its operation
+func checkPathParameters(method, path string, op *Operation) []string {
+	var problems []string
+	variables := make(map[string]bool)
+	for _, match := range pathVariable.FindAllStringSubmatch(path, -1) {
+		variables[match[1]] = true
+	}
+	for _, param := range op.Parameters {
+		if param.In != InPath {
+			continue
+		}
+		if !variables[param.Name] {
+			problems = append(problems, fmt.Sprintf("%s %s documents the path parameter %s that is not in the path", method, path, param.Name))
+		}
+		delete(variables, param.Name)
+	}
+	for variable := range variables {
+		problems = append(problems, fmt.Sprintf("%s %s does not document the path parameter %s", method, path, variable))
+	}
+	return problems
+}
+
+// CheckText describes every operation of the document that a text, such as
+// the README, does not mention as "METHOD /path"
+func (d *Document) CheckText(text string) []string {
+	var problems []string
+	for path, item := range d.Paths {
+		for _, method := range item.Methods() {
+			if !mentions(text, method+" "+path) {
+				problems = append(problems, fmt.Sprintf("%s %s is not documented", method, path))
+			}
+		}
+	}
+	sort.Strings(problems)
+	return problems
+}
+
+// mentions reports whether text contains an endpoint that is not just the
+// start of a longer path
+func mentions(text, endpoint string) bool {
+	for i := strings.Index(text, endpoint); i >= 0; {
+		rest := text[i+len(endpoint):]
+		if rest == "" || !strings.ContainsRune("/{}._-", rune(rest[0])) && !isAlphanumeric(rest[0]) {
+			return true
+		}
+		next := strings.Index(rest, endpoint)
+		if next < 0 {
+			return false
+		}
+		i += len(endpoint) + next
+	}
+	return false
+}
+
+// isAlphanumeric reports whether c is an ASCII letter or digit
+func isAlphanumeric(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9'
+}
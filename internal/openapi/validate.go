@@ -0,0 +1,208 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Problem is a request value that does not match the document
+type Problem struct {
+	Field   string
+	Message string
+}
+
+// ValidateParameters checks the path variables and query parameters of a
+// request against the parameters of an operation. Unknown query parameters
+// are ignored.
+func (d *Document) ValidateParameters(op *Operation, vars map[string]string, query url.Values) []Problem {
+	var problems []Problem
+	for _, param := range op.Parameters {
+		value := vars[param.Name]
+		if param.In == InQuery {
+			value = query.Get(param.Name)
+		}
+		if value == "" {
+			if param.Required {
+				problems = append(problems, Problem{Field: param.Name, Message: "is required"})
+			}
+			continue
+		}
+		if message := checkParameter(d.Resolve(param.Schema), value); message != "" {
+			problems = append(problems, Problem{Field: param.Name, Message: message})
+		}
+	}
+	return problems
+}
+
+// checkParameter checks a parameter value against a schema and returns
+// what is wrong with it
+func checkParameter(schema *Schema, value string) string {
+	switch schema.Type {
+	case "integer":
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return "must be an integer"
+		}
+		return checkRange(schema, float64(n))
+	case "number":
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil || math.IsNaN(n) || math.IsInf(n, 0) {
+			return "must be a number"
+		}
+		return checkRange(schema, n)
+	case "boolean":
+		if _, err := strconv.ParseBool(value); err != nil {
+			return "must be true or false"
+		}
+	case "string":
+		return checkString(schema, value)
+	}
+	return ""
+}
+
+// checkRange checks the minimum and maximum of a number
+func checkRange(schema *Schema, n float64) string {
+	switch {
+	case schema.Minimum != nil && n < *schema.Minimum && *schema.Minimum == 0:
+		return "must not be negative"
+	case schema.Minimum != nil && n < *schema.Minimum:
+		return fmt.Sprintf("must be at least %v", *schema.Minimum)
+	case schema.Maximum != nil && n > *schema.Maximum:
+		return fmt.Sprintf("must be at most %v", *schema.Maximum)
+	}
+	return ""
+}
+
+// checkString checks the enum and format of a string
+func checkString(schema *Schema, value string) string {
+	if len(schema.Enum) > 0 && !slices.Contains(schema.Enum, value) {
+		return "must be one of " + strings.Join(schema.Enum, ", ")
+	}
+	if schema.Format == "date-time" {
+		if _, err := time.Parse(time.RFC3339, value); err != nil {
+			return "must be an RFC 3339 time"
+		}
+	}
+	return ""
+}
+
+// ValidateJSON checks a JSON document against a schema. It returns nil
+// without checking anything when data is not valid JSON, decoding reports
+// that.
+func (d *Document) ValidateJSON(schema *Schema, data []byte) []Problem {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if decoder.Decode(&value) != nil {
+		return nil
+	}
+	var problems []Problem
+	d.validateValue(schema, value, "", &problems)
+	return problems
+}
+
+// validateValue checks a decoded value and appends its problems, field is
+// the path of the value such as overrides[0].match_id
+func (d *Document) validateValue(schema *Schema, value interface{}, field string, problems *[]Problem) {
+	schema = d.Resolve(schema)
+	if schema == nil || schema.Type == "" {
+		return
+	}
+	report := func(message string) {
+		*problems = append(*problems, Problem{Field: field, Message: message})
+	}
+	if value == nil {
+		if !schema.Nullable {
+			report("must not be null")
+		}
+		return
+	}
+
+	switch schema.Type {
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			report("must be an object")
+			return
+		}
+		for _, name := range schema.Required {
+			if _, ok := object[name]; !ok {
+				*problems = append(*problems, Problem{Field: join(field, name), Message: "is required"})
+			}
+		}
+		names := make([]string, 0, len(object))
+		for name := range object {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			property, known := schema.Properties[name]
+			if !known {
+				property, known = schema.AdditionalProperties.(*Schema)
+			}
+			if !known {
+				if schema.AdditionalProperties == false {
+					*problems = append(*problems, Problem{Field: join(field, name), Message: "is not a known field"})
+				}
+				continue
+			}
+			d.validateValue(property, object[name], join(field, name), problems)
+		}
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			report("must be an array")
+			return
+		}
+		for i, item := range items {
+			d.validateValue(schema.Items, item, fmt.Sprintf("%s[%d]", field, i), problems)
+		}
+	case "integer", "number":
+		number, ok := value.(json.Number)
+		if !ok {
+			report("must be a number")
+			return
+		}
+		n, err := number.Float64()
+		if err != nil {
+			report("must be a number")
+			return
+		}
+		if schema.Type == "integer" && n != math.Trunc(n) {
+			report("must be an integer")
+			return
+		}
+		if message := checkRange(schema, n); message != "" {
+			report(message)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			report("must be true or false")
+		}
+	case "string":
+		text, ok := value.(string)
+		if !ok {
+			report("must be a string")
+			return
+		}
+		if message := checkString(schema, text); message != "" {
+			report(message)
+		}
+	}
+}
+
+// join appends a property name to a field path
+func join(field, name string) string {
+	if field == "" {
+		return name
+	}
+	return field + "." + name
+}
@@ -0,0 +1,378 @@
+package ratings
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/cahitcaginkaratas/backend_insider/internal/models"
+)
+
+// DefaultXi is the time decay per day, a match half a year old counts roughly 70%
+const DefaultXi = 0.0019
+
+// Options controls the maximum likelihood fit
+type Options struct {
+	Xi            float64   // time decay per day, 0 weighs all matches equally
+	ReferenceDate time.Time // matches are weighted by age relative to this date, zero means the latest match
+	MaxIterations int
+	Tolerance     float64
+}
+
+// DefaultOptions returns the options used when nothing is configured
+func DefaultOptions() Options {
+	return Options{
+		Xi:            DefaultXi,
+		MaxIterations: 500,
+		Tolerance:     1e-9,
+	}
+}
+
+// Result holds the fitted league parameters and the ratings of every team
+type Result struct {
+	Fit   models.RatingFit    `json:"fit"`
+	Teams []models.TeamRating `json:"teams"`
+}
+
+// Engine returns the Poisson engine described by the fit
+func (r *Result) Engine() models.PoissonEngine {
+	return models.NewPoissonEngine(&r.Fit)
+}
+
+// Prior returns the rating of an average team of the fit, for teams that have
+// no matches in it. Attack ratings are centred on zero, so the average team
+// has no attack rating and the mean defence rating.
+func (r *Result) Prior() models.TeamRating {
+	var prior models.TeamRating
+	for _, team := range r.Teams {
+		prior.Defence += team.Defence
+	}
+	if len(r.Teams) > 0 {
+		prior.Defence /= float64(len(r.Teams))
+	}
+	return prior
+}
+
+// fitMatch is an observation with team indexes and its time decay weight
+type fitMatch struct {
+	home, away           int
+	homeGoals, awayGoals int
+	weight               float64
+}
+
+// Fit estimates attack and defence ratings, home advantage and the Dixon-Coles
+// low-score correction by maximising the time weighted likelihood.
+//
+// The scoring rates are log(homeRate) = home + attack[home] - defence[away]
+// and log(awayRate) = attack[away] - defence[home], attack ratings are
+// centred on zero so defence ratings carry the league scoring level.
+func Fit(observations []Observation, opts Options) (*Result, error) {
+	if len(observations) == 0 {
+		return nil, errors.New("no played matches to fit")
+	}
+	if opts.MaxIterations <= 0 {
+		opts.MaxIterations = DefaultOptions().MaxIterations
+	}
+	if opts.Tolerance <= 0 {
+		opts.Tolerance = DefaultOptions().Tolerance
+	}
+
+	reference := opts.ReferenceDate
+	if reference.IsZero() {
+		for _, observation := range observations {
+			if observation.Date.After(reference) {
+				reference = observation.Date
+			}
+		}
+	}
+
+	// Index teams by name in alphabetical order
+	teamIndex := make(map[string]int)
+	var names []string
+	for _, observation := range observations {
+		for _, name := range []string{observation.HomeTeam, observation.AwayTeam} {
+			if _, ok := teamIndex[name]; !ok {
+				teamIndex[name] = 0
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	for i, name := range names {
+		teamIndex[name] = i
+	}
+	if len(names) < 2 {
+		return nil, errors.New("at least two teams are needed to fit ratings")
+	}
+
+	matchCounts := make([]int, len(names))
+	matches := make([]fitMatch, 0, len(observations))
+	for _, observation := range observations {
+		days := reference.Sub(observation.Date).Hours() / 24
+		if days < 0 {
+			days = 0
+		}
+		match := fitMatch{
+			home:      teamIndex[observation.HomeTeam],
+			away:      teamIndex[observation.AwayTeam],
+			homeGoals: observation.HomeGoals,
+			awayGoals: observation.AwayGoals,
+			weight:    math.Exp(-opts.Xi * days),
+		}
+		matchCounts[match.home]++
+		matchCounts[match.away]++
+		matches = append(matches, match)
+	}
+
+	problem := &likelihood{teams: len(names), matches: matches}
+	params, iterations, converged := minimize(problem, problem.start(), opts.MaxIterations, opts.Tolerance)
+
+	// Centre attack ratings on zero, the shift cancels out in every scoring rate
+	n := len(names)
+	mean := 0.0
+	for i := 0; i < n; i++ {
+		mean += params[i]
+	}
+	mean /= float64(n)
+	for i := 0; i < n; i++ {
+		params[i] -= mean
+		params[n+i] -= mean
+	}
+
+	result := &Result{
+		Fit: models.RatingFit{
+			HomeAdvantage: params[2*n],
+			Rho:           params[2*n+1],
+			Xi:            opts.Xi,
+			LogLikelihood: problem.logLikelihood(params),
+			Matches:       len(matches),
+			Iterations:    iterations,
+			Converged:     converged,
+			ReferenceDate: reference,
+		},
+		Teams: make([]models.TeamRating, n),
+	}
+	for i, name := range names {
+		result.Teams[i] = models.TeamRating{
+			Name:    name,
+			Matches: matchCounts[i],
+			Attack:  params[i],
+			Defence: params[n+i],
+		}
+	}
+
+	return result, nil
+}
+
+// likelihood is the weighted Dixon-Coles log-likelihood over the parameter
+// vector [attack..., defence..., home advantage, rho]
+type likelihood struct {
+	teams   int
+	matches []fitMatch
+}
+
+// start returns the initial parameter vector
+func (l *likelihood) start() []float64 {
+	params := make([]float64, 2*l.teams+2)
+	params[2*l.teams] = 0.25
+	return params
+}
+
+// rates returns the scoring rates of a match for the given parameters
+func (l *likelihood) rates(params []float64, match fitMatch) (homeRate, awayRate float64) {
+	n := l.teams
+	homeRate = math.Exp(params[2*n] + params[match.home] - params[n+match.away])
+	awayRate = math.Exp(params[match.away] - params[n+match.home])
+	return homeRate, awayRate
+}
+
+// logLikelihood returns the full weighted log-likelihood including constant terms
+func (l *likelihood) logLikelihood(params []float64) float64 {
+	value, _ := l.evaluate(params, false)
+	for _, match := range l.matches {
+		homeFactorial, _ := math.Lgamma(float64(match.homeGoals + 1))
+		awayFactorial, _ := math.Lgamma(float64(match.awayGoals + 1))
+		value -= match.weight * (homeFactorial + awayFactorial)
+	}
+	return value
+}
+
+// evaluate returns the weighted log-likelihood without the factorial terms
+// and optionally its gradient, a rho outside the valid range yields -Inf
+func (l *likelihood) evaluate(params []float64, withGradient bool) (float64, []float64) {
+	n := l.teams
+	rho := params[2*n+1]
+
+	var gradient []float64
+	if withGradient {
+		gradient = make([]float64, len(params))
+	}
+
+	value := 0.0
+	for _, match := range l.matches {
+		homeRate, awayRate := l.rates(params, match)
+
+		// Rho has to keep every low-score correction of the match positive,
+		// not only the one of the observed score
+		if rho > math.Min(1/(homeRate*awayRate), 1) || rho < math.Max(-1/homeRate, -1/awayRate) {
+			return math.Inf(-1), gradient
+		}
+		tau := models.DixonColesTau(match.homeGoals, match.awayGoals, homeRate, awayRate, rho)
+		if tau <= 0 {
+			return math.Inf(-1), gradient
+		}
+
+		x, y := float64(match.homeGoals), float64(match.awayGoals)
+		value += match.weight * (math.Log(tau) + x*math.Log(homeRate) - homeRate + y*math.Log(awayRate) - awayRate)
+
+		if !withGradient {
+			continue
+		}
+
+		// Derivatives of log(tau) with respect to the two rates and rho
+		var dTauHome, dTauAway, dTauRho float64
+		switch {
+		case match.homeGoals == 0 && match.awayGoals == 0:
+			dTauHome, dTauAway, dTauRho = -awayRate*rho, -homeRate*rho, -homeRate*awayRate
+		case match.homeGoals == 0 && match.awayGoals == 1:
+			dTauHome, dTauRho = rho, homeRate
+		case match.homeGoals == 1 && match.awayGoals == 0:
+			dTauAway, dTauRho = rho, awayRate
+		case match.homeGoals == 1 && match.awayGoals == 1:
+			dTauRho = -1
+		}
+
+		dLogHome := match.weight * (x - homeRate + homeRate*dTauHome/tau)
+		dLogAway := match.weight * (y - awayRate + awayRate*dTauAway/tau)
+
+		gradient[match.home] += dLogHome
+		gradient[n+match.away] -= dLogHome
+		gradient[2*n] += dLogHome
+		gradient[match.away] += dLogAway
+		gradient[n+match.home] -= dLogAway
+		gradient[2*n+1] += match.weight * dTauRho / tau
+	}
+
+	return value, gradient
+}
+
+// minimize maximises the likelihood with BFGS and a backtracking line search,
+// it returns the parameters, the number of iterations and whether it converged
+func minimize(l *likelihood, params []float64, maxIterations int, tolerance float64) ([]float64, int, bool) {
+	size := len(params)
+	objective := func(p []float64, withGradient bool) (float64, []float64) {
+		value, gradient := l.evaluate(p, withGradient)
+		for i := range gradient {
+			gradient[i] = -gradient[i]
+		}
+		return -value, gradient
+	}
+
+	totalWeight := 0.0
+	for _, match := range l.matches {
+		totalWeight += match.weight
+	}
+
+	// Inverse Hessian approximation, scaled by the amount of data
+	inverse := identity(size, 1/math.Max(totalWeight, 1))
+
+	value, gradient := objective(params, true)
+	for iteration := 1; iteration <= maxIterations; iteration++ {
+		direction := make([]float64, size)
+		for i := range direction {
+			for j := range direction {
+				direction[i] -= inverse[i][j] * gradient[j]
+			}
+		}
+		slope := dot(gradient, direction)
+		if slope >= 0 {
+			inverse = identity(size, 1/math.Max(totalWeight, 1))
+			for i := range direction {
+				direction[i] = -inverse[i][i] * gradient[i]
+			}
+			slope = dot(gradient, direction)
+		}
+
+		// Backtrack until the Armijo condition holds
+		step := 1.0
+		next := make([]float64, size)
+		var nextValue float64
+		for attempt := 0; attempt < 60; attempt++ {
+			for i := range next {
+				next[i] = params[i] + step*direction[i]
+			}
+			nextValue, _ = objective(next, false)
+			if !math.IsNaN(nextValue) && nextValue <= value+1e-4*step*slope {
+				break
+			}
+			step /= 2
+		}
+		if math.IsNaN(nextValue) || math.IsInf(nextValue, 0) || nextValue > value {
+			return params, iteration, false
+		}
+
+		_, nextGradient := objective(next, true)
+		change := value - nextValue
+
+		s := make([]float64, size)
+		y := make([]float64, size)
+		for i := range s {
+			s[i] = next[i] - params[i]
+			y[i] = nextGradient[i] - gradient[i]
+		}
+		params, value, gradient = next, nextValue, nextGradient
+
+		if change <= tolerance*(1+math.Abs(value)) || maxAbs(gradient) <= tolerance*math.Max(totalWeight, 1) {
+			return params, iteration, true
+		}
+
+		// BFGS update of the inverse Hessian
+		sy := dot(s, y)
+		if sy <= 1e-12 {
+			continue
+		}
+		hy := make([]float64, size)
+		for i := range hy {
+			for j := range hy {
+				hy[i] += inverse[i][j] * y[j]
+			}
+		}
+		yhy := dot(y, hy)
+		for i := range inverse {
+			for j := range inverse[i] {
+				inverse[i][j] += (sy+yhy)*s[i]*s[j]/(sy*sy) - (hy[i]*s[j]+s[i]*hy[j])/sy
+			}
+		}
+	}
+
+	return params, maxIterations, false
+}
+
+// identity returns a scaled identity matrix
+func identity(size int, scale float64) [][]float64 {
+	matrix := make([][]float64, size)
+	for i := range matrix {
+		matrix[i] = make([]float64, size)
+		matrix[i][i] = scale
+	}
+	return matrix
+}
+
+// dot returns the dot product of two vectors
+func dot(a, b []float64) float64 {
+	sum := 0.0
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
+
+// maxAbs returns the largest absolute value of a vector
+func maxAbs(v []float64) float64 {
+	largest := 0.0
+	for _, x := range v {
+		largest = math.Max(largest, math.Abs(x))
+	}
+	return largest
+}
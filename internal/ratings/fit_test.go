@@ -0,0 +1,132 @@
+package ratings
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/cahitcaginkaratas/backend_insider/internal/database"
+	"github.com/cahitcaginkaratas/backend_insider/internal/models"
+)
+
+// synthetic plays every pairing of the teams home and away the given number
+// of times with the Dixon-Coles engine
+func synthetic(teams []models.Team, engine models.PoissonEngine, meetings int, random *rand.Rand) []Observation {
+	date := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var observations []Observation
+	for round := 0; round < meetings; round++ {
+		for i := range teams {
+			for j := range teams {
+				if i == j {
+					continue
+				}
+				scores := engine.ScoreMatrix(&teams[i], &teams[j])
+				homeGoals, awayGoals := scores.Sample(random.Float64())
+				observations = append(observations, Observation{
+					HomeTeam:  teams[i].Name,
+					AwayTeam:  teams[j].Name,
+					HomeGoals: homeGoals,
+					AwayGoals: awayGoals,
+					Date:      date,
+				})
+			}
+		}
+	}
+	return observations
+}
+
+func TestFitRecoversParameters(t *testing.T) {
+	// Attack ratings are centred on zero like the fitted ones
+	attacks := []float64{0.4, 0.2, 0.0, -0.1, -0.2, -0.3}
+	defences := []float64{0.1, 0.0, -0.1, -0.2, -0.3, -0.4}
+	teams := make([]models.Team, len(attacks))
+	for i := range teams {
+		teams[i] = models.Team{Name: fmt.Sprintf("Team %d", i+1), Attack: attacks[i], Defence: defences[i]}
+	}
+	engine := models.PoissonEngine{HomeAdvantage: 0.3, Rho: -0.1}
+
+	opts := DefaultOptions()
+	opts.Xi = 0
+	result, err := Fit(synthetic(teams, engine, 60, rand.New(rand.NewSource(1))), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Fit.Converged {
+		t.Errorf("fit did not converge in %d iterations", result.Fit.Iterations)
+	}
+
+	near := func(name string, got, want, tolerance float64) {
+		t.Helper()
+		if math.Abs(got-want) > tolerance {
+			t.Errorf("%s: got %.3f, want %.3f ± %.2f", name, got, want, tolerance)
+		}
+	}
+	near("home advantage", result.Fit.HomeAdvantage, engine.HomeAdvantage, 0.1)
+	near("rho", result.Fit.Rho, engine.Rho, 0.15)
+	for i, rating := range result.Teams {
+		// Teams are sorted by name, which is the order they were created in
+		near(rating.Name+" attack", rating.Attack, attacks[i], 0.15)
+		near(rating.Name+" defence", rating.Defence, defences[i], 0.15)
+	}
+}
+
+func TestStoreGivesUnfittedTeamsThePrior(t *testing.T) {
+	db := database.NewMemoryDB()
+	if err := db.InitDB(); err != nil {
+		t.Fatal(err)
+	}
+	seed, err := database.DefaultSeed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := database.Seed(db, seed); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the first three seed teams play, the fourth one has no data
+	var played []models.Team
+	for _, team := range seed.Teams[:3] {
+		played = append(played, models.Team{Name: team.Name, Defence: -0.3})
+	}
+	engine := models.PoissonEngine{HomeAdvantage: 0.3}
+	opts := DefaultOptions()
+	opts.Xi = 0
+	result, err := Fit(synthetic(played, engine, 10, rand.New(rand.NewSource(1))), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	before := models.CurrentEngine()
+	unmatched, err := Store(db, result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unmatched) != 0 {
+		t.Errorf("got unmatched teams %v, want none", unmatched)
+	}
+	if models.CurrentEngine() != before {
+		t.Error("Store switched the simulation engine")
+	}
+
+	prior := result.Prior()
+	if prior.Defence == 0 {
+		t.Fatal("the prior defence is 0, the test cannot tell it from an unset rating")
+	}
+	teams, err := db.GetTeams()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, team := range teams {
+		if team.Name != seed.Teams[3].Name {
+			continue
+		}
+		if team.Attack != prior.Attack || team.Defence != prior.Defence {
+			t.Errorf("%s: got attack %.3f defence %.3f, want the prior %.3f and %.3f",
+				team.Name, team.Attack, team.Defence, prior.Attack, prior.Defence)
+		}
+		return
+	}
+	t.Errorf("team %s is missing", seed.Teams[3].Name)
+}
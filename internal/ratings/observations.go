@@ -0,0 +1,155 @@
+package ratings
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cahitcaginkaratas/backend_insider/internal/models"
+)
+
+// Observation is a single played match used for fitting
+type Observation struct {
+	HomeTeam  string
+	AwayTeam  string
+	HomeGoals int
+	AwayGoals int
+	Date      time.Time
+}
+
+// FromMatches converts the played matches from the database into observations
+func FromMatches(matches []models.Match) []Observation {
+	var observations []Observation
+	for _, match := range matches {
+		if !match.Played {
+			continue
+		}
+
+		date := match.Date
+		if date.IsZero() {
+			date = match.UpdatedAt
+		}
+
+		observations = append(observations, Observation{
+			HomeTeam:  match.HomeTeam.Name,
+			AwayTeam:  match.AwayTeam.Name,
+			HomeGoals: match.HomeGoals,
+			AwayGoals: match.AwayGoals,
+			Date:      date,
+		})
+	}
+	return observations
+}
+
+// csvDateLayouts are the date formats used by football-data.co.uk files
+var csvDateLayouts = []string{"02/01/2006", "02/01/06", "2006-01-02"}
+
+// ReadCSV reads results in the football-data.co.uk column layout.
+// Only the Date, HomeTeam, AwayTeam and FTHG/FTAG (or HG/AG) columns are used,
+// rows without a score are fixtures that have not been played and are skipped.
+func ReadCSV(r io.Reader) ([]Observation, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.TrimPrefix(strings.TrimSpace(name), "\ufeff")] = i
+	}
+
+	column := func(names ...string) (int, error) {
+		for _, name := range names {
+			if i, ok := columns[name]; ok {
+				return i, nil
+			}
+		}
+		return 0, fmt.Errorf("missing column %s", names[0])
+	}
+
+	dateCol, err := column("Date")
+	if err != nil {
+		return nil, err
+	}
+	homeCol, err := column("HomeTeam", "Home")
+	if err != nil {
+		return nil, err
+	}
+	awayCol, err := column("AwayTeam", "Away")
+	if err != nil {
+		return nil, err
+	}
+	homeGoalsCol, err := column("FTHG", "HG")
+	if err != nil {
+		return nil, err
+	}
+	awayGoalsCol, err := column("FTAG", "AG")
+	if err != nil {
+		return nil, err
+	}
+
+	var observations []Observation
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line, _ := reader.FieldPos(0)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		field := func(i int) string {
+			if i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		if field(homeGoalsCol) == "" && field(awayGoalsCol) == "" {
+			continue
+		}
+
+		observation := Observation{
+			HomeTeam: field(homeCol),
+			AwayTeam: field(awayCol),
+		}
+		if observation.HomeTeam == "" || observation.AwayTeam == "" {
+			return nil, fmt.Errorf("line %d: missing team name", line)
+		}
+		observation.HomeGoals, err = strconv.Atoi(field(homeGoalsCol))
+		if err != nil || observation.HomeGoals < 0 {
+			return nil, fmt.Errorf("line %d: invalid home goals %q", line, field(homeGoalsCol))
+		}
+		observation.AwayGoals, err = strconv.Atoi(field(awayGoalsCol))
+		if err != nil || observation.AwayGoals < 0 {
+			return nil, fmt.Errorf("line %d: invalid away goals %q", line, field(awayGoalsCol))
+		}
+		observation.Date, err = parseDate(field(dateCol))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		observations = append(observations, observation)
+	}
+
+	return observations, nil
+}
+
+// parseDate parses a date in any of the supported layouts
+func parseDate(value string) (time.Time, error) {
+	for _, layout := range csvDateLayouts {
+		date, err := time.Parse(layout, value)
+		if err == nil {
+			return date, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", value)
+}
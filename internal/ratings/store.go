@@ -0,0 +1,60 @@
+package ratings
+
+import (
+	"github.com/cahitcaginkaratas/backend_insider/internal/database"
+	"github.com/cahitcaginkaratas/backend_insider/internal/models"
+)
+
+// Store saves the fit and copies the ratings onto the matching teams, teams
+// without matches in the fit get the average rating. Fitted teams that are
+// not in the database are returned by name. Switching the simulation to the
+// new engine is up to the caller.
+func Store(db database.Database, result *Result) ([]string, error) {
+	teams, err := db.GetTeams()
+	if err != nil {
+		return nil, err
+	}
+
+	teamsByName := make(map[string]*models.Team)
+	for i := range teams {
+		teamsByName[teams[i].Name] = &teams[i]
+	}
+
+	fitted := make(map[string]bool, len(result.Teams))
+	var unmatched []string
+	for i, rating := range result.Teams {
+		team, ok := teamsByName[rating.Name]
+		if !ok {
+			unmatched = append(unmatched, rating.Name)
+			continue
+		}
+		team.Attack = rating.Attack
+		team.Defence = rating.Defence
+		result.Teams[i].TeamID = team.ID
+		fitted[team.Name] = true
+	}
+
+	prior := result.Prior()
+	for i := range teams {
+		if !fitted[teams[i].Name] {
+			teams[i].Attack = prior.Attack
+			teams[i].Defence = prior.Defence
+		}
+	}
+
+	err = db.SaveRatingFit(&result.Fit, teams)
+	if err != nil {
+		return nil, err
+	}
+	return unmatched, nil
+}
+
+// Load returns the Poisson engine of the latest stored fit, or nil when no
+// fit has been stored
+func Load(db database.Database) (models.Engine, error) {
+	fit, err := db.GetLatestRatingFit()
+	if err != nil || fit == nil {
+		return nil, err
+	}
+	return models.NewPoissonEngine(fit), nil
+}